@@ -7,4 +7,10 @@ require (
 	github.com/grafana/grafana-plugin-sdk-go v0.139.0
 	github.com/magefile/mage v1.13.0 // indirect
 	github.com/pkg/errors v0.9.1
+	github.com/xitongsys/parquet-go v1.6.2
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+	google.golang.org/grpc v1.46.0
 )
@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/ReneKroon/ttlcache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+// Stores annotations (alerts, opsnotes and sdts) already converted from santaba response, keyed by scope, type and time range.
+// Annotation panels are refreshed together with graphs, caching avoids calling alert/opsnote/sdt API on every refresh
+var annotationCache = ttlcache.NewCache() //nolint:gochecknoglobals
+
+func GetAnnotations(key string) ([]models.Annotation, bool) {
+	if v, ok := annotationCache.Get(key); ok {
+		if annotations, ok := v.([]models.Annotation); ok {
+			return annotations, true
+		}
+	}
+	return nil, false
+}
+
+func StoreAnnotations(key string, annotations []models.Annotation) {
+	annotationCache.SetWithTTL(key, annotations, time.Duration(constants.AnnotationCacheTTLInSeconds)*time.Second)
+}
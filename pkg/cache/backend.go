@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Backend is a store shared by plugin processes of grafana replicas, so that raw data, time ranges and the API call budget are
+not kept per process. Without backend caches are local to the process. With backend, entries are still kept locally for a few
+seconds, so that a query reading an entry many times does not go to the backend every time. Shared entries are listed in an
+index per datasource, so that any process can invalidate entries other processes stored
+*/
+type Backend interface {
+	// Get returns value of key, false when key is not present
+	Get(key string) ([]byte, bool, error)
+	// Set sets value of key, ttl of 0 never expires
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+	// IncrBy adds n to counter of key and returns its new value, counter expires ttl after it is created
+	IncrBy(key string, n int64, ttl time.Duration) (int64, error)
+	// IndexAdd adds member to index until expireAt, zero expireAt never expires. Members expired are dropped from index
+	IndexAdd(index string, member string, expireAt time.Time) error
+	// IndexMembers returns members of index not expired
+	IndexMembers(index string) ([]string, error)
+	IndexRemove(index string, members ...string) error
+}
+
+var (
+	currentBackend Backend //nolint:gochecknoglobals
+	backendMutex   sync.RWMutex
+)
+
+// SetBackend makes caches shared through backend, nil makes them local. Local entries are dropped, they may be stale
+func SetBackend(b Backend) {
+	backendMutex.Lock()
+	currentBackend = b
+	backendMutex.Unlock()
+	for _, cache := range indexedCaches {
+		cache.purgeLocal()
+	}
+}
+
+func getBackend() Backend {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+	return currentBackend
+}
@@ -0,0 +1,432 @@
+package cache_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+// memoryRedis is an in-memory stand-in of redis, speaking the subset of RESP RedisBackend uses
+type memoryRedis struct {
+	listener net.Listener
+	password string
+	mutex    sync.Mutex
+	values   map[string][]byte
+	sets     map[string]map[string]float64
+	expireAt map[string]time.Time
+}
+
+func startMemoryRedis(t *testing.T, password string) *memoryRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &memoryRedis{listener: listener, password: password, values: map[string][]byte{},
+		sets: map[string]map[string]float64{}, expireAt: map[string]time.Time{}}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (m *memoryRedis) address() string {
+	return m.listener.Addr().String()
+}
+
+func (m *memoryRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := m.password == ""
+	// commands of a transaction, nil when out of transaction
+	var queued [][]string
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(args[0])
+		if !authenticated && command != "AUTH" {
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		switch command {
+		case "AUTH":
+			authenticated = args[1] == m.password
+			if !authenticated {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			fmt.Fprint(conn, "+OK\r\n")
+		case "SELECT":
+			fmt.Fprint(conn, "+OK\r\n")
+		case "MULTI":
+			queued = [][]string{}
+			fmt.Fprint(conn, "+OK\r\n")
+		case "DISCARD":
+			queued = nil
+			fmt.Fprint(conn, "+OK\r\n")
+		case "EXEC":
+			m.mutex.Lock()
+			reply := "*" + strconv.Itoa(len(queued)) + "\r\n"
+			for _, args := range queued {
+				reply += m.execute(strings.ToUpper(args[0]), args[1:])
+			}
+			m.mutex.Unlock()
+			queued = nil
+			fmt.Fprint(conn, reply)
+		default:
+			if queued != nil {
+				queued = append(queued, args)
+				fmt.Fprint(conn, "+QUEUED\r\n")
+				continue
+			}
+			m.mutex.Lock()
+			fmt.Fprint(conn, m.execute(command, args[1:]))
+			m.mutex.Unlock()
+		}
+	}
+}
+
+// execute runs command with mutex held, so that commands of a transaction run together
+func (m *memoryRedis) execute(command string, args []string) string {
+	key := args[0]
+	if expireAt, ok := m.expireAt[key]; ok && time.Now().After(expireAt) {
+		delete(m.values, key)
+		delete(m.expireAt, key)
+	}
+	switch command {
+	case "GET":
+		value, ok := m.values[key]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(value)) + "\r\n" + string(value) + "\r\n"
+	case "SET":
+		var expireAt time.Time
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				if _, ok := m.values[key]; ok {
+					return "$-1\r\n"
+				}
+			case "PX":
+				i++
+				ms, _ := strconv.ParseInt(args[i], 10, 64)
+				expireAt = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+		}
+		m.values[key] = []byte(args[1])
+		delete(m.expireAt, key)
+		if !expireAt.IsZero() {
+			m.expireAt[key] = expireAt
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := m.values[key]
+		delete(m.values, key)
+		delete(m.expireAt, key)
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "INCRBY":
+		current, _ := strconv.ParseInt(string(m.values[key]), 10, 64)
+		n, _ := strconv.ParseInt(args[1], 10, 64)
+		m.values[key] = []byte(strconv.FormatInt(current+n, 10))
+		return ":" + strconv.FormatInt(current+n, 10) + "\r\n"
+	case "ZADD":
+		if m.sets[key] == nil {
+			m.sets[key] = map[string]float64{}
+		}
+		score, _ := strconv.ParseFloat(args[1], 64)
+		_, ok := m.sets[key][args[2]]
+		m.sets[key][args[2]] = score
+		if ok {
+			return ":0\r\n"
+		}
+		return ":1\r\n"
+	case "ZREM":
+		removed := 0
+		for _, member := range args[1:] {
+			if _, ok := m.sets[key][member]; ok {
+				delete(m.sets[key], member)
+				removed++
+			}
+		}
+		return ":" + strconv.Itoa(removed) + "\r\n"
+	case "ZRANGEBYSCORE", "ZREMRANGEBYSCORE":
+		var members []string
+		for member, score := range m.sets[key] {
+			if inScoreRange(score, args[1], args[2]) {
+				members = append(members, member)
+			}
+		}
+		if command == "ZREMRANGEBYSCORE" {
+			for _, member := range members {
+				delete(m.sets[key], member)
+			}
+			return ":" + strconv.Itoa(len(members)) + "\r\n"
+		}
+		sort.Slice(members, func(i, j int) bool { return m.sets[key][members[i]] < m.sets[key][members[j]] })
+		reply := "*" + strconv.Itoa(len(members)) + "\r\n"
+		for _, member := range members {
+			reply += "$" + strconv.Itoa(len(member)) + "\r\n" + member + "\r\n"
+		}
+		return reply
+	default:
+		return "-ERR unknown command '" + command + "'\r\n"
+	}
+}
+
+// inScoreRange tells whether score is within min and max of a sorted set range, which are inclusive unless prefixed with (
+func inScoreRange(score float64, min string, max string) bool {
+	bound := func(value string) (float64, bool) {
+		exclusive := strings.HasPrefix(value, "(")
+		parsed, _ := strconv.ParseFloat(strings.TrimPrefix(value, "("), 64)
+		return parsed, exclusive
+	}
+	low, lowExclusive := bound(min)
+	high, highExclusive := bound(max)
+	return (score > low || !lowExclusive && score == low) && (score < high || !highExclusive && score == high)
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		args[i] = string(value[:size])
+	}
+	return args, nil
+}
+
+func TestRedisBackend(t *testing.T) {
+	server := startMemoryRedis(t, "secret")
+	redis := cache.NewRedisBackend(server.address(), "secret", 1)
+
+	if _, ok, err := redis.Get("missing"); ok || err != nil {
+		t.Fatalf("missing key: ok = %v, err = %v", ok, err)
+	}
+	if err := redis.Set("key", []byte("value\r\nwith newline"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, err := redis.Get("key"); !ok || err != nil || string(value) != "value\r\nwith newline" {
+		t.Fatalf("Get = %q, %v, %v", value, ok, err)
+	}
+	if err := redis.Delete("key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := redis.Get("key"); ok {
+		t.Fatal("key is present after Delete")
+	}
+
+	if err := redis.Set("expiring", []byte("value"), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok, _ := redis.Get("expiring"); ok {
+		t.Fatal("key is present after its ttl")
+	}
+
+	var expireAt time.Time
+	for i, want := range []int64{2, 5} {
+		got, err := redis.IncrBy("counter", []int64{2, 3}[i], time.Minute)
+		if err != nil || got != want {
+			t.Fatalf("IncrBy = %d, %v, want %d", got, err, want)
+		}
+		server.mutex.Lock()
+		if i == 0 {
+			expireAt = server.expireAt["counter"]
+		} else if server.expireAt["counter"] != expireAt {
+			t.Errorf("expiry of counter moved from %v to %v, want it set once with counter", expireAt, server.expireAt["counter"])
+		}
+		server.mutex.Unlock()
+		if expireAt.IsZero() {
+			t.Fatal("counter is created without expiry")
+		}
+	}
+
+	if _, _, err := cache.NewRedisBackend(server.address(), "wrong", 0).Get("key"); err == nil {
+		t.Fatal("wrong password must fail")
+	}
+}
+
+// Processes sharing a backend are simulated by switching backend, which drops entries of the process
+func TestSharedCacheAcrossReplicas(t *testing.T) {
+	server := startMemoryRedis(t, "")
+	t.Cleanup(func() { cache.SetBackend(nil) })
+	cache.SetBackend(cache.NewRedisBackend(server.address(), "", 0))
+
+	metaData := models.MetaData{Id: "replica-query", QueryId: "replica", DataSourceUID: "uid", CacheTTLInSeconds: 60}
+	rawData := &models.MultiInstanceRawData{
+		Data: models.MultiInstanceData{
+			DataSourceName: "CPU",
+			DataPoints:     []string{"idle"},
+			Instances: map[string]models.ValuesAndTime{
+				"CPU-0": {Time: []int64{120000, 60000}, Values: [][]interface{}{{12.5}, {"No Data"}}},
+			},
+		},
+		Error: "OK",
+	}
+	cache.StoreData(metaData, rawData)
+	cache.StoreFirstTimeStamp(metaData, 60)
+	cache.StoreLastTimeStamp(metaData, 120)
+	cache.AddNrOfApiCalls("uid", 3)
+
+	cache.SetBackend(cache.NewRedisBackend(server.address(), "", 0))
+	data, ok := cache.GetData(metaData)
+	if !ok {
+		t.Fatal("raw data stored by other replica is not found")
+	}
+	if !reflect.DeepEqual(data, rawData) {
+		t.Fatalf("raw data = %+v, want %+v", data, rawData)
+	}
+	if from, to, ok := cache.GetCachedTimeRange(metaData); !ok || from != 60 || to != 120 {
+		t.Fatalf("time range = %d - %d, %v", from, to, ok)
+	}
+	cache.AddNrOfApiCalls("uid", 2)
+	if calls := cache.GetNrOfApiCalls("uid").NrOfCalls; calls != 5 {
+		t.Fatalf("api calls = %d, want calls of both replicas", calls)
+	}
+
+	cache.Remove(metaData)
+	cache.SetBackend(cache.NewRedisBackend(server.address(), "", 0))
+	if _, ok := cache.GetData(metaData); ok {
+		t.Fatal("raw data removed by other replica is still found")
+	}
+}
+
+func TestInvalidationAcrossReplicas(t *testing.T) {
+	server := startMemoryRedis(t, "")
+	t.Cleanup(func() { cache.SetBackend(nil) })
+	cache.SetBackend(cache.NewRedisBackend(server.address(), "", 0))
+
+	metaData := models.MetaData{Id: "invalidated-query", QueryId: "invalidated", DataSourceUID: t.Name(), CacheTTLInSeconds: 60}
+	other := models.MetaData{Id: "other-query", QueryId: "other", DataSourceUID: t.Name() + "other", CacheTTLInSeconds: 60}
+	cache.StoreData(metaData, &models.MultiInstanceRawData{Error: "OK"})
+	cache.StoreData(other, &models.MultiInstanceRawData{Error: "OK"})
+
+	// replica invalidating has never seen the entries
+	cache.SetBackend(cache.NewRedisBackend(server.address(), "", 0))
+	if removed := cache.InvalidateCacheEntries(models.CacheFilter{DataSourceUID: metaData.DataSourceUID}); removed != 1 {
+		t.Errorf("removed %d entries, want entry stored by other replica", removed)
+	}
+
+	cache.SetBackend(cache.NewRedisBackend(server.address(), "", 0))
+	if _, ok := cache.GetData(metaData); ok {
+		t.Error("raw data invalidated by other replica is still found")
+	}
+	if _, ok := cache.GetData(other); !ok {
+		t.Error("raw data of other datasource is invalidated")
+	}
+}
+
+func TestMetadataInvalidationAcrossReplicas(t *testing.T) {
+	server := startMemoryRedis(t, "")
+	t.Cleanup(func() { cache.SetBackend(nil) })
+	redis := cache.NewRedisBackend(server.address(), "", 0)
+	cache.SetBackend(redis)
+
+	client := httpclient.SantabaClient{
+		DataSourceUID:  t.Name(),
+		PluginSettings: &models.PluginSettings{Path: "portal"},
+		AuthSettings:   &models.AuthSettings{},
+	}
+	calls := 0
+	get := func() {
+		_, err := cache.GetMetadata(client, constants.DataSourceReq, "setting/datasources", func(httpclient.SantabaClient) ([]byte, error) {
+			calls++
+			return []byte(`{}`), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get()
+	get()
+	// other replica invalidates metadata of the datasource
+	if _, err := redis.IncrBy(cache.SharedMetadataGenerationKey(client), 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	get()
+
+	if calls != 2 {
+		t.Errorf("metadata fetched %d times, want cached metadata dropped once invalidated by other replica", calls)
+	}
+}
+
+func TestApiCallsBudgetIsReservedAtomicallyAcrossReplicas(t *testing.T) {
+	server := startMemoryRedis(t, "")
+	t.Cleanup(func() { cache.SetBackend(nil) })
+	cache.SetBackend(cache.NewRedisBackend(server.address(), "", 0))
+
+	const replicas, planned = 20, 60
+	minute := time.Now().Unix() / constants.SharedApiCallsWindowSeconds
+	var reserved int64
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < replicas; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, calls := cache.ReserveApiCalls(t.Name(), planned, true)
+			atomic.AddInt64(&reserved, calls)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	counted := cache.GetNrOfApiCalls(t.Name()).NrOfCalls
+	if time.Now().Unix()/constants.SharedApiCallsWindowSeconds != minute {
+		t.Skip("api calls window changed during the test")
+	}
+	if reserved != constants.MaxApiCallsRateLimit {
+		t.Errorf("replicas reserved %d calls, want the whole budget of %d", reserved, constants.MaxApiCallsRateLimit)
+	}
+	if counted != constants.MaxApiCallsRateLimit {
+		t.Errorf("shared counter has %d calls, want calls over budget given back", counted)
+	}
+	if sofar, calls := cache.ReserveApiCalls(t.Name(), 5, false); sofar != constants.MaxApiCallsRateLimit || calls != 5 {
+		t.Errorf("calls without limit reserved %d of 5 after %d calls, want all counted", calls, sofar)
+	}
+}
@@ -0,0 +1,350 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ReneKroon/ttlcache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+/*
+indexedCache is ttlcache keeping an index of its entries with their tags and expiry, as ttlcache can neither list keys nor
+tell ttl remaining. Index is what cache admin endpoints list and invalidate by. Entries are tagged with datasource, host and
+query they belong to, untagged entries are not reached by admin endpoints and just expire.
+Caches having a codec are shared through backend when one is set, see Backend. Index then holds entries known to this process
+*/
+type indexedCache struct {
+	*ttlcache.Cache
+	name string
+	// coverage returns time range covered by data of entry in epoch milliseconds, nil for caches not holding time series
+	coverage func(data interface{}) (int64, int64, bool)
+	// codec is nil for caches not shared through backend
+	codec   *cacheCodec
+	mutex   sync.Mutex
+	entries map[string]*indexEntry
+}
+
+type indexEntry struct {
+	tags     models.CacheTags
+	ttl      time.Duration
+	expireAt time.Time
+	// data of entries of caches not shared, so that listing reads them without extending their ttl as ttlcache Get does
+	data interface{}
+}
+
+// sharedIndexMember is member of shared index of a datasource, tags of the entry are in it so that filters apply without reading it
+type sharedIndexMember struct {
+	Key     string `json:"key"`
+	Host    string `json:"host,omitempty"`
+	QueryId string `json:"queryId,omitempty"`
+}
+
+type cacheCodec struct {
+	encode func(data interface{}) ([]byte, error)
+	decode func(value []byte) (interface{}, error)
+}
+
+// Caches listed and invalidated by admin endpoints
+var indexedCaches []*indexedCache //nolint:gochecknoglobals
+
+func newIndexedCache(name string, coverage func(data interface{}) (int64, int64, bool), codec *cacheCodec) *indexedCache {
+	cache := &indexedCache{Cache: ttlcache.NewCache(), name: name, coverage: coverage, codec: codec,
+		entries: make(map[string]*indexEntry)}
+	// callback runs in its own goroutine, entry may have been set again meanwhile
+	cache.SetExpirationCallback(func(key string, _ interface{}) {
+		cache.mutex.Lock()
+		defer cache.mutex.Unlock()
+		if entry, ok := cache.entries[key]; ok && entry.ttl > 0 && !entry.expireAt.After(time.Now()) {
+			delete(cache.entries, key)
+		}
+	})
+	indexedCaches = append(indexedCaches, cache)
+	return cache
+}
+
+// SetWithTTL sets entry with tags, ttl of 0 never expires
+func (c *indexedCache) SetWithTTL(key string, data interface{}, ttl time.Duration, tags models.CacheTags) {
+	shared := c.sharedBackend()
+	entry := &indexEntry{tags: tags, ttl: ttl, expireAt: time.Now().Add(ttl)}
+	if shared == nil {
+		entry.data = data
+	}
+	c.mutex.Lock()
+	c.entries[key] = entry
+	c.mutex.Unlock()
+	if shared == nil {
+		c.Cache.SetWithTTL(key, data, ttl)
+		return
+	}
+	c.Cache.SetWithTTL(key, data, localTTL(ttl))
+	value, err := c.codec.encode(data)
+	if err == nil {
+		err = shared.Set(c.sharedKey(key), value, ttl)
+	}
+	if err == nil && tags.DataSourceUID != "" {
+		var expireAt time.Time
+		if ttl > 0 {
+			expireAt = entry.expireAt
+		}
+		err = shared.IndexAdd(c.sharedIndexKey(tags.DataSourceUID), sharedMember(key, tags), expireAt)
+	}
+	if err != nil {
+		log.DefaultLogger.Warn("Could not store cache entry in shared cache", "cache", c.name, "key", key, "error", err)
+	}
+}
+
+// Get extends ttl of entry on hit, as ttlcache does. Entries not present locally are read from shared cache
+func (c *indexedCache) Get(key string) (interface{}, bool) {
+	data, ok := c.Cache.Get(key)
+	if !ok {
+		data, ok = c.getShared(key)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if entry, indexed := c.entries[key]; indexed {
+		if !ok {
+			delete(c.entries, key)
+		} else if entry.ttl > 0 {
+			entry.expireAt = time.Now().Add(entry.ttl)
+		}
+	}
+	return data, ok
+}
+
+func (c *indexedCache) getShared(key string) (interface{}, bool) {
+	data, ok := c.readShared(key)
+	if ok {
+		c.Cache.SetWithTTL(key, data, localTTL(0))
+	}
+	return data, ok
+}
+
+// readShared reads entry of shared cache without keeping it locally
+func (c *indexedCache) readShared(key string) (interface{}, bool) {
+	shared := c.sharedBackend()
+	if shared == nil {
+		return nil, false
+	}
+	value, ok, err := shared.Get(c.sharedKey(key))
+	if err != nil {
+		log.DefaultLogger.Warn("Could not read cache entry from shared cache", "cache", c.name, "key", key, "error", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	data, err := c.codec.decode(value)
+	if err != nil {
+		log.DefaultLogger.Warn("Could not decode cache entry of shared cache", "cache", c.name, "key", key, "error", err)
+		return nil, false
+	}
+	return data, true
+}
+
+// peek reads entry of index without extending its ttl, entries of shared caches are read from shared cache
+func (c *indexedCache) peek(key string, entry indexEntry, now time.Time) (interface{}, bool) {
+	if entry.ttl > 0 && !entry.expireAt.After(now) {
+		return nil, false
+	}
+	if c.sharedBackend() != nil {
+		return c.readShared(key)
+	}
+	return entry.data, entry.data != nil
+}
+
+// Remove removes entry, entries of shared cache stored by other processes stay in shared index until they expire
+func (c *indexedCache) Remove(key string) bool {
+	c.mutex.Lock()
+	entry, indexed := c.entries[key]
+	delete(c.entries, key)
+	c.mutex.Unlock()
+	removed := c.Cache.Remove(key)
+	if shared := c.sharedBackend(); shared != nil {
+		err := shared.Delete(c.sharedKey(key))
+		if err == nil && indexed && entry.tags.DataSourceUID != "" {
+			err = shared.IndexRemove(c.sharedIndexKey(entry.tags.DataSourceUID), sharedMember(key, entry.tags))
+		}
+		if err != nil {
+			log.DefaultLogger.Warn("Could not remove cache entry from shared cache", "cache", c.name, "key", key, "error", err)
+		}
+	}
+	return removed
+}
+
+/*
+invalidateShared removes entries matching filter from shared cache, entries stored by any process are reached through shared
+index of the datasource. Other processes keep their local copy for a few seconds at most. Returns number of entries removed
+*/
+func (c *indexedCache) invalidateShared(shared Backend, filter models.CacheFilter) int {
+	keys := make(map[string]bool)
+	for key := range c.matching(filter) {
+		keys[key] = true
+	}
+	index := c.sharedIndexKey(filter.DataSourceUID)
+	members, err := shared.IndexMembers(index)
+	if err != nil {
+		log.DefaultLogger.Warn("Could not read shared cache index, only entries of this process are invalidated", "cache", c.name,
+			"error", err)
+	}
+	var removedMembers []string
+	for _, member := range members {
+		var indexed sharedIndexMember
+		if err := json.Unmarshal([]byte(member), &indexed); err != nil {
+			removedMembers = append(removedMembers, member)
+			continue
+		}
+		if matches(filter, models.CacheTags{DataSourceUID: filter.DataSourceUID, Host: indexed.Host, QueryId: indexed.QueryId}) {
+			keys[indexed.Key] = true
+			removedMembers = append(removedMembers, member)
+		}
+	}
+	for key := range keys {
+		c.Remove(key)
+	}
+	if err := shared.IndexRemove(index, removedMembers...); err != nil {
+		log.DefaultLogger.Warn("Could not update shared cache index", "cache", c.name, "error", err)
+	}
+	return len(keys)
+}
+
+// Local entries are dropped, shared entries are left as they are
+func (c *indexedCache) purgeLocal() {
+	c.mutex.Lock()
+	c.entries = make(map[string]*indexEntry)
+	c.mutex.Unlock()
+	c.Purge()
+}
+
+func (c *indexedCache) sharedBackend() Backend {
+	if c.codec == nil {
+		return nil
+	}
+	return getBackend()
+}
+
+func (c *indexedCache) sharedKey(key string) string {
+	return constants.SharedCacheKeyPrefix + c.name + ":" + key
+}
+
+func (c *indexedCache) sharedIndexKey(dataSourceUID string) string {
+	return constants.SharedCacheKeyPrefix + c.name + ":index:" + dataSourceUID
+}
+
+func sharedMember(key string, tags models.CacheTags) string {
+	// marshalling a struct of strings can not fail
+	member, _ := json.Marshal(sharedIndexMember{Key: key, Host: tags.Host, QueryId: tags.QueryId})
+	return string(member)
+}
+
+// Entries of shared caches are kept locally only for a few seconds, other processes may update them
+func localTTL(ttl time.Duration) time.Duration {
+	local := constants.SharedCacheLocalTTLSeconds * time.Second
+	if ttl > 0 && ttl < local {
+		return ttl
+	}
+	return local
+}
+
+func (c *indexedCache) tags(key string) models.CacheTags {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		return entry.tags
+	}
+	return models.CacheTags{}
+}
+
+// Keys of entries matching filter with their index entry, as of now
+func (c *indexedCache) matching(filter models.CacheFilter) map[string]indexEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	matched := make(map[string]indexEntry)
+	for key, entry := range c.entries {
+		if matches(filter, entry.tags) {
+			matched[key] = *entry
+		}
+	}
+	return matched
+}
+
+// ListCacheEntries lists entries of indexed caches matching filter, with size, time coverage and ttl remaining
+func ListCacheEntries(filter models.CacheFilter) []models.CacheEntryInfo {
+	now := time.Now()
+	var infos []models.CacheEntryInfo
+	for _, cache := range indexedCaches {
+		for key, entry := range cache.matching(filter) {
+			data, ok := cache.peek(key, entry, now)
+			if !ok {
+				continue
+			}
+			info := models.CacheEntryInfo{
+				Cache:               cache.name,
+				Key:                 key,
+				DataSourceUID:       entry.tags.DataSourceUID,
+				Host:                entry.tags.Host,
+				QueryId:             entry.tags.QueryId,
+				SizeBytes:           sizeOf(data),
+				TTLRemainingSeconds: -1,
+			}
+			if entry.ttl > 0 {
+				info.TTLRemainingSeconds = int64(entry.expireAt.Sub(now).Seconds())
+			}
+			if cache.coverage != nil {
+				if from, to, ok := cache.coverage(data); ok {
+					info.From, info.To = from, to
+				}
+			}
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Cache != infos[j].Cache {
+			return infos[i].Cache < infos[j].Cache
+		}
+		return infos[i].Key < infos[j].Key
+	})
+	return infos
+}
+
+/*
+InvalidateCacheEntries removes entries of indexed caches matching filter and returns number of entries removed. Entries of shared
+caches are removed whichever process stored them
+*/
+func InvalidateCacheEntries(filter models.CacheFilter) int {
+	removed := 0
+	for _, cache := range indexedCaches {
+		if shared := cache.sharedBackend(); shared != nil {
+			removed += cache.invalidateShared(shared, filter)
+			continue
+		}
+		for key := range cache.matching(filter) {
+			if cache.Remove(key) {
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+// Entries of datasource of filter match, empty host and query id of filter match any value
+func matches(filter models.CacheFilter, tags models.CacheTags) bool {
+	return tags.DataSourceUID == filter.DataSourceUID && (filter.Host == "" || tags.Host == filter.Host) &&
+		(filter.QueryId == "" || tags.QueryId == filter.QueryId)
+}
+
+// Size of data gob encoded, data gob can not encode, like structs of unexported fields, is sized by its type
+func sizeOf(data interface{}) int {
+	b := new(bytes.Buffer)
+	if err := gob.NewEncoder(b).Encode(data); err != nil {
+		return int(reflect.TypeOf(data).Size())
+	}
+	return b.Len()
+}
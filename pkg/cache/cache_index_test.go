@@ -0,0 +1,54 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+func storeRawData(t *testing.T, uid string, ttlSeconds int64) models.MetaData {
+	t.Helper()
+	metaData := models.MetaData{Id: t.Name() + uid, QueryId: t.Name() + uid, DataSourceUID: t.Name() + uid, Host: "server",
+		CacheTTLInSeconds: ttlSeconds}
+	cache.StoreData(metaData, &models.MultiInstanceRawData{Error: "OK"})
+	t.Cleanup(func() { cache.Remove(metaData) })
+	return metaData
+}
+
+func TestListCacheEntriesDoesNotExtendTTL(t *testing.T) {
+	metaData := storeRawData(t, "", 1)
+	filter := models.CacheFilter{DataSourceUID: metaData.DataSourceUID}
+
+	for deadline := time.Now().Add(1500 * time.Millisecond); time.Now().Before(deadline); {
+		cache.ListCacheEntries(filter)
+		time.Sleep(100 * time.Millisecond)
+	}
+	if _, ok := cache.GetData(metaData); ok {
+		t.Error("entry listed by admin outlived its ttl")
+	}
+	if entries := cache.ListCacheEntries(filter); len(entries) != 0 {
+		t.Errorf("expired entries are listed: %v", entries)
+	}
+}
+
+func TestCacheAdminIsScopedToDataSource(t *testing.T) {
+	own := storeRawData(t, "own", 60)
+	other := storeRawData(t, "other", 60)
+	filter := models.CacheFilter{DataSourceUID: own.DataSourceUID}
+
+	entries := cache.ListCacheEntries(filter)
+	if len(entries) != 1 || entries[0].DataSourceUID != own.DataSourceUID {
+		t.Errorf("listed entries %v, want only entry of own datasource", entries)
+	}
+	if removed := cache.InvalidateCacheEntries(filter); removed != 1 {
+		t.Errorf("removed %d entries, want 1", removed)
+	}
+	if _, ok := cache.GetData(own); ok {
+		t.Error("entry of own datasource is not invalidated")
+	}
+	if _, ok := cache.GetData(other); !ok {
+		t.Error("entry of other datasource is invalidated")
+	}
+}
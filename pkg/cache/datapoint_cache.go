@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ReneKroon/ttlcache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	httpclient "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+)
+
+/*
+Stores datasource definitions (collect interval and datapoints with description, type and alert expression) by datasource id.
+Definitions rarely change, caching avoids calling datasource API for every query just to set field config. Failures, like a key
+without datasource read permission, are cached for a short time so that every panel query does not repeat the failing call
+*/
+var dataSourceDefinitionCache = ttlcache.NewCache() //nolint:gochecknoglobals
+
+type dataSourceDefinitionEntry struct {
+	definition models.DataSourceDefinition
+	err        error
+}
+
+func GetDataSourceDefinition(santabaClient httpclient.SantabaClient, queryModel models.QueryModel) (models.DataSourceDefinition, error) {
+	key := fmt.Sprintf("%s-%d", santabaClient.CacheScope(), queryModel.DataSourceSelected.Ds)
+	if v, ok := dataSourceDefinitionCache.Get(key); ok {
+		entry := v.(dataSourceDefinitionEntry)
+		if entry.err != nil {
+			return entry.definition, entry.err
+		}
+		return entry.definition, nil
+	}
+	// call is counted in the api calls budget of the datasource, but is not worth failing a query for when budget is spent
+	if _, reserved := reserveApiCalls(santabaClient.DataSourceUID, 1, true); reserved == 0 {
+		return models.DataSourceDefinition{}, httpclient.ErrRateLimit
+	}
+	dataSourceDefinition, err := fetchDataSourceDefinition(santabaClient, queryModel)
+	if err != nil {
+		dataSourceDefinitionCache.SetWithTTL(key, dataSourceDefinitionEntry{err: err},
+			time.Duration(constants.DataPointDefinitionNegativeCacheTTLSeconds)*time.Second)
+		return dataSourceDefinition, err
+	}
+	dataSourceDefinitionCache.SetWithTTL(key, dataSourceDefinitionEntry{definition: dataSourceDefinition},
+		time.Duration(constants.DataPointDefinitionCacheTTLMinutes)*time.Minute)
+	return dataSourceDefinition, nil
+}
+
+func fetchDataSourceDefinition(santabaClient httpclient.SantabaClient, queryModel models.QueryModel) (models.DataSourceDefinition, error) {
+	var dataSourceDefinition models.DataSourceDefinition
+	requestURL := utils.BuildURLReplacingQueryParams(constants.DataPointReq, &queryModel, 0, 0, models.MetaData{})
+	respByte, err := santabaClient.Get(requestURL, constants.DataPointReq)
+	if err != nil {
+		santabaClient.Logger.Error("Error from server => ", err)
+		return dataSourceDefinition, err //nolint:wrapcheck
+	}
+	err = httpclient.UnmarshalResponse(respByte, &dataSourceDefinition)
+	if err != nil {
+		santabaClient.Logger.Error(constants.ErrorUnmarshallingErrorData+"dataSourceDefinition =>", err.Error())
+	}
+	return dataSourceDefinition, err //nolint:wrapcheck
+}
+
+// GetDataPointDefinitions returns datapoint definitions of selected datasource by datapoint name
+func GetDataPointDefinitions(santabaClient httpclient.SantabaClient, queryModel models.QueryModel) (map[string]models.DataPointDefinition, error) {
+	dataSourceDefinition, err := GetDataSourceDefinition(santabaClient, queryModel)
+	if err != nil {
+		return nil, err
+	}
+	definitions := make(map[string]models.DataPointDefinition)
+	for _, dataPoint := range dataSourceDefinition.DataPoints {
+		definitions[dataPoint.Name] = dataPoint
+	}
+	return definitions, nil
+}
@@ -0,0 +1,55 @@
+package cache_test
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func TestDataSourceDefinitionFailureIsCachedAndCounted(t *testing.T) {
+	var calls int32
+	client := httpclient.SantabaClient{
+		DataSourceUID:  t.Name(),
+		PluginSettings: &models.PluginSettings{Path: "portal"},
+		AuthSettings:   &models.AuthSettings{},
+		Logger:         log.DefaultLogger,
+		Client: &http.Client{Transport: testutil.RoundTripFunc(func(request *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return testutil.Response(request, http.StatusForbidden, `{"errorMessage":"permission denied"}`), nil
+		})},
+	}
+	queryModel := models.QueryModel{DataSourceSelected: models.DataSource{Ds: 42}}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetDataSourceDefinition(client, queryModel); err == nil {
+			t.Fatal("failing definition call must return error")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("definition API called %d times, want failure to be cached", calls)
+	}
+	if counted := cache.GetNrOfApiCalls(t.Name()).NrOfCalls; counted != 1 {
+		t.Errorf("api calls budget counts %d calls, want 1", counted)
+	}
+}
+
+func TestDataSourceDefinitionIsNotRequestedWhenBudgetIsSpent(t *testing.T) {
+	stub := &testutil.SantabaStub{}
+	cache.AddNrOfApiCalls(t.Name(), constants.MaxApiCallsRateLimit)
+	queryModel := models.QueryModel{DataSourceSelected: models.DataSource{Ds: 42}}
+
+	if _, err := cache.GetDataSourceDefinition(stub.Client(t), queryModel); !errors.Is(err, httpclient.ErrRateLimit) {
+		t.Errorf("definition with budget spent returned error %v, want %v", err, httpclient.ErrRateLimit)
+	}
+	if requests := stub.Requests(""); len(requests) > 0 {
+		t.Errorf("definition with budget spent requested %v", requests)
+	}
+}
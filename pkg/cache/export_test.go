@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+)
+
+// ExpireMetadata makes cached response of request URL stale, next get serves it and refreshes it in background
+func ExpireMetadata(santabaClient httpclient.SantabaClient, requestURL string) {
+	key := getMetadataKey(santabaClient, requestURL)
+	if v, ok := metadataCache.Get(key); ok {
+		entry := v.(metadataEntry)
+		entry.freshUntil = time.Time{}
+		metadataCache.SetWithTTL(key, entry, time.Minute)
+	}
+}
+
+// SharedMetadataGenerationKey is key of counter other processes increment when invalidating metadata of the client
+func SharedMetadataGenerationKey(santabaClient httpclient.SantabaClient) string {
+	return sharedMetadataGenerationKey(santabaClient.CacheScope())
+}
+
+// ReserveApiCalls is reserveApiCalls, for tests of cache_test package
+var ReserveApiCalls = reserveApiCalls //nolint:gochecknoglobals
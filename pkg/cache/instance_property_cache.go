@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/ReneKroon/ttlcache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	httpclient "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+)
+
+// Stores properties of instances of a host datasource, keyed by both instance name and display name.
+// Instance properties change rarely, so they are fetched once for all queries filtering on them
+var instancePropertyCache = ttlcache.NewCache() //nolint:gochecknoglobals
+
+func GetInstanceProperties(santabaClient httpclient.SantabaClient, queryModel models.QueryModel) (map[string]map[string]string, error) {
+	key := santabaClient.CacheScope() + "|" + queryModel.HostSelected.Value + "|" + strconv.FormatInt(queryModel.HdsSelected, 10)
+	if v, ok := instancePropertyCache.Get(key); ok {
+		return v.(map[string]map[string]string), nil
+	}
+	requestURL := utils.BuildURLReplacingQueryParams(constants.InstancePropertiesReq, &queryModel, 0, 0, models.MetaData{})
+	instanceProperties := make(map[string]map[string]string)
+	_, err := santabaClient.ForEachPage(requestURL, constants.InstancePropertiesReq, func(items []json.RawMessage) error {
+		for _, item := range items {
+			var instance models.Instance
+			if err := json.Unmarshal(item, &instance); err != nil {
+				santabaClient.Logger.Error(constants.ErrorUnmarshallingErrorData+"instances =>", err.Error())
+				return err //nolint:wrapcheck
+			}
+			properties := getInstanceProperties(instance)
+			instanceProperties[instance.Name] = properties
+			instanceProperties[instance.DisplayName] = properties
+		}
+		return nil
+	})
+	if err != nil {
+		santabaClient.Logger.Error("Error from server => ", err)
+		return nil, err //nolint:wrapcheck
+	}
+	instancePropertyCache.SetWithTTL(key, instanceProperties, time.Duration(constants.InstancePropertyCacheTTLMinutes)*time.Minute)
+	return instanceProperties, nil
+}
+
+// Name, displayName and description are exposed as properties so they can be filtered on like any other property
+func getInstanceProperties(instance models.Instance) map[string]string {
+	properties := map[string]string{
+		"name":        instance.Name,
+		"displayName": instance.DisplayName,
+		"description": instance.Description,
+	}
+	for _, propertyList := range [][]models.Property{instance.AutoProperties, instance.SystemProperties, instance.CustomProperties} {
+		for _, property := range propertyList {
+			properties[property.Name] = property.Value
+		}
+	}
+	return properties
+}
@@ -0,0 +1,47 @@
+package cache_test
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+var instancesURLRegex = regexp.MustCompile(`devices/(\d+)/devicedatasources/(\d+)/instances`) //nolint:gochecknoglobals
+
+func TestInstancePropertiesOfDevicesStaySeparate(t *testing.T) {
+	// every device datasource has instance a with its device and host datasource ids as properties
+	client := httpclient.SantabaClient{
+		DataSourceUID:  t.Name(),
+		PluginSettings: &models.PluginSettings{Path: "portal"},
+		AuthSettings:   &models.AuthSettings{},
+		Logger:         log.DefaultLogger,
+		Client: &http.Client{Transport: testutil.RoundTripFunc(func(request *http.Request) (*http.Response, error) {
+			match := instancesURLRegex.FindStringSubmatch(request.URL.String())
+			body := fmt.Sprintf(`{"status":200,"errmsg":"OK","data":{"total":1,"items":[{"name":"a","customProperties":[
+				{"name":"device","value":%q},{"name":"hds","value":%q}]}]}}`, match[1], match[2])
+			return testutil.Response(request, http.StatusOK, body), nil
+		})},
+	}
+
+	// ids of both concatenate to 1234
+	for _, selection := range []struct {
+		host string
+		hds  int64
+	}{{host: "12", hds: 34}, {host: "123", hds: 4}} {
+		queryModel := models.QueryModel{HostSelected: models.LabelStringValue{Value: selection.host}, HdsSelected: selection.hds}
+		properties, err := cache.GetInstanceProperties(client, queryModel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := properties["a"]; got["device"] != selection.host || got["hds"] != fmt.Sprint(selection.hds) {
+			t.Errorf("instance properties of device %s, hds %d are %v", selection.host, selection.hds, got)
+		}
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/ReneKroon/ttlcache"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
 	httpclient "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
@@ -16,7 +15,7 @@ import (
 )
 
 // Stores mapping of host data source id against ket host and datasource. caching this mapping avoids multiple API call for when host variable is changed
-var hostDsAndHdsMapping = ttlcache.NewCache() //nolint:gochecknoglobals
+var hostDsAndHdsMapping = newIndexedCache("hostMapping", nil, nil) //nolint:gochecknoglobals
 
 func get(key string) (interface{}, bool) {
 	if v, ok := hostDsAndHdsMapping.Get(key); ok {
@@ -25,13 +24,14 @@ func get(key string) (interface{}, bool) {
 	return nil, false
 }
 
-func add(key string, value interface{}) {
-	hostDsAndHdsMapping.SetWithTTL(key, value, time.Duration(constants.InterpolateDataCacheTTLMinutes*60)*time.Second)
+func add(key string, value interface{}, santabaClient httpclient.SantabaClient, host string) {
+	hostDsAndHdsMapping.SetWithTTL(key, value, time.Duration(constants.InterpolateDataCacheTTLMinutes*60)*time.Second,
+		models.CacheTags{DataSourceUID: santabaClient.DataSourceUID, Host: host})
 }
 
 func InterpolateHostDataSourceDetails(santabaClient httpclient.SantabaClient, queryModel models.QueryModel,
 	response backend.DataResponse) (models.QueryModel, backend.DataResponse) {
-	hdsSelected, present := get(fmt.Sprintf("%s-%d", queryModel.HostSelected.Value, queryModel.DataSourceSelected.Ds))
+	hdsSelected, present := get(fmt.Sprintf("%s|%s-%d", santabaClient.CacheScope(), queryModel.HostSelected.Value, queryModel.DataSourceSelected.Ds))
 	if present {
 		queryModel.HdsSelected = hdsSelected.(int64)
 		return queryModel, response
@@ -42,7 +42,7 @@ func InterpolateHostDataSourceDetails(santabaClient httpclient.SantabaClient, qu
 		return queryModel, response
 	}
 	var respByte []byte
-	respByte, response.Error = santabaClient.Get(requestURL, constants.HostDataSourceReq)
+	respByte, response.Error = santabaClient.GetAllPages(requestURL, constants.HostDataSourceReq)
 	if response.Error != nil {
 		santabaClient.Logger.Error("Error from server => ", response.Error)
 		return queryModel, response
@@ -55,7 +55,8 @@ func InterpolateHostDataSourceDetails(santabaClient httpclient.SantabaClient, qu
 	}
 	if hdsReponse.Total == 1 {
 		queryModel.HdsSelected = hdsReponse.Items[0].Id
-		add(fmt.Sprintf("%s-%d", queryModel.HostSelected.Value, queryModel.DataSourceSelected.Ds), queryModel.HdsSelected)
+		add(fmt.Sprintf("%s|%s-%d", santabaClient.CacheScope(), queryModel.HostSelected.Value, queryModel.DataSourceSelected.Ds), queryModel.HdsSelected, santabaClient,
+			queryModel.HostSelected.Label)
 	} else if hdsReponse.Total > 1 {
 		response.Error = errors.New(constants.MoreThanOneHostDataSources + queryModel.DataSourceSelected.Label)
 		return queryModel, response
@@ -68,7 +69,7 @@ func InterpolateHostDataSourceDetails(santabaClient httpclient.SantabaClient, qu
 
 func InterpolateHostDetails(santabaClient httpclient.SantabaClient, queryModel models.QueryModel,
 	response backend.DataResponse) (models.QueryModel, backend.DataResponse) {
-	hostId, present := get(queryModel.HostSelected.Label)
+	hostId, present := get(santabaClient.CacheScope() + "|" + queryModel.HostSelected.Label)
 	if present {
 		queryModel.HostSelected.Value = hostId.(string)
 		return queryModel, response
@@ -93,7 +94,7 @@ func InterpolateHostDetails(santabaClient httpclient.SantabaClient, queryModel m
 	}
 	if len(autoCompleteHosts.Items) > 0 {
 		queryModel.HostSelected.Value = strings.Split(autoCompleteHosts.Items[0], ":")[0]
-		add(queryModel.HostSelected.Label, queryModel.HostSelected.Value)
+		add(santabaClient.CacheScope()+"|"+queryModel.HostSelected.Label, queryModel.HostSelected.Value, santabaClient, queryModel.HostSelected.Label)
 	} else {
 		response.Error = fmt.Errorf(constants.NoHostFoundForGivenGlobPattern, queryModel.HostSelected.Label)
 		return queryModel, response
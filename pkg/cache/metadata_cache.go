@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ReneKroon/ttlcache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	httpclient "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/tracing"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+/*
+Stores responses of resource calls made by query editor, like autocomplete and datasource lists, keyed by portal and resolved
+request URL. Entries are fresh for the TTL of their endpoint and served stale for as long again while being refreshed in background.
+Errors are cached for a short time so that typing in editor does not repeat a failing call on every keystroke
+*/
+var metadataCache = newMetadataCache() //nolint:gochecknoglobals
+
+var (
+	refreshing      = make(map[string]bool) //nolint:gochecknoglobals
+	refreshingMutex sync.Mutex              //nolint:gochecknoglobals
+	/*
+		generation is part of the key, invalidation moves cache scope to next generation and old entries just expire. With shared
+		backend generation of the backend is part of the key as well, so that invalidation reaches every process
+	*/
+	generations     = make(map[string]int) //nolint:gochecknoglobals
+	generationMutex sync.Mutex             //nolint:gochecknoglobals
+)
+
+// freshness is tracked on entries, hits must not keep stale entries alive
+func newMetadataCache() *ttlcache.Cache {
+	cache := ttlcache.NewCache()
+	cache.SkipTtlExtensionOnHit(true)
+	return cache
+}
+
+// Fresh TTL of metadata endpoints in seconds, endpoints not listed are not cached
+var metadataTTLs = map[string]int64{ //nolint:gochecknoglobals
+	constants.AutoCompleteHostReq:     60,
+	constants.AutoCompleteInstanceReq: 60,
+	constants.AutoCompleteGroupReq:    60,
+	constants.ServiceOrDeviceGroupReq: 300,
+	constants.AllHostReq:              300,
+	constants.AllInstanceReq:          120,
+	constants.DataSourceReq:           300,
+	constants.HostDataSourceReq:       600,
+	constants.DataPointReq:            600,
+}
+
+// autocomplete URLs carry current time as cache buster, it is not part of the key
+var cacheBusterRegex = regexp.MustCompile(`([?&])_=\d+&?`) //nolint:gochecknoglobals
+
+type metadataEntry struct {
+	body       []byte
+	err        error
+	freshUntil time.Time
+}
+
+func getMetadataKey(santabaClient httpclient.SantabaClient, requestURL string) string {
+	scope := santabaClient.CacheScope()
+	generationMutex.Lock()
+	generation := strconv.Itoa(generations[scope])
+	generationMutex.Unlock()
+	if shared := getBackend(); shared != nil {
+		value, _, err := shared.Get(sharedMetadataGenerationKey(scope))
+		if err != nil {
+			log.DefaultLogger.Warn("Could not read metadata generation from shared cache", "error", err)
+		}
+		generation += "." + string(value)
+	}
+	return scope + "|" + generation + "|" + cacheBusterRegex.ReplaceAllString(requestURL, "$1")
+}
+
+func sharedMetadataGenerationKey(scope string) string {
+	return constants.SharedCacheKeyPrefix + "metadataGeneration:" + scope
+}
+
+// fetchMetadata calls santaba with the client it is given, background refresh gives a client not bound to the request
+type fetchMetadata func(santabaClient httpclient.SantabaClient) ([]byte, error)
+
+// GetMetadata returns cached response of request, calling fetch when missing. Requests without TTL are always fetched
+func GetMetadata(santabaClient httpclient.SantabaClient, request string, requestURL string, fetch fetchMetadata) ([]byte, error) {
+	ttl, ok := metadataTTLs[request]
+	if !ok {
+		return fetch(santabaClient)
+	}
+	key := getMetadataKey(santabaClient, requestURL)
+	if v, ok := metadataCache.Get(key); ok {
+		entry := v.(metadataEntry)
+		if time.Now().After(entry.freshUntil) {
+			go refreshMetadata(santabaClient, request, key, ttl, fetch)
+		}
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		return entry.body, nil
+	}
+	return storeMetadata(santabaClient, key, ttl, fetch)
+}
+
+func storeMetadata(santabaClient httpclient.SantabaClient, key string, ttl int64, fetch fetchMetadata) ([]byte, error) {
+	body, err := fetch(santabaClient)
+	if err != nil {
+		negativeTTL := time.Duration(constants.MetadataNegativeCacheTTLSeconds) * time.Second
+		metadataCache.SetWithTTL(key, metadataEntry{err: err, freshUntil: time.Now().Add(negativeTTL)}, negativeTTL)
+		return nil, err
+	}
+	fresh := time.Duration(ttl) * time.Second
+	metadataCache.SetWithTTL(key, metadataEntry{body: body, freshUntil: time.Now().Add(fresh)}, 2*fresh)
+	return body, nil
+}
+
+/*
+Only one refresh per key runs at a time, stale entry is kept when refresh fails. Refresh runs after the resource call has been
+answered, so it gets a context of its own, linked to the trace of the call
+*/
+func refreshMetadata(santabaClient httpclient.SantabaClient, request string, key string, ttl int64, fetch fetchMetadata) {
+	refreshingMutex.Lock()
+	if refreshing[key] {
+		refreshingMutex.Unlock()
+		return
+	}
+	refreshing[key] = true
+	refreshingMutex.Unlock()
+	defer func() {
+		refreshingMutex.Lock()
+		delete(refreshing, key)
+		refreshingMutex.Unlock()
+	}()
+	var span trace.Span
+	santabaClient.Ctx, span = tracing.StartDetached(santabaClient.Ctx, "cache.RefreshMetadata",
+		attribute.String("lm.request", request))
+	body, err := fetch(santabaClient)
+	tracing.End(span, err)
+	if err != nil {
+		santabaClient.Logger.Warn("Refreshing metadata failed, serving stale data", "key", key, "error", err)
+		return
+	}
+	fresh := time.Duration(ttl) * time.Second
+	metadataCache.SetWithTTL(key, metadataEntry{body: body, freshUntil: time.Now().Add(fresh)}, 2*fresh)
+}
+
+/*
+InvalidateMetadata drops cached metadata of the cache scope of the client, other datasources of the same portal keep their
+entries
+*/
+func InvalidateMetadata(santabaClient httpclient.SantabaClient) {
+	scope := santabaClient.CacheScope()
+	generationMutex.Lock()
+	generations[scope]++
+	generationMutex.Unlock()
+	if shared := getBackend(); shared != nil {
+		if _, err := shared.IncrBy(sharedMetadataGenerationKey(scope), 1, 0); err != nil {
+			log.DefaultLogger.Warn("Could not invalidate metadata of other processes", "error", err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInvalidateMetadataIsScopedToDataSource(t *testing.T) {
+	clientOf := func(uid string) httpclient.SantabaClient {
+		return httpclient.SantabaClient{
+			DataSourceUID:  t.Name() + uid,
+			PluginSettings: &models.PluginSettings{Path: "portal"},
+			AuthSettings:   &models.AuthSettings{},
+		}
+	}
+	invalidated, other := clientOf("invalidated"), clientOf("other")
+	calls := map[string]int{}
+	get := func(client httpclient.SantabaClient) {
+		_, err := cache.GetMetadata(client, constants.DataSourceReq, "setting/datasources", func(httpclient.SantabaClient) ([]byte, error) {
+			calls[client.DataSourceUID]++
+			return []byte(`{}`), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get(invalidated)
+	get(other)
+	cache.InvalidateMetadata(invalidated)
+	get(invalidated)
+	get(other)
+
+	if calls[invalidated.DataSourceUID] != 2 {
+		t.Errorf("invalidated datasource fetched %d times, want cached metadata dropped", calls[invalidated.DataSourceUID])
+	}
+	if calls[other.DataSourceUID] != 1 {
+		t.Errorf("other datasource of portal fetched %d times, want its metadata kept", calls[other.DataSourceUID])
+	}
+}
+
+func TestStaleMetadataIsRefreshedAfterRequestEnds(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	requestCtx, cancel := context.WithCancel(context.Background())
+	requestCtx, requestSpan := otel.Tracer("test").Start(requestCtx, "request")
+	client := httpclient.SantabaClient{
+		DataSourceUID:  t.Name(),
+		PluginSettings: &models.PluginSettings{Path: "portal"},
+		AuthSettings:   &models.AuthSettings{},
+		Logger:         log.DefaultLogger,
+		Ctx:            requestCtx,
+	}
+	refreshed := make(chan error, 1)
+	body := "stale"
+	fetch := func(santabaClient httpclient.SantabaClient) ([]byte, error) {
+		if body == "stale" {
+			return []byte(body), nil
+		}
+		refreshed <- santabaClient.Ctx.Err()
+		return []byte("fresh"), nil
+	}
+	get := func() string {
+		respByte, err := cache.GetMetadata(client, constants.DataSourceReq, "setting/datasources", fetch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(respByte)
+	}
+
+	get()
+	cache.ExpireMetadata(client, "setting/datasources")
+	body = "fresh"
+	// refresh runs after resource call is answered, when grafana has cancelled its context
+	requestSpan.End()
+	cancel()
+	if got := get(); got != "stale" {
+		t.Errorf("stale entry answered %q, want it served while refreshing", got)
+	}
+
+	select {
+	case err := <-refreshed:
+		if err != nil {
+			t.Errorf("refresh called santaba with context of ended request: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stale entry was not refreshed")
+	}
+	for deadline := time.Now().Add(time.Second); get() != "fresh"; {
+		if time.Now().After(deadline) {
+			t.Fatal("refreshed response is not cached")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, span := range recorder.Ended() {
+		if span.Name() != "cache.RefreshMetadata" {
+			continue
+		}
+		links := span.Links()
+		if span.Parent().IsValid() || len(links) != 1 || links[0].SpanContext.SpanID() != requestSpan.SpanContext().SpanID() {
+			t.Errorf("refresh span has parent %v and links %v, want new trace linked to request span", span.Parent(), links)
+		}
+		return
+	}
+	t.Error("refresh span not recorded")
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ReneKroon/ttlcache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	httpclient "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+)
+
+// Stores devices matching a property filter. Resolving filter needs all devices with properties, which is an expensive call
+// on large portals, so resolution is cached and shared by all queries using the same filter
+var propertyFilterCache = ttlcache.NewCache() //nolint:gochecknoglobals
+
+// ResolvePropertyFilter returns devices matching filter, cached by filterKey which identifies the filter, like its expression
+func ResolvePropertyFilter(santabaClient httpclient.SantabaClient, queryModel models.QueryModel, filterKey string,
+	filter utils.PropertyFilter) ([]models.ResolvedDevice, error) {
+	key := santabaClient.CacheScope() + filterKey
+	if v, ok := propertyFilterCache.Get(key); ok {
+		return v.([]models.ResolvedDevice), nil
+	}
+	requestURL := utils.BuildURLReplacingQueryParams(constants.PropertyDevicesReq, &queryModel, 0, 0, models.MetaData{})
+	resolved := []models.ResolvedDevice{}
+	// devices are matched page by page, only matching ones are kept
+	_, err := santabaClient.ForEachPage(requestURL, constants.PropertyDevicesReq, func(items []json.RawMessage) error {
+		for _, item := range items {
+			var device models.Device
+			if err := json.Unmarshal(item, &device); err != nil {
+				santabaClient.Logger.Error(constants.ErrorUnmarshallingErrorData+"devices =>", err.Error())
+				return err //nolint:wrapcheck
+			}
+			properties := getDeviceProperties(device)
+			if filter.Match(properties) {
+				resolved = append(resolved, models.ResolvedDevice{
+					Id:          strconv.FormatInt(device.Id, 10),
+					DisplayName: device.DisplayName,
+					Properties:  properties,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		santabaClient.Logger.Error("Error from server => ", err)
+		return nil, err //nolint:wrapcheck
+	}
+	sort.SliceStable(resolved, func(i, j int) bool { return resolved[i].DisplayName < resolved[j].DisplayName })
+	propertyFilterCache.SetWithTTL(key, resolved, time.Duration(constants.PropertyFilterCacheTTLMinutes)*time.Minute)
+	return resolved, nil
+}
+
+// Properties set on device take precedence over inherited ones, same as in LM
+func getDeviceProperties(device models.Device) map[string]string {
+	properties := make(map[string]string)
+	for _, propertyList := range [][]models.Property{device.InheritedProperties, device.AutoProperties,
+		device.SystemProperties, device.CustomProperties} {
+		for _, property := range propertyList {
+			properties[property.Name] = property.Value
+		}
+	}
+	return properties
+}
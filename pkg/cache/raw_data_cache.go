@@ -1,24 +1,82 @@
 package cache
 
 import (
-	"bytes"
-	"encoding/gob"
+	"encoding/json"
+	"math"
 	"time"
 
-	"github.com/ReneKroon/ttlcache"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 )
 
 // queryEditorTempCache whole raw data response and is used while making selection query editor.
 // this avoids multiple http calls while making selection.
-var rawDataCache = ttlcache.NewCache() //nolint:gochecknoglobals
+var rawDataCache = newIndexedCache("rawData", rawDataCoverage, &cacheCodec{encode: encodeRawData, decode: decodeRawData}) //nolint:gochecknoglobals,lll
+
+// sharedRawData is raw data entry as stored in shared cache, entries are either raw data of single call or calls merged by index
+type sharedRawData struct {
+	Single *models.MultiInstanceRawData         `json:"single,omitempty"`
+	Merged map[int]*models.MultiInstanceRawData `json:"merged,omitempty"`
+}
+
+func encodeRawData(data interface{}) ([]byte, error) {
+	var shared sharedRawData
+	switch v := data.(type) {
+	case *models.MultiInstanceRawData:
+		shared.Single = v
+	case map[int]*models.MultiInstanceRawData:
+		shared.Merged = v
+	}
+	return json.Marshal(shared) //nolint:wrapcheck
+}
+
+func decodeRawData(value []byte) (interface{}, error) {
+	var shared sharedRawData
+	if err := json.Unmarshal(value, &shared); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if shared.Merged != nil {
+		return shared.Merged, nil
+	}
+	return shared.Single, nil
+}
+
+func tagsOf(metaData models.MetaData) models.CacheTags {
+	return models.CacheTags{DataSourceUID: metaData.DataSourceUID, Host: metaData.Host, QueryId: metaData.QueryId}
+}
+
+// Entries are either raw data of single call or calls merged by index
+func rawDataCoverage(data interface{}) (int64, int64, bool) {
+	var rawData []*models.MultiInstanceRawData
+	switch v := data.(type) {
+	case *models.MultiInstanceRawData:
+		rawData = append(rawData, v)
+	case map[int]*models.MultiInstanceRawData:
+		for _, entry := range v {
+			rawData = append(rawData, entry)
+		}
+	}
+	from, to := int64(math.MaxInt64), int64(0)
+	for _, entry := range rawData {
+		for _, valueAndTime := range entry.Data.Instances {
+			for _, t := range valueAndTime.Time {
+				if t < from {
+					from = t
+				}
+				if t > to {
+					to = t
+				}
+			}
+		}
+	}
+	return from, to, to > 0
+}
 
 func GetData(metaData models.MetaData) (interface{}, bool) {
 	if _, ok := rawDataCache.Get(metaData.Id); !ok {
 		if v, ok := rawDataCache.Get(metaData.QueryId); ok {
 			// copy data with query id to ID, Data with ID holds only necessory data not all
-			rawDataCache.SetWithTTL(metaData.Id, v, time.Duration(metaData.CacheTTLInSeconds)*time.Second)
+			rawDataCache.SetWithTTL(metaData.Id, v, time.Duration(metaData.CacheTTLInSeconds)*time.Second, rawDataCache.tags(metaData.QueryId))
 			rawDataCache.Remove(metaData.QueryId)
 		}
 	}
@@ -35,18 +93,14 @@ func GetCount() int {
 }
 
 func GetRealSize(metaData models.MetaData) int {
-	b := new(bytes.Buffer)
-	v, ok := GetData(metaData)
-	if ok {
-		if err := gob.NewEncoder(b).Encode(v); err != nil {
-			return 0
-		}
+	if v, ok := GetData(metaData); ok {
+		return sizeOf(v)
 	}
-	return b.Len()
+	return 0
 }
 
 func StoreData(metaData models.MetaData, rawDataMap *models.MultiInstanceRawData) {
-	rawDataCache.SetWithTTL(metaData.Id, rawDataMap, time.Duration(metaData.CacheTTLInSeconds)*time.Second)
+	rawDataCache.SetWithTTL(metaData.Id, rawDataMap, time.Duration(metaData.CacheTTLInSeconds)*time.Second, tagsOf(metaData))
 }
 
 func StoreDataAt(metaData models.MetaData, presentAt int, newData *models.MultiInstanceRawData, logger log.Logger) {
@@ -62,7 +116,7 @@ func StoreDataAt(metaData models.MetaData, presentAt int, newData *models.MultiI
 	} else {
 		rawDataMap[0] = newData
 	}
-	rawDataCache.SetWithTTL(metaData.Id, rawDataMap, time.Duration(metaData.CacheTTLInSeconds)*time.Second)
+	rawDataCache.SetWithTTL(metaData.Id, rawDataMap, time.Duration(metaData.CacheTTLInSeconds)*time.Second, tagsOf(metaData))
 }
 
 func StoreAdditionalDataAt(index int, dataToAdd *models.MultiInstanceRawData, rawDataMap map[int]*models.MultiInstanceRawData) map[int]*models.MultiInstanceRawData {
@@ -0,0 +1,315 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+)
+
+/*
+RedisBackend is Backend speaking Redis protocol (RESP), it works with redis and compatible servers. Connections are dialed on
+demand and kept idle for reuse, a connection failing a command is closed
+*/
+type RedisBackend struct {
+	address  string
+	password string
+	db       int
+	timeout  time.Duration
+	idle     chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// errRedisNil is reply of GET for missing key
+var errRedisNil = errors.New("redis: nil") //nolint:gochecknoglobals
+
+// NewRedisBackend returns backend of redis at address, password and db are sent on every new connection when set
+func NewRedisBackend(address string, password string, db int) *RedisBackend {
+	return &RedisBackend{
+		address:  address,
+		password: password,
+		db:       db,
+		timeout:  constants.RedisTimeoutSeconds * time.Second,
+		idle:     make(chan *redisConn, constants.RedisMaxIdleConns),
+	}
+}
+
+func (r *RedisBackend) Get(key string) ([]byte, bool, error) {
+	reply, err := r.do("GET", key)
+	if errors.Is(err, errRedisNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf(constants.RedisUnexpectedReply, reply)
+	}
+	return value, true, nil
+}
+
+func (r *RedisBackend) Set(key string, value []byte, ttl time.Duration) error {
+	args := []interface{}{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", ttl.Milliseconds())
+	}
+	_, err := r.do(args...)
+	return err
+}
+
+func (r *RedisBackend) Delete(key string) error {
+	_, err := r.do("DEL", key)
+	return err
+}
+
+/*
+IncrBy creates counter with its expiry, unless present, and increments it in one transaction, so that counter of a time window
+goes away with the window even when the process incrementing it dies midway
+*/
+func (r *RedisBackend) IncrBy(key string, n int64, ttl time.Duration) (int64, error) {
+	var reply interface{}
+	if ttl > 0 {
+		replies, err := r.multi([]interface{}{"SET", key, "0", "PX", ttl.Milliseconds(), "NX"}, []interface{}{"INCRBY", key, n})
+		if err != nil {
+			return 0, err
+		}
+		reply = replies[len(replies)-1]
+	} else {
+		var err error
+		if reply, err = r.do("INCRBY", key, n); err != nil {
+			return 0, err
+		}
+	}
+	value, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf(constants.RedisUnexpectedReply, reply)
+	}
+	return value, nil
+}
+
+// Index is a sorted set scored by expiry in epoch milliseconds, members expired are dropped when members are added
+func (r *RedisBackend) IndexAdd(index string, member string, expireAt time.Time) error {
+	score := "+inf"
+	if !expireAt.IsZero() {
+		score = strconv.FormatInt(expireAt.UnixMilli(), 10)
+	}
+	_, err := r.multi([]interface{}{"ZADD", index, score, member},
+		[]interface{}{"ZREMRANGEBYSCORE", index, "-inf", strconv.FormatInt(time.Now().UnixMilli(), 10)})
+	return err
+}
+
+func (r *RedisBackend) IndexMembers(index string) ([]string, error) {
+	reply, err := r.do("ZRANGEBYSCORE", index, "("+strconv.FormatInt(time.Now().UnixMilli(), 10), "+inf")
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf(constants.RedisUnexpectedReply, reply)
+	}
+	members := make([]string, 0, len(items))
+	for _, item := range items {
+		member, ok := item.([]byte)
+		if !ok {
+			return nil, fmt.Errorf(constants.RedisUnexpectedReply, item)
+		}
+		members = append(members, string(member))
+	}
+	return members, nil
+}
+
+func (r *RedisBackend) IndexRemove(index string, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	args := []interface{}{"ZREM", index}
+	for _, member := range members {
+		args = append(args, member)
+	}
+	_, err := r.do(args...)
+	return err
+}
+
+func (r *RedisBackend) do(args ...interface{}) (interface{}, error) {
+	return r.withConn(func(conn *redisConn) (interface{}, error) {
+		return conn.command(r.timeout, args...)
+	})
+}
+
+// multi runs commands in a MULTI/EXEC transaction and returns their replies, none runs when one of them is rejected
+func (r *RedisBackend) multi(commands ...[]interface{}) ([]interface{}, error) {
+	reply, err := r.withConn(func(conn *redisConn) (interface{}, error) {
+		if _, err := conn.command(r.timeout, "MULTI"); err != nil {
+			return nil, err
+		}
+		for _, args := range commands {
+			if _, err := conn.command(r.timeout, args...); err != nil {
+				// connection goes back to idle connections only when out of the transaction
+				if _, discardErr := conn.command(r.timeout, "DISCARD"); discardErr != nil {
+					return nil, discardErr
+				}
+				return nil, err
+			}
+		}
+		return conn.command(r.timeout, "EXEC")
+	})
+	if err != nil {
+		return nil, err
+	}
+	replies, ok := reply.([]interface{})
+	if !ok || len(replies) != len(commands) {
+		return nil, fmt.Errorf(constants.RedisUnexpectedReply, reply)
+	}
+	for _, reply := range replies {
+		if replyErr, ok := reply.(redisError); ok {
+			return nil, replyErr
+		}
+	}
+	return replies, nil
+}
+
+// withConn runs f on an idle or new connection, connection failing with other than a reply of the server is closed
+func (r *RedisBackend) withConn(f func(conn *redisConn) (interface{}, error)) (interface{}, error) {
+	conn, err := r.conn()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := f(conn)
+	var replyErr redisError
+	if err != nil && !errors.Is(err, errRedisNil) && !errors.As(err, &replyErr) {
+		_ = conn.conn.Close()
+		return nil, err
+	}
+	select {
+	case r.idle <- conn:
+	default:
+		_ = conn.conn.Close()
+	}
+	return reply, err
+}
+
+func (r *RedisBackend) conn() (*redisConn, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, nil
+	default:
+	}
+	netConn, err := net.DialTimeout("tcp", r.address, r.timeout)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	if r.password != "" {
+		if _, err := conn.command(r.timeout, "AUTH", r.password); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+	if r.db != 0 {
+		if _, err := conn.command(r.timeout, "SELECT", r.db); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// redisError is error reply of the server, connection is still usable after it
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func (c *redisConn) command(timeout time.Duration, args ...interface{}) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		var value []byte
+		switch v := arg.(type) {
+		case string:
+			value = []byte(v)
+		case []byte:
+			value = v
+		case int:
+			value = []byte(strconv.Itoa(v))
+		case int64:
+			value = []byte(strconv.FormatInt(v, 10))
+		default:
+			return nil, fmt.Errorf(constants.RedisUnsupportedArgument, arg)
+		}
+		buf = append(buf, "$"+strconv.Itoa(len(value))+"\r\n"...)
+		buf = append(buf, value...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return readReply(c.reader)
+}
+
+// Reads one RESP reply. Bulk strings are []byte, integers int64, arrays []interface{}
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf(constants.RedisUnexpectedReply, line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64) //nolint:wrapcheck
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		if size < 0 {
+			return nil, errRedisNil
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		return value[:size], nil
+	case '*':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		if size < 0 {
+			return nil, errRedisNil
+		}
+		items := make([]interface{}, size)
+		for i := range items {
+			// error of an item, like reply of a command in transaction, is kept as item so that rest of the reply is read
+			var replyErr redisError
+			if items[i], err = readReply(reader); errors.As(err, &replyErr) {
+				items[i] = replyErr
+			} else if err != nil && !errors.Is(err, errRedisNil) {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf(constants.RedisUnexpectedReply, line)
+	}
+}
@@ -1,8 +1,10 @@
 package cache
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
+	"strconv"
 	"sync"
 	"time"
 
@@ -21,10 +23,11 @@ import (
 var mutex sync.Mutex
 
 // TimeRange of all Api calls made so far
-var timeRangeCache = ttlcache.NewCache()
+var timeRangeCache = newIndexedCache("timeRange", timeRangeCoverage, &cacheCodec{encode: encodeTimeRange, decode: decodeTimeRange}) //nolint:gochecknoglobals,lll
 
-// Track API calls made so far current minute
+// Track API calls made so far current minute, apiCallsMutex makes read and update of a tracker one step
 var apiCallsTracker sync.Map
+var apiCallsMutex sync.Mutex
 
 type ApiCallsTracker struct {
 	TimeStamp      int64
@@ -46,7 +49,7 @@ func GetTimeRanges(query backend.DataQuery, queryModel models.QueryModel, metaDa
 	waitSec := checkToWait(metaData, query, queryModel, logger)
 	currentApiCalls := numberOfApiCalls(firstRawDataEntryTimestamp, query.TimeRange.To.Unix(), queryModel)
 	if (waitSec == 0 || response.Error != nil) && (queryModel.MaxNumberOfApiCallPerQuery < 0 || queryModel.MaxNumberOfApiCallPerQuery > currentApiCalls) {
-		if lastRawDataEntryTimestamp > 0 && queryModel.EnableStrategicApiCallFeature {
+		if lastRawDataEntryTimestamp > 0 && queryModel.IncrementalCache() {
 			lastRawDataEntryTimestamp++
 		} else {
 			lastRawDataEntryTimestamp = unixTruncateToNearestMinute(query.TimeRange.From.Unix(), 60)
@@ -87,7 +90,7 @@ func GetTimeRanges(query backend.DataQuery, queryModel models.QueryModel, metaDa
 			logger.Info("Id", metaData.Id)
 			logger.Info(constants.WaitingSecondsForNextData, waitSec)
 		} else if len(prependTimeRangeForApiCall) == 0 && len(appendTimeRangeForApiCall) == 0 &&
-			metaData.IsForLastXTime && queryModel.EnableStrategicApiCallFeature {
+			metaData.IsForLastXTime && queryModel.IncrementalCache() {
 			logger.Warn(constants.NoTimeRangeError)
 		}
 	}
@@ -118,13 +121,14 @@ func calcTimeRanges(timeRangeStart int64, timeRangeEnd int64, queryModel models.
 	if pendingApiCalls > 0 {
 		logger.Warn(constants.PendingApiCallsMsg, pendingApiCalls)
 	}
-	apisCallsSofar := GetNrOfApiCalls(pluginContext.DataSourceInstanceSettings.UID).NrOfCalls
+	apisCallsSofar, reservedApiCalls := reserveApiCalls(pluginContext.DataSourceInstanceSettings.UID, currentApiCalls,
+		queryModel.EnableApiCallThrottler)
 	logger.Debug("Api calls so far this minute", apisCallsSofar)
-	if queryModel.EnableApiCallThrottler && (currentApiCalls+int64(apisCallsSofar)) > constants.MaxApiCallsRateLimit {
-		metaData.PendingApiCalls = (int(currentApiCalls) + apisCallsSofar) - constants.MaxApiCallsRateLimit
-		currentApiCalls = constants.MaxApiCallsRateLimit - int64(apisCallsSofar)
+	if reservedApiCalls < currentApiCalls {
+		metaData.PendingApiCalls = int(currentApiCalls - reservedApiCalls)
+		currentApiCalls = reservedApiCalls
 	}
-	logger.Info("Available nr of Api Calls", constants.MaxApiCallsRateLimit-int64(apisCallsSofar))
+	logger.Info("Available nr of Api Calls", constants.MaxApiCallsRateLimit-apisCallsSofar)
 	logger.Info("Cache size (same as number of panels)", GetCount())
 	pendingTimeRange = make([]models.PendingTimeRange, currentApiCalls)
 	var call int64
@@ -145,7 +149,6 @@ func calcTimeRanges(timeRangeStart int64, timeRangeEnd int64, queryModel models.
 		recordsToAppend = recordsToAppend - constants.MaxNumberOfRecordsPerApiCall
 		timeRangeEnd = from - 1
 	}
-	AddNrOfApiCalls(pluginContext.DataSourceInstanceSettings.UID, int(currentApiCalls))
 	mutex.Unlock()
 	return pendingTimeRange, metaData
 }
@@ -176,7 +179,7 @@ func StoreFirstTimeStamp(metaData models.MetaData, timestamp int64) {
 	if timestamp > 0 && getTimeRange(metaData).startTime > timestamp {
 		timeRange := getTimeRange(metaData)
 		timeRange.startTime = timestamp
-		timeRangeCache.SetWithTTL(metaData.Id, timeRange, time.Duration(metaData.CacheTTLInSeconds+60)*time.Second)
+		timeRangeCache.SetWithTTL(metaData.Id, timeRange, time.Duration(metaData.CacheTTLInSeconds+60)*time.Second, tagsOf(metaData))
 	}
 }
 
@@ -185,11 +188,34 @@ func StoreLastTimeStamp(metaData models.MetaData, timestamp int64) {
 	if timestamp > 0 && getTimeRange(metaData).endTime < timestamp {
 		timeRange := getTimeRange(metaData)
 		timeRange.endTime = timestamp
-		timeRangeCache.SetWithTTL(metaData.Id, timeRange, time.Duration(metaData.CacheTTLInSeconds+60)*time.Second)
+		timeRangeCache.SetWithTTL(metaData.Id, timeRange, time.Duration(metaData.CacheTTLInSeconds+60)*time.Second, tagsOf(metaData))
 	}
 }
 
+// GetCachedTimeRange returns first and last timestamp of data present in cache, ok is false when nothing is cached
+func GetCachedTimeRange(metaData models.MetaData) (int64, int64, bool) {
+	timeRange := getTimeRange(metaData)
+	return timeRange.startTime, timeRange.endTime, timeRange.endTime > 0
+}
+
+// GetNrOfApiCalls returns api calls of current minute. With shared cache, calls are of every process sharing the cache
 func GetNrOfApiCalls(key string) ApiCallsTracker {
+	apiCTrack := getLocalNrOfApiCalls(key)
+	if shared := getBackend(); shared != nil {
+		value, ok, err := shared.Get(sharedApiCallsKey(key))
+		if err != nil {
+			log.DefaultLogger.Warn("Could not read api calls from shared cache, using calls of this process", "error", err)
+			return apiCTrack
+		}
+		apiCTrack.NrOfCalls = 0
+		if ok {
+			apiCTrack.NrOfCalls, _ = strconv.Atoi(string(value))
+		}
+	}
+	return apiCTrack
+}
+
+func getLocalNrOfApiCalls(key string) ApiCallsTracker {
 	v, ok := apiCallsTracker.Load(key)
 	if ok {
 		apiCTrack := v.(ApiCallsTracker)
@@ -201,7 +227,58 @@ func GetNrOfApiCalls(key string) ApiCallsTracker {
 }
 
 func AddNrOfApiCalls(id string, currentApiCalls int) {
-	apiCTrack := GetNrOfApiCalls(id)
+	if shared := getBackend(); shared != nil && currentApiCalls > 0 {
+		if _, err := shared.IncrBy(sharedApiCallsKey(id), int64(currentApiCalls), 2*constants.SharedApiCallsWindowSeconds*time.Second); err != nil {
+			log.DefaultLogger.Warn("Could not add api calls to shared cache", "error", err)
+		}
+	}
+	apiCallsMutex.Lock()
+	defer apiCallsMutex.Unlock()
+	addLocalNrOfApiCalls(id, currentApiCalls)
+}
+
+/*
+reserveApiCalls adds planned calls to api calls of current minute, returning calls made before and calls reserved. With limit,
+calls over constants.MaxApiCallsRateLimit are not reserved. Shared counter is incremented before it is checked, so processes
+sharing the cache cannot take the same remaining calls, calls over the limit are given back
+*/
+func reserveApiCalls(id string, planned int64, limit bool) (int64, int64) {
+	if shared := getBackend(); shared != nil && planned > 0 {
+		key, ttl := sharedApiCallsKey(id), 2*constants.SharedApiCallsWindowSeconds*time.Second
+		total, err := shared.IncrBy(key, planned, ttl)
+		if err == nil {
+			reserved := planned
+			if excess := total - constants.MaxApiCallsRateLimit; limit && excess > 0 {
+				reserved = planned - excess
+				if reserved < 0 {
+					reserved = 0
+				}
+				if _, err = shared.IncrBy(key, reserved-planned, ttl); err != nil {
+					log.DefaultLogger.Warn("Could not give back api calls to shared cache", "error", err)
+				}
+			}
+			apiCallsMutex.Lock()
+			addLocalNrOfApiCalls(id, int(reserved))
+			apiCallsMutex.Unlock()
+			return total - planned, reserved
+		}
+		log.DefaultLogger.Warn("Could not reserve api calls in shared cache, using calls of this process", "error", err)
+	}
+	apiCallsMutex.Lock()
+	defer apiCallsMutex.Unlock()
+	sofar, reserved := int64(getLocalNrOfApiCalls(id).NrOfCalls), planned
+	if limit && sofar+planned > constants.MaxApiCallsRateLimit {
+		reserved = constants.MaxApiCallsRateLimit - sofar
+		if reserved < 0 {
+			reserved = 0
+		}
+	}
+	addLocalNrOfApiCalls(id, int(reserved))
+	return sofar, reserved
+}
+
+func addLocalNrOfApiCalls(id string, currentApiCalls int) {
+	apiCTrack := getLocalNrOfApiCalls(id)
 	if (apiCTrack.TimeStamp + 60) > time.Now().Unix() {
 		apiCallsTracker.Store(id, ApiCallsTracker{
 			TimeStamp:      apiCTrack.TimeStamp,
@@ -217,18 +294,47 @@ func AddNrOfApiCalls(id string, currentApiCalls int) {
 	}
 }
 
+// Counter of api calls of datasource in current minute
+func sharedApiCallsKey(id string) string {
+	window := time.Now().Unix() / constants.SharedApiCallsWindowSeconds
+	return constants.SharedCacheKeyPrefix + "apiCalls:" + id + ":" + strconv.FormatInt(window, 10)
+}
+
 func unixTruncateToNearestMinute(inputTime int64, intervalMin int64) int64 {
 	inputTimeTruncated := time.UnixMilli(inputTime * 1000).Truncate(time.Duration(intervalMin) * time.Second)
 
 	return inputTimeTruncated.Unix()
 }
 
+// Time range is stored in shared cache as [start, end]
+func encodeTimeRange(data interface{}) ([]byte, error) {
+	timeRange, _ := data.(TimeRange)
+	return json.Marshal([2]int64{timeRange.startTime, timeRange.endTime}) //nolint:wrapcheck
+}
+
+func decodeTimeRange(value []byte) (interface{}, error) {
+	var startEnd [2]int64
+	if err := json.Unmarshal(value, &startEnd); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return TimeRange{startTime: startEnd[0], endTime: startEnd[1]}, nil
+}
+
+// Time range cache holds first and last timestamp of raw data in seconds
+func timeRangeCoverage(data interface{}) (int64, int64, bool) {
+	timeRange, ok := data.(TimeRange)
+	if !ok || timeRange.endTime == 0 {
+		return 0, 0, false
+	}
+	return timeRange.startTime * 1000, timeRange.endTime * 1000, true
+}
+
 func getTimeRange(metaData models.MetaData) TimeRange {
 	if v, ok := timeRangeCache.Get(metaData.Id); ok {
 		return v.(TimeRange)
 	} else if v, ok := timeRangeCache.Get(metaData.QueryId); ok {
 		if !metaData.EditMode {
-			timeRangeCache.Set(metaData.Id, v.(TimeRange))
+			timeRangeCache.SetWithTTL(metaData.Id, v.(TimeRange), ttlcache.ItemExpireWithGlobalTTL, timeRangeCache.tags(metaData.QueryId))
 			timeRangeCache.Remove(metaData.QueryId)
 		}
 		return v.(TimeRange)
@@ -1,5 +1,7 @@
 package constants
 
+const PluginId = "logicmonitor-datasource"
+
 const (
 	RootURL               = "https://%s.logicmonitor.com/santaba/rest/"
 	SantabaRestPath       = "/santaba/rest"
@@ -15,11 +17,22 @@ const (
 	NoData             = "No Data"
 	ResponseStr        = "response"
 	TimeStr            = "time"
+	ExportStr          = "export"
+	HostStr            = "host"
+	InstanceStr        = "instance"
+	DataPointStr       = "datapoint"
+	ValueStr           = "value"
 	RequestNotValidStr = "Request not valid"
 )
 
 const (
 	Authorization     = "Authorization"
+	ContentType       = "Content-Type"
+	Accept            = "Accept"
+	ApplicationJSON   = "application/json"
+	ViewerRole        = "Viewer"
+	EditorRole        = "Editor"
+	AdminRole         = "Admin"
 	LMv1              = "LMv1"
 	XVersion          = "x-version"
 	XVersionValue3    = "3"
@@ -31,6 +44,165 @@ const (
 const (
 	Regex  = "Regex"
 	Select = "Select"
+	// Pattern selects instances by include/exclude patterns, Glob and Regex being the pattern syntax
+	Pattern = "Pattern"
+	Glob    = "Glob"
+)
+
+// Scopes of SDTs created from grafana and their LM SDT types
+const (
+	DeviceSdtScope   = "device"
+	GroupSdtScope    = "group"
+	InstanceSdtScope = "instance"
+	DeviceSdtType    = "DeviceSDT"
+	GroupSdtType     = "DeviceGroupSDT"
+	InstanceSdtType  = "DeviceDataSourceInstanceSDT"
+	OneTimeSdt       = "oneTime"
+)
+
+// Request log levels, debug being the default
+const (
+	RequestLogOff   = "off"
+	RequestLogDebug = "debug"
+	RequestLogInfo  = "info"
+	Redacted        = "[REDACTED]"
+)
+
+// Paging of list endpoints. LM caps page size at 1000, list URLs ask for pages of that size
+const (
+	PageSize               = 1000
+	MaxPages               = 1000
+	PageThrottleRemaining  = 5
+	MaxPageThrottleSeconds = 60
+)
+
+// Raw data export formats and their content types
+const (
+	ExportCSV            = "csv"
+	ExportNDJSON         = "ndjson"
+	ExportParquet        = "parquet"
+	CSVContentType       = "text/csv"
+	NDJSONContentType    = "application/x-ndjson"
+	ParquetContentType   = "application/vnd.apache.parquet"
+	ContentDisposition   = "Content-Disposition"
+	ExportFileNameFormat = "attachment; filename=\"logicmonitor-export.%s\""
+	// Windows rate limited by throttler are retried after a wait, as many times as below
+	ExportThrottleWaitSeconds = 60
+	MaxExportThrottleRetries  = 5
+)
+
+// Cache warming. Warming uses at most its share of the per minute API call budget of the datasource
+const (
+	CacheWarmingTickSeconds          = 15
+	DefaultCacheWarmingBudgetPercent = 10
+	MaxCacheWarmingBudgetPercent     = 50
+	DefaultCacheWarmingRangeSeconds  = 3600
+	MinCacheWarmingIntervalSeconds   = 60
+	// Learned queries are kept for a day and more, so that dashboards opened every morning stay warm
+	CacheWarmingLearnedTTLHours = 26
+	MaxCacheWarmingQueries      = 200
+)
+
+// OTLP collector grafana passes to plugins it has tracing enabled for
+const (
+	OTLPAddressEnv     = "GF_INSTANCE_OTLP_ADDRESS"
+	OTLPPropagationEnv = "GF_INSTANCE_OTLP_PROPAGATION"
+	OTLPPropagationW3C = "w3c"
+)
+
+// Shared cache backend of HA deployments, configured by environment of the plugin process
+const (
+	RedisAddressEnv             = "GF_PLUGIN_LOGICMONITOR_REDIS_ADDRESS"
+	RedisPasswordEnv            = "GF_PLUGIN_LOGICMONITOR_REDIS_PASSWORD"
+	RedisDBEnv                  = "GF_PLUGIN_LOGICMONITOR_REDIS_DB"
+	RedisTimeoutSeconds         = 5
+	RedisMaxIdleConns           = 8
+	SharedCacheKeyPrefix        = "lm-grafana:"
+	SharedCacheLocalTTLSeconds  = 5
+	SharedApiCallsWindowSeconds = 60
+	CredentialFingerprintLength = 16
+)
+
+// Health check statuses and thresholds
+const (
+	HealthPass                 = "pass"
+	HealthWarn                 = "warn"
+	HealthFail                 = "fail"
+	HealthSlowLatencyMs        = 2000
+	HealthRateLimitWarnPercent = 10
+	// LMv1 signature carries epoch, portal rejects requests too far from its clock
+	HealthClockSkewWarnMs    = 60 * 1000
+	HealthClockSkewFailMs    = 30 * 60 * 1000
+	RateLimitLimitHeader     = "X-Rate-Limit-Limit"
+	RateLimitRemainingHeader = "X-Rate-Limit-Remaining"
+	RateLimitWindowHeader    = "X-Rate-Limit-Window"
+)
+
+// Period over period comparison of time shifted queries
+const (
+	CompareDelta   = "delta"
+	ComparePercent = "percent"
+	// Suffixes added to instance of shifted and comparison series
+	ShiftedSuffix = " (%s ago)"
+	DeltaSuffix   = " (delta %s)"
+	PercentSuffix = " (%% change %s)"
+)
+
+const (
+	NormalGroupType     = "Normal"
+	BizServiceGroupType = "BizService"
+)
+
+/*
+Cache strategies of queries. Incremental keeps raw data of a query across time ranges and fetches only what is missing, window
+caches the one-minute aligned time range of the query on its own, for when ranges are read once, like by export.
+*/
+const (
+	IncrementalCacheStrategy = "incremental"
+	WindowCacheStrategy      = "window"
+)
+
+// Query modes and annotation types as sent by the query editor.
+const (
+	TimeSeriesQueryMode = "TimeSeries"
+	AnnotationQueryMode = "Annotation"
+	RankingQueryMode    = "Ranking"
+	AlertAnnotation     = "alerts"
+	OpsNoteAnnotation   = "opsnotes"
+	SdtAnnotation       = "sdts"
+	AnnotationsStr      = "annotations"
+)
+
+// Downsample functions applied when query interval or max data points is larger than collect interval.
+const (
+	DownsampleAvg  = "avg"
+	DownsampleMin  = "min"
+	DownsampleMax  = "max"
+	DownsampleLast = "last"
+	DownsampleLttb = "lttb"
+)
+
+// Null policies, how missing values ("No Data" from LM and inserted gaps) are sent to grafana.
+const (
+	NullAsNaN      = "nan"
+	NullAsNull     = "null"
+	NullAsZero     = "zero"
+	NullAsPrevious = "previous"
+	NullAsDrop     = "drop"
+)
+
+// Ranking options, series are reduced to a score and top/bottom N are returned as series or as table of scores.
+const (
+	ReducerAvg       = "avg"
+	ReducerMin       = "min"
+	ReducerMax       = "max"
+	ReducerLast      = "last"
+	ReducerP95       = "p95"
+	RankTop          = "top"
+	RankBottom       = "bottom"
+	RankSeriesFormat = "series"
+	RankTableFormat  = "table"
+	RankingStr       = "ranking"
 )
 
 const (
@@ -69,6 +241,8 @@ const (
 	TotalApiCallsInLastOneMinute      = "Total API calls in last one minute"
 	APICallSMoreThanRateLimit         = "%d API calls required! causes rate limit error, please reduce the time range"
 	AuthSuccessMsg                    = "Authentication Success"
+	HealthWarningsMsg                 = "Authentication Success with warnings: %s"
+	HealthFailedMsg                   = "Health check failed: %s"
 	InternalServerErrorJsonErrMessage = `{ "error":"%s"}`
 	HttpNotOk                         = "HTTPS STATUS = %d, Message = %s"
 	MoreThanOneHostDataSources        = "Selected variable host on variable has more than one hostDatasources for ds = "
@@ -78,6 +252,41 @@ const (
 	NoTimeRangeError                  = "no timeRange for API call"
 	WaitingSecondsForNextData         = "Waiting seconds for next data"
 	NoHostFoundForGivenGlobPattern    = "No host found for globe pattern = %s"
+	AnnotationScopeMissing            = "select a device or group for annotations"
+	InvalidAnnotationType             = "invalid annotation type = %s"
+	NonNumericDataPoint               = "non numeric value for datapoint = %s, instance = %s, value = %v"
+	DataPointDefinitionErrMsg         = "Could not get datapoint definitions, fields are sent without config"
+	WidgetTypeNotSupported            = "widget type %s is not supported"
+	WidgetHasNoDataPoints             = "widget has no datapoints"
+	MultiDeviceGlobNotSupported       = "device glob %s matches multiple devices, only single device per query is supported"
+	VirtualDataPointNotTranslated     = "virtual datapoint %s = %s could not be translated"
+	NoDeviceMatchingPropertyFilter    = "no device matching property filter = %s"
+	TooManyDevicesForPropertyFilter   = "%d devices match property filter, maximum is %d. Please narrow down the filter"
+	NoDeviceInGroup                   = "no device matching %s in group %s"
+	TooManyDevicesInGroup             = "%d devices in group to rank, maximum is %d. Please narrow down the group or host"
+	InvalidInstancePattern            = "invalid instance pattern %s: %w"
+	InvalidTimeShift                  = "invalid time shift %s, expected a positive duration like 1h, 7d or 1w"
+	TimeShiftFailed                   = "query shifted by %s failed, series of shifted range are missing: %w"
+	InvalidCompareMode                = "invalid compare mode = %s"
+	ActionsDisabledErrMsg             = "Actions are disabled for this datasource"
+	ActionRoleErrMsg                  = "Role %s is required for this action"
+	MethodNotAllowedErrMsg            = "Method not allowed"
+	ResourceNotFoundErrMsg            = "Resource not found"
+	InvalidParameterErrMsg            = "Invalid %s = %s"
+	UnsupportedSchemaVersion          = "query schema version %d is newer than supported version %d, please upgrade the plugin"
+	InvalidSdtScope                   = "invalid SDT scope = %s"
+	InvalidSdtTimeRange               = "SDT end time must be after start time"
+	MissingActionField                = "%s is required"
+	TooManyPages                      = "Stopped paging after %d pages of %s"
+	PageThrottled                     = "Only %d API calls left in rate limit window, waiting %d seconds before next page"
+	DeviceLabel                       = "device"
+	SystemGroupsProperty              = "system.groups"
+	SystemDisplayNameProperty         = "system.displayname"
+	InvalidExportFormat               = "invalid export format = %s, expected csv, ndjson or parquet"
+	InvalidExportTimeRange            = "export end time must be after start time"
+	ExportPropertyFilterUnsupported   = "export of property filter queries is not supported, select a host"
+	RedisUnexpectedReply              = "unexpected redis reply %v"
+	RedisUnsupportedArgument          = "unsupported redis argument %T"
 )
 
 // These constants are from PathEndpoints.ts.
@@ -96,6 +305,22 @@ const (
 	RawDataSingleInstaceReq = "RawDataReq"
 	RawDataMultiInstanceReq = "RawDataMultiInstanceReq"
 	HealthCheckReq          = "HealthCheckReq"
+	DeviceAlertReq          = "DeviceAlertReq"
+	GroupAlertReq           = "GroupAlertReq"
+	DeviceActiveAlertReq    = "DeviceActiveAlertReq"
+	GroupActiveAlertReq     = "GroupActiveAlertReq"
+	DeviceOpsNoteReq        = "DeviceOpsNoteReq"
+	GroupOpsNoteReq         = "GroupOpsNoteReq"
+	DeviceSdtReq            = "DeviceSdtReq"
+	GroupSdtReq             = "GroupSdtReq"
+	ImportDashboardReq      = "ImportDashboardReq"
+	DashboardWidgetsReq     = "DashboardWidgetsReq"
+	InvalidateMetadataReq   = "InvalidateMetadataReq"
+	AckAlertReq             = "AckAlertReq"
+	CreateSdtReq            = "CreateSdtReq"
+	DeleteSdtReq            = "DeleteSdtReq"
+	PropertyDevicesReq      = "PropertyDevicesReq"
+	InstancePropertiesReq   = "InstancePropertiesReq"
 )
 
 const (
@@ -112,9 +337,9 @@ const (
 	HostParentFilters    = `[{"filter":"%s","exclude":false,"token":"fullname","matchFilterAsGlob":true}]`
 	AutoCompleteHostsURL = `autocomplete/names?queryToken=display&needIdPrefix=true&size=10&_=%d&type=hostChain&query=%s&parentsFilters=` //nolint:lll
 
-	DataSourceURL = `device/devices/%s/devicedatasources?format=json&fields=id,dataSourceDisplayName,dataSourceId,instanceNumber&size=-1&filter=instanceNumber>:1` //nolint:lll
+	DataSourceURL = `device/devices/%s/devicedatasources?format=json&fields=id,dataSourceDisplayName,dataSourceId,instanceNumber&size=1000&filter=instanceNumber>:1` //nolint:lll
 
-	HostDataSourceURL = `device/devices/%s/devicedatasources?format=json&fields=id&size=-1&filter=dataSourceId:%d,instanceNumber>:1` //nolint:lll
+	HostDataSourceURL = `device/devices/%s/devicedatasources?format=json&fields=id&size=1000&filter=dataSourceId:%d,instanceNumber>:1` //nolint:lll
 
 	InstanceParentFilters = `[{"filter":"%s","exclude":false,"token":"fullname","matchFilterAsGlob":true},{"filter":"%s","exclude":false,"token":"display","matchFilterAsGlob":true},{"filter":"%s","exclude":false,"token":"display","matchFilterAsGlob":false}]` //nolint:lll
 
@@ -124,6 +349,9 @@ const (
 	DataPointURL = "setting/datasources/%d?format=json&fields=dataPoints,collectInterval"
 
 	HealthCheckURL = "device/devices?size=1"
+	// HealthAlertsURL and HealthLogsURL = Cheapest reads of alerts and audit logs, used to check permissions of the key.
+	HealthAlertsURL = "alert/alerts?size=1&fields=id"
+	HealthLogsURL   = "setting/accesslogs?size=1&fields=id"
 
 	RawDataSingleInstanceURL = "device/devices/%s/devicedatasources/%d/instances/%s/data?start=%d&end=%d"
 
@@ -132,10 +360,40 @@ const (
 	RawDataMultiInstanceURLWithDpFilter = "device/devices/%s/devicedatasources/%d/data?start=%d&end=%d&datapoints=%s"
 
 	// AllHostURL = Get All Hosts.
-	AllHostURL = "device/devices?format=json&fields=id,displayName&size=-1"
+	AllHostURL = "device/devices?format=json&fields=id,displayName&size=1000"
 
 	// AllInstanceURL = Get All Instances by hostId and Host Datasource Id.
-	AllInstanceURL = "device/devices/%s/devicedatasources/%d/instances?format=json&fields=id,name&size=-1"
+	AllInstanceURL = "device/devices/%s/devicedatasources/%d/instances?format=json&fields=id,name&size=1000"
+
+	// InstancePropertiesURL = Get All Instances with their properties by hostId and Host Datasource Id.
+	InstancePropertiesURL = "device/devices/%s/devicedatasources/%d/instances?format=json&fields=id,name,displayName,description,systemProperties,autoProperties,customProperties&size=1000" //nolint:lll
+
+	// DeviceAlertURL and GroupAlertURL = Cleared alerts overlapping the time range, filled with id, end and start of the range.
+	DeviceAlertURL = "device/devices/%s/alerts?format=json&size=1000&filter=startEpoch<:%d,endEpoch>:%d,cleared:true"
+	GroupAlertURL  = "device/groups/%d/alerts?format=json&size=1000&filter=startEpoch<:%d,endEpoch>:%d,cleared:true"
+	// DeviceActiveAlertURL and GroupActiveAlertURL = Active alerts started before end of the time range.
+	DeviceActiveAlertURL = "device/devices/%s/alerts?format=json&size=1000&filter=startEpoch<:%d,cleared:false"
+	GroupActiveAlertURL  = "device/groups/%d/alerts?format=json&size=1000&filter=startEpoch<:%d,cleared:false"
+
+	// DeviceOpsNoteURL and GroupOpsNoteURL = OpsNotes in the time range scoped to a device or group.
+	DeviceOpsNoteURL = "setting/opsnotes?format=json&size=1000&filter=happenOnInSec>:%d,happenOnInSec<:%d,monitorObjectNames:%s"
+	GroupOpsNoteURL  = "setting/opsnotes?format=json&size=1000&filter=happenOnInSec>:%d,happenOnInSec<:%d,monitorObjectGroups:%s"
+
+	// DeviceSdtURL and GroupSdtURL = SDTs of a device or group, filtered on time range after fetching.
+	DeviceSdtURL = "device/devices/%s/sdts?format=json&size=1000"
+	GroupSdtURL  = "device/groups/%d/sdts?format=json&size=1000"
+
+	// PropertyDevicesURL = All devices with properties, property filter of query is applied on these.
+	PropertyDevicesURL = "device/devices?format=json&fields=id,displayName,systemProperties,customProperties,inheritedProperties,autoProperties&size=1000"
+
+	// AckAlertURL, SdtURL and SdtByIdURL = Write APIs used by alert acknowledgement and SDT actions.
+	AckAlertURL = "alert/alerts/%s/ack"
+	SdtURL      = "sdt/sdts"
+	SdtByIdURL  = "sdt/sdts/%s"
+
+	// DashboardURL and DashboardWidgetsURL = LM dashboard with widget positions and its widgets, used to import dashboard.
+	DashboardURL        = "dashboard/dashboards/%d?format=json&fields=id,name,description,widgetsConfig"
+	DashboardWidgetsURL = "dashboard/dashboards/%d/widgets?format=json&size=1000"
 )
 
 const (
@@ -152,4 +410,25 @@ const (
 	MaxNumberOfRecordsPerApiCall                = 500
 	MaxApiCallsRateLimit                        = 500
 	EditModeLastingSeconds                      = 60 // this is the seconds EditMode will be lasted after last dit on query
+	AnnotationCacheTTLInSeconds                 = 60
+	DataPointDefinitionCacheTTLMinutes          = 60
+	DataPointDefinitionNegativeCacheTTLSeconds  = 60
+	PropertyFilterCacheTTLMinutes               = 10
+	MaxDevicesPerPropertyFilter                 = 50
+	InstancePropertyCacheTTLMinutes             = 10
+	MetadataNegativeCacheTTLSeconds             = 15
+	ExportCacheTTLInSeconds                     = 5
+)
+
+// LM widget types translated on dashboard import.
+const (
+	CustomGraphWidget = "cgraph"
+	TextWidget        = "text"
+)
+
+// Datapoint types as defined in datasource definition.
+const (
+	CounterDataPoint = 1
+	GaugeDataPoint   = 2
+	DeriveDataPoint  = 3
 )
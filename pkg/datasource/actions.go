@@ -0,0 +1,111 @@
+package datasource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+)
+
+var roleRank = map[string]int{ //nolint:gochecknoglobals
+	constants.ViewerRole: 1,
+	constants.EditorRole: 2,
+	constants.AdminRole:  3,
+}
+
+/*
+Actions write to LM with credentials of the datasource, not of the grafana user. They are off unless enabled in datasource
+settings and are allowed only for users having at least the configured role, Editor by default. Every attempt is audit logged
+*/
+func (ds *LogicmonitorDataSource) actionHandler(action string) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		user, role := "unknown", ""
+		if grafanaUser := httpadapter.UserFromContext(r.Context()); grafanaUser != nil {
+			user, role = grafanaUser.Login, grafanaUser.Role
+		}
+		minRole := ds.santabaClient.PluginSettings.ActionsMinRole
+		if _, ok := roleRank[minRole]; !ok {
+			minRole = constants.EditorRole
+		}
+
+		status, message := http.StatusOK, ""
+		switch {
+		case !ds.santabaClient.PluginSettings.EnableActions:
+			status, message = http.StatusForbidden, constants.ActionsDisabledErrMsg
+		case roleRank[role] < roleRank[minRole]:
+			status, message = http.StatusForbidden, fmt.Sprintf(constants.ActionRoleErrMsg, minRole)
+		}
+		if status != http.StatusOK {
+			ds.Logger.Warn("Audit: action rejected", "user", user, "role", role, "action", action, "reason", message)
+			writeError(w, status, message)
+			return
+		}
+
+		// body is nil for calls without one, like DELETE /sdts/{sdtId}
+		var body []byte
+		var err error
+		if r.Body != nil {
+			body, err = ioutil.ReadAll(r.Body)
+		}
+		if err == nil {
+			body, err = withPathParams(body, params)
+		}
+		var respByte []byte
+		if err == nil {
+			respByte, err = doAction(action, body, ds.clientFor(r))
+		}
+		if err != nil {
+			ds.Logger.Warn("Audit: action failed", "user", user, "role", role, "action", action, "request", string(body), "error", err)
+			writeError(w, errorStatus(err), err.Error())
+			return
+		}
+		ds.Logger.Info("Audit: action done", "user", user, "role", role, "action", action, "request", string(body))
+		writeJSON(w, http.StatusOK, respByte)
+	}
+}
+
+// Path parameters, like sdtId of DELETE /sdts/{sdtId}, are merged into the body
+func withPathParams(body []byte, params map[string]string) ([]byte, error) {
+	if len(params) == 0 {
+		return body, nil
+	}
+	merged := make(map[string]interface{})
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &merged); err != nil {
+			return body, fmt.Errorf("%w: %s", logicmonitor.ErrInvalidAction, err.Error())
+		}
+	}
+	for k, v := range params {
+		merged[k] = v
+	}
+	return json.Marshal(merged) //nolint:wrapcheck
+}
+
+func doAction(action string, body []byte, santabaClient httpclient.SantabaClient) ([]byte, error) {
+	switch action {
+	case constants.AckAlertReq:
+		var request models.AckAlertRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return nil, fmt.Errorf("%w: %s", logicmonitor.ErrInvalidAction, err.Error())
+		}
+		return logicmonitor.AckAlert(request, santabaClient) //nolint:wrapcheck
+	case constants.CreateSdtReq:
+		var request models.CreateSdtRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return nil, fmt.Errorf("%w: %s", logicmonitor.ErrInvalidAction, err.Error())
+		}
+		return logicmonitor.CreateSdt(request, santabaClient) //nolint:wrapcheck
+	default:
+		var request models.DeleteSdtRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			return nil, fmt.Errorf("%w: %s", logicmonitor.ErrInvalidAction, err.Error())
+		}
+		return logicmonitor.DeleteSdt(request, santabaClient) //nolint:wrapcheck
+	}
+}
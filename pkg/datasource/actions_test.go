@@ -0,0 +1,111 @@
+package datasource_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+)
+
+const ackBody = `{"alertId":"LMA1","comment":"on it"}`
+
+func TestActionsAreGatedOnSettingAndRole(t *testing.T) {
+	cases := []struct {
+		name     string
+		jsonData string
+		role     string
+		status   int
+		error    string
+	}{
+		{"disabled by default", "", constants.AdminRole, http.StatusForbidden, constants.ActionsDisabledErrMsg},
+		{"viewer below default role", `{"enableActions":true}`, constants.ViewerRole, http.StatusForbidden,
+			fmt.Sprintf(constants.ActionRoleErrMsg, constants.EditorRole)},
+		{"no role below default role", `{"enableActions":true}`, "", http.StatusForbidden,
+			fmt.Sprintf(constants.ActionRoleErrMsg, constants.EditorRole)},
+		{"editor at default role", `{"enableActions":true}`, constants.EditorRole, http.StatusOK, ""},
+		{"unknown minimum role is editor", `{"enableActions":true,"actionsMinRole":"Owner"}`, constants.ViewerRole, http.StatusForbidden,
+			fmt.Sprintf(constants.ActionRoleErrMsg, constants.EditorRole)},
+		{"editor below admin role", `{"enableActions":true,"actionsMinRole":"Admin"}`, constants.EditorRole, http.StatusForbidden,
+			fmt.Sprintf(constants.ActionRoleErrMsg, constants.AdminRole)},
+		{"admin at admin role", `{"enableActions":true,"actionsMinRole":"Admin"}`, constants.AdminRole, http.StatusOK, ""},
+		{"viewer at viewer role", `{"enableActions":true,"actionsMinRole":"Viewer"}`, constants.ViewerRole, http.StatusOK, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stub := (&testutil.SantabaStub{}).Route("alert/alerts/LMA1/ack", http.StatusOK, testutil.Envelope(`{}`))
+			ds, pluginContext := newDataSource(t, stub, c.jsonData)
+			logger := &testutil.Logger{}
+			ds.Logger = logger
+
+			status, body := callResource(t, ds, pluginContext, c.role, http.MethodPost, "alerts/ack", ackBody)
+			if status != c.status || !strings.Contains(body, c.error) {
+				t.Errorf("answered %d %s, want %d containing %q", status, body, c.status, c.error)
+			}
+			requests := len(stub.Requests("alert/alerts/LMA1/ack"))
+			if c.status == http.StatusOK {
+				if requests != 1 || len(logger.Logged("Audit: action done")) != 1 {
+					t.Errorf("allowed action requested LM %d times and logged %d done audits", requests, len(logger.Logged("Audit: action done")))
+				}
+				return
+			}
+			if requests > 0 {
+				t.Errorf("rejected action requested LM %d times", requests)
+			}
+			rejected := logger.Logged("Audit: action rejected")
+			if len(rejected) != 1 {
+				t.Fatalf("rejected action logged %d rejected audits", len(rejected))
+			}
+			if reason, _ := rejected[0].Arg("reason"); reason != c.error {
+				t.Errorf("rejected audit has reason %v, want %s", reason, c.error)
+			}
+		})
+	}
+}
+
+func TestActionsAreAuditLogged(t *testing.T) {
+	stub := (&testutil.SantabaStub{}).
+		Route("alert/alerts/LMA1/ack", http.StatusOK, testutil.Envelope(`{}`)).
+		Route("sdt/sdts/D_5", http.StatusOK, testutil.Envelope(`{}`))
+	ds, pluginContext := newDataSource(t, stub, `{"enableActions":true}`)
+	logger := &testutil.Logger{}
+	ds.Logger = logger
+
+	if status, body := callResource(t, ds, pluginContext, constants.EditorRole, http.MethodPost, "alerts/ack", ackBody); status != http.StatusOK {
+		t.Fatalf("ack answered %d %s", status, body)
+	}
+	if status, body := callResource(t, ds, pluginContext, constants.EditorRole, http.MethodDelete, "sdts/D_5", ""); status != http.StatusOK {
+		t.Fatalf("delete sdt answered %d %s", status, body)
+	}
+	if requests := stub.Requests("sdt/sdts/D_5"); len(requests) != 1 || requests[0].Method != http.MethodDelete {
+		t.Errorf("delete sdt requested %v", requests)
+	}
+	status, body := callResource(t, ds, pluginContext, constants.EditorRole, http.MethodPost, "alerts/ack", `{"alertId":"LMA1"}`)
+	if status != http.StatusBadRequest || !strings.Contains(body, "comment is required") {
+		t.Errorf("ack without comment answered %d %s", status, body)
+	}
+
+	done := logger.Logged("Audit: action done")
+	if len(done) != 2 {
+		t.Fatalf("logged %d done audits, want 2", len(done))
+	}
+	for i, action := range []string{constants.AckAlertReq, constants.DeleteSdtReq} {
+		for key, want := range map[string]string{"user": "user", "role": constants.EditorRole, "action": action} {
+			if got, _ := done[i].Arg(key); got != want {
+				t.Errorf("done audit %d has %s = %v, want %s", i, key, got, want)
+			}
+		}
+	}
+	if request, _ := done[1].Arg("request"); !strings.Contains(fmt.Sprint(request), `"sdtId":"D_5"`) {
+		t.Errorf("delete sdt audit has request %v, want sdtId of the path", request)
+	}
+	failed := logger.Logged("Audit: action failed")
+	if len(failed) != 1 {
+		t.Fatalf("logged %d failed audits, want 1", len(failed))
+	}
+	if err, _ := failed[0].Arg("error"); !strings.Contains(fmt.Sprint(err), "comment is required") {
+		t.Errorf("failed audit has error %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package datasource
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+)
+
+// adminOnly lets only grafana admins through, cache entries show hosts and queries of every dashboard of the datasource
+func (ds *LogicmonitorDataSource) adminOnly(next handlerFunc) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		user, role := "unknown", ""
+		if grafanaUser := httpadapter.UserFromContext(r.Context()); grafanaUser != nil {
+			user, role = grafanaUser.Login, grafanaUser.Role
+		}
+		if roleRank[role] < roleRank[constants.AdminRole] {
+			ds.Logger.Warn("Audit: cache admin rejected", "user", user, "role", role, "path", r.URL.Path)
+			writeError(w, http.StatusForbidden, fmt.Sprintf(constants.ActionRoleErrMsg, constants.AdminRole))
+			return
+		}
+		next(w, r, params)
+	}
+}
+
+/*
+Entries of this datasource are selected, optionally of host and query id given as query parameters. Entries of other datasources
+are never reached, admins of this datasource may not be admins of those
+*/
+func cacheFilter(ds *LogicmonitorDataSource, r *http.Request) models.CacheFilter {
+	query := r.URL.Query()
+	return models.CacheFilter{DataSourceUID: ds.santabaClient.DataSourceUID, Host: query.Get("host"), QueryId: query.Get("queryId")}
+}
+
+func (ds *LogicmonitorDataSource) listCacheEntries(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	entries := cache.ListCacheEntries(cacheFilter(ds, r))
+	if entries == nil {
+		entries = []models.CacheEntryInfo{}
+	}
+	respByte, err := json.Marshal(entries)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, respByte)
+}
+
+func (ds *LogicmonitorDataSource) invalidateCacheEntries(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	filter := cacheFilter(ds, r)
+	removed := cache.InvalidateCacheEntries(filter)
+	if filter.Host == "" && filter.QueryId == "" {
+		cache.InvalidateMetadata(ds.santabaClient)
+	}
+	user := "unknown"
+	if grafanaUser := httpadapter.UserFromContext(r.Context()); grafanaUser != nil {
+		user = grafanaUser.Login
+	}
+	ds.Logger.Info("Audit: cache invalidated", "user", user, "host", filter.Host, "queryId", filter.QueryId, "removed", removed)
+	respByte, _ := json.Marshal(map[string]int{"removed": removed})
+	writeJSON(w, http.StatusOK, respByte)
+}
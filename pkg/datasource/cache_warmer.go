@@ -0,0 +1,247 @@
+package datasource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+/*
+cacheWarmer keeps raw data cache of queries warm, so that dashboards opened at once do not hit the rate limit on cold cache.
+Queries are configured in datasource settings or learned from QueryData traffic of time ranges ending now. Each query is run
+through the normal query path once per collect interval, for its time range ending now. Warming uses at most its share of
+the per minute API call budget of the datasource, the rest stays reserved for dashboards. Only queries cached incrementally
+are warmed, see warmInterval
+*/
+type cacheWarmer struct {
+	santabaClient httpclient.SantabaClient
+	dataSourceUID string
+	learn         bool
+	budget        int64
+	// calls counts every http request made by warming, calls of current minute are calls less callsAtMinute
+	calls         int64
+	minute        int64
+	callsAtMinute int64
+	mutex         sync.Mutex
+	queries       map[string]*warmQuery
+	ctx           context.Context
+	cancel        context.CancelFunc
+	done          chan struct{}
+}
+
+type warmQuery struct {
+	query         backend.DataQuery
+	pluginContext backend.PluginContext
+	rangeSeconds  int64
+	interval      time.Duration
+	nextRun       time.Time
+	// lastSeen is zero for configured queries, learned queries are forgotten when not seen for a while
+	lastSeen time.Time
+}
+
+func newCacheWarmer(ds *LogicmonitorDataSource) *cacheWarmer {
+	settings := ds.santabaClient.PluginSettings
+	budgetPercent := settings.CacheWarmingBudgetPercent
+	if budgetPercent <= 0 {
+		budgetPercent = constants.DefaultCacheWarmingBudgetPercent
+	}
+	if budgetPercent > constants.MaxCacheWarmingBudgetPercent {
+		budgetPercent = constants.MaxCacheWarmingBudgetPercent
+	}
+	warmer := &cacheWarmer{
+		santabaClient: ds.santabaClient,
+		dataSourceUID: ds.dsInfo.UID,
+		learn:         settings.CacheWarmingLearn,
+		budget:        int64(constants.MaxApiCallsRateLimit * budgetPercent / 100),
+		queries:       make(map[string]*warmQuery),
+		done:          make(chan struct{}),
+	}
+	warmer.ctx, warmer.cancel = context.WithCancel(context.Background())
+	warmer.santabaClient.Ctx = warmer.ctx
+	warmer.santabaClient.Client = &http.Client{
+		Transport: &countingTransport{base: ds.santabaClient.Client.Transport, calls: &warmer.calls},
+		Timeout:   ds.santabaClient.Client.Timeout,
+	}
+	pluginContext := backend.PluginContext{DataSourceInstanceSettings: ds.dsInfo}
+	now := time.Now()
+	for i, configured := range settings.CacheWarmingQueries {
+		rangeSeconds := configured.RangeSeconds
+		if rangeSeconds <= 0 {
+			rangeSeconds = constants.DefaultCacheWarmingRangeSeconds
+		}
+		query := backend.DataQuery{RefID: "warm" + strconv.Itoa(i), JSON: configured.Query}
+		if interval, ok := warmInterval(query); ok {
+			warmer.queries[warmKey(query, rangeSeconds)] = &warmQuery{query: query, pluginContext: pluginContext,
+				rangeSeconds: rangeSeconds, interval: interval, nextRun: now}
+		} else {
+			ds.Logger.Warn("Skipping cache warming query that is not an incrementally cached data query", "index", i)
+		}
+	}
+	return warmer
+}
+
+func (w *cacheWarmer) start() {
+	go w.run()
+}
+
+// stop cancels calls in flight and waits for the warmer to exit
+func (w *cacheWarmer) stop() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *cacheWarmer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(constants.CacheWarmingTickSeconds * time.Second)
+	defer ticker.Stop()
+	for {
+		w.warmDueQueries()
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *cacheWarmer) warmDueQueries() {
+	for _, query := range w.dueQueries(time.Now()) {
+		if w.ctx.Err() != nil || !w.hasBudget(time.Now()) {
+			return
+		}
+		w.warm(query, time.Now())
+	}
+}
+
+// Queries due to run, the most overdue first. Learned queries not seen for long are forgotten
+func (w *cacheWarmer) dueQueries(now time.Time) []*warmQuery {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var due []*warmQuery
+	for key, query := range w.queries {
+		if !query.lastSeen.IsZero() && now.Sub(query.lastSeen) > constants.CacheWarmingLearnedTTLHours*time.Hour {
+			delete(w.queries, key)
+			continue
+		}
+		if !query.nextRun.After(now) {
+			due = append(due, query)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].nextRun.Before(due[j].nextRun) })
+	return due
+}
+
+// Warming stops for the minute when its share is used, or when dashboards have used the whole budget
+func (w *cacheWarmer) hasBudget(now time.Time) bool {
+	calls := atomic.LoadInt64(&w.calls)
+	if minute := now.Unix() / 60; minute != w.minute {
+		w.minute, w.callsAtMinute = minute, calls
+	}
+	return calls-w.callsAtMinute < w.budget && cache.GetNrOfApiCalls(w.dataSourceUID).NrOfCalls < constants.MaxApiCallsRateLimit
+}
+
+func (w *cacheWarmer) warm(query *warmQuery, now time.Time) {
+	w.mutex.Lock()
+	dataQuery, pluginContext := query.query, query.pluginContext
+	query.nextRun = now.Add(query.interval)
+	w.mutex.Unlock()
+	dataQuery.TimeRange = backend.TimeRange{From: now.Add(-time.Duration(query.rangeSeconds) * time.Second), To: now}
+	response := logicmonitor.Query(w.santabaClient, pluginContext, dataQuery)
+	if response.Error != nil {
+		w.santabaClient.Logger.Debug("Cache warming query failed", "refId", dataQuery.RefID, "error", response.Error)
+	}
+}
+
+// learnQuery remembers data query of a time range ending now, it is warmed from its next collect interval
+func (w *cacheWarmer) learnQuery(pluginContext backend.PluginContext, query backend.DataQuery) {
+	now := time.Now()
+	if !w.learn || now.Sub(query.TimeRange.To) > constants.LastXMunitesCheckForFrameIdCalculationInSec*time.Second {
+		return
+	}
+	rangeSeconds := query.TimeRange.To.Unix() - query.TimeRange.From.Unix()
+	key := warmKey(query, rangeSeconds)
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if known, ok := w.queries[key]; ok {
+		if !known.lastSeen.IsZero() {
+			known.lastSeen, known.pluginContext = now, pluginContext
+		}
+		return
+	}
+	interval, ok := warmInterval(query)
+	if !ok || !w.evictForNewQuery() {
+		return
+	}
+	w.queries[key] = &warmQuery{query: query, pluginContext: pluginContext, rangeSeconds: rangeSeconds, interval: interval,
+		nextRun: now.Add(interval), lastSeen: now}
+}
+
+// Makes room for a learned query by forgetting the least recently seen one, configured queries are never evicted
+func (w *cacheWarmer) evictForNewQuery() bool {
+	if len(w.queries) < constants.MaxCacheWarmingQueries {
+		return true
+	}
+	var oldestKey string
+	var oldest time.Time
+	for key, query := range w.queries {
+		if !query.lastSeen.IsZero() && (oldestKey == "" || query.lastSeen.Before(oldest)) {
+			oldestKey, oldest = key, query.lastSeen
+		}
+	}
+	if oldestKey == "" {
+		return false
+	}
+	delete(w.queries, oldestKey)
+	return true
+}
+
+/*
+Only time series and ranking queries read raw data cache, they are warmed once per collect interval. Queries cached per time
+range window are not warmed, their cache entry is keyed by the minute-truncated time range, so the range warmed is never the
+one a dashboard requests next
+*/
+func warmInterval(query backend.DataQuery) (time.Duration, bool) {
+	queryModel, err := models.UnmarshalQueryModel(query.JSON)
+	if err != nil || queryModel.QueryMode == constants.AnnotationQueryMode || queryModel.DataPointSelected == nil ||
+		!queryModel.IncrementalCache() {
+		return 0, false
+	}
+	interval := queryModel.CollectInterval
+	if interval < constants.MinCacheWarmingIntervalSeconds {
+		interval = constants.MinCacheWarmingIntervalSeconds
+	}
+	return time.Duration(interval) * time.Second, true
+}
+
+func warmKey(query backend.DataQuery, rangeSeconds int64) string {
+	sum := sha256.Sum256(query.JSON)
+	return hex.EncodeToString(sum[:]) + ":" + strconv.FormatInt(rangeSeconds, 10)
+}
+
+// countingTransport counts requests made through it
+type countingTransport struct {
+	base  http.RoundTripper
+	calls *int64
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt64(t.calls, 1)
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r) //nolint:wrapcheck
+}
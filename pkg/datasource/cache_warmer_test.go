@@ -0,0 +1,185 @@
+package datasource_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	plugin "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/datasource"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestWarmInterval(t *testing.T) {
+	dataPoint := `"dataPointSelected":[{"label":"idle"}],"collectInterval":120`
+	cases := []struct {
+		name     string
+		query    string
+		interval time.Duration
+		warmed   bool
+	}{
+		{"incremental query", `{"schemaVersion":1,` + dataPoint + `}`, 2 * time.Minute, true},
+		{"old query with strategic API calls", `{"enableStrategicApiCallFeature":true,` + dataPoint + `}`, 2 * time.Minute, true},
+		{"old query without strategic API calls", `{` + dataPoint + `}`, 0, false},
+		{"window cached query", `{"schemaVersion":1,"cacheStrategy":"window",` + dataPoint + `}`, 0, false},
+		{"annotation query", `{"schemaVersion":1,"queryMode":"Annotation",` + dataPoint + `}`, 0, false},
+		{"query without datapoints", `{"schemaVersion":1}`, 0, false},
+	}
+	for _, c := range cases {
+		interval, warmed := plugin.WarmInterval(backend.DataQuery{JSON: []byte(c.query)})
+		if warmed != c.warmed || interval != c.interval {
+			t.Errorf("%s: warmed %v every %s, want %v every %s", c.name, warmed, interval, c.warmed, c.interval)
+		}
+	}
+}
+
+// hostQueryJSON is the query model of hostQuery for another host
+func hostQueryJSON(t *testing.T, host int) json.RawMessage {
+	t.Helper()
+	var queryModel map[string]interface{}
+	if err := json.Unmarshal(hostQuery(t, time.Now()).JSON, &queryModel); err != nil {
+		t.Fatal(err)
+	}
+	queryModel["hostSelected"] = map[string]interface{}{"label": "server", "value": strconv.Itoa(host)}
+	queryJSON, err := json.Marshal(queryModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return queryJSON
+}
+
+// warmingSettings are datasource settings warming configured queries of hosts, warming itself is left disabled
+func warmingSettings(t *testing.T, settings map[string]interface{}, hosts ...int) string {
+	t.Helper()
+	queries := make([]map[string]interface{}, 0, len(hosts))
+	for _, host := range hosts {
+		queries = append(queries, map[string]interface{}{"query": hostQueryJSON(t, host), "rangeSeconds": 3600})
+	}
+	settings["cacheWarmingQueries"] = queries
+	jsonData, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(jsonData)
+}
+
+func TestWarmingStopsWhenBudgetShareIsUsed(t *testing.T) {
+	// 1% of the api calls budget
+	budget := constants.MaxApiCallsRateLimit / 100
+	hosts := make([]int, 0, 4*budget)
+	for host := 1; host <= 4*budget; host++ {
+		hosts = append(hosts, host)
+	}
+	stub := (&testutil.SantabaStub{}).Route("/devicedatasources/11/data", http.StatusOK, rawDataResponse(time.Now()))
+	ds, _ := newDataSource(t, stub, warmingSettings(t, map[string]interface{}{"cacheWarmingBudgetPercent": 1}, hosts...))
+	warmer := plugin.NewCacheWarmer(ds)
+
+	minute := time.Now().Unix() / 60
+	warmer.WarmDueQueries()
+	calls := len(stub.Requests(""))
+	due := warmer.DueQueries(time.Now())
+	warmer.WarmDueQueries()
+	again := len(stub.Requests(""))
+	if time.Now().Unix()/60 != minute {
+		t.Skip("budget share was renewed by the next minute during the test")
+	}
+	if calls < budget || calls > 2*budget {
+		t.Errorf("warming made %d calls, want its share of %d calls, exceeded at most by the last query", calls, budget)
+	}
+	if due == 0 || due == len(hosts) {
+		t.Errorf("%d of %d queries are still due, want warming stopped part way", due, len(hosts))
+	}
+	if again != calls {
+		t.Errorf("warming again in the same minute made %d more calls", again-calls)
+	}
+}
+
+func TestWarmingStopsWhenDashboardsUsedBudget(t *testing.T) {
+	stub := (&testutil.SantabaStub{}).Route("/devicedatasources/11/data", http.StatusOK, rawDataResponse(time.Now()))
+	ds, _ := newDataSource(t, stub, warmingSettings(t, map[string]interface{}{}, 1, 2))
+	warmer := plugin.NewCacheWarmer(ds)
+	cache.AddNrOfApiCalls(t.Name(), constants.MaxApiCallsRateLimit)
+
+	warmer.WarmDueQueries()
+	if requests := stub.Requests(""); len(requests) > 0 {
+		t.Errorf("warming with api calls budget used requested %v", requests)
+	}
+	if due := warmer.DueQueries(time.Now()); due != 2 {
+		t.Errorf("%d queries are due, want both configured queries", due)
+	}
+}
+
+func TestLearnedQueriesAreEvicted(t *testing.T) {
+	ds, pluginContext := newDataSource(t, &testutil.SantabaStub{}, warmingSettings(t, map[string]interface{}{"cacheWarmingLearn": true}, 1))
+	warmer := plugin.NewCacheWarmer(ds)
+	configured := backend.DataQuery{RefID: "warm0", JSON: hostQueryJSON(t, 1)}
+	learned := func(host int) backend.DataQuery {
+		now := time.Now()
+		return backend.DataQuery{RefID: "A", JSON: hostQueryJSON(t, host), TimeRange: backend.TimeRange{From: now.Add(-time.Hour), To: now}}
+	}
+
+	old := learned(1000)
+	old.TimeRange = backend.TimeRange{From: old.TimeRange.From.Add(-time.Hour), To: old.TimeRange.To.Add(-time.Hour)}
+	warmer.LearnQuery(pluginContext, old)
+	if warmer.Queries() != 1 {
+		t.Errorf("learned query of time range not ending now")
+	}
+
+	// the first learned query is the least recently seen one when the limit is reached
+	for host := 2; host <= constants.MaxCacheWarmingQueries+1; host++ {
+		warmer.LearnQuery(pluginContext, learned(host))
+	}
+	if warmer.Queries() != constants.MaxCacheWarmingQueries {
+		t.Errorf("warming %d queries, want at most %d", warmer.Queries(), constants.MaxCacheWarmingQueries)
+	}
+	if !warmer.Warms(configured, 3600) || warmer.Warms(learned(2), 3600) || !warmer.Warms(learned(3), 3600) {
+		t.Errorf("configured query warmed %v, first learned %v, second learned %v, want least recently seen learned evicted",
+			warmer.Warms(configured, 3600), warmer.Warms(learned(2), 3600), warmer.Warms(learned(3), 3600))
+	}
+
+	warmer.DueQueries(time.Now().Add((constants.CacheWarmingLearnedTTLHours + 1) * time.Hour))
+	if warmer.Queries() != 1 || !warmer.Warms(configured, 3600) {
+		t.Errorf("warming %d queries after learned ones were not seen for long, want the configured one", warmer.Queries())
+	}
+}
+
+func TestDisposeCancelsWarmingInFlight(t *testing.T) {
+	started := make(chan struct{})
+	var once sync.Once
+	transport := testutil.RoundTripFunc(func(request *http.Request) (*http.Response, error) {
+		if !strings.Contains(request.URL.String(), "/data") {
+			return testutil.Response(request, http.StatusNotFound, `{"errorMessage":"not found"}`), nil
+		}
+		once.Do(func() { close(started) })
+		<-request.Context().Done()
+		return nil, request.Context().Err()
+	})
+	jsonData := warmingSettings(t, map[string]interface{}{"path": "portal", "isBearerEnabled": true, "enableCacheWarming": true}, 1)
+	ds, err := plugin.NewDataSourceWithTransport(backend.DataSourceInstanceSettings{UID: t.Name(), JSONData: []byte(jsonData),
+		DecryptedSecureJSONData: map[string]string{"bearer_token": "token"}}, transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("warming did not start calling LM")
+	}
+	disposed := make(chan struct{})
+	go func() {
+		ds.Dispose()
+		close(disposed)
+	}()
+	select {
+	case <-disposed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dispose did not return while warming call was in flight")
+	}
+}
@@ -6,15 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
-	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/tracing"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
@@ -24,12 +27,24 @@ var (
 )
 
 type LogicmonitorDataSource struct {
-	dsInfo        *backend.DataSourceInstanceSettings
-	Logger        log.Logger
-	santabaClient httpclient.SantabaClient
+	dsInfo          *backend.DataSourceInstanceSettings
+	Logger          log.Logger
+	santabaClient   httpclient.SantabaClient
+	resourceHandler backend.CallResourceHandler
+	// warmer is nil unless cache warming is enabled in datasource settings
+	warmer *cacheWarmer
 }
 
 func LogicmonitorBackendDataSource(dsSettings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+	ds, err := newDataSource(dsSettings, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// Transport is set before cache warming starts, as warmer calls through it. Nil transport is the one of plugin settings
+func newDataSource(dsSettings backend.DataSourceInstanceSettings, transport http.RoundTripper) (*LogicmonitorDataSource, error) {
 	logger := log.New()
 	logger.Debug("Initializing new data source instance")
 
@@ -42,24 +57,34 @@ func LogicmonitorBackendDataSource(dsSettings backend.DataSourceInstanceSettings
 		return nil, err //nolint:wrapcheck
 	}
 
-	return &LogicmonitorDataSource{
+	ds := &LogicmonitorDataSource{
 		dsInfo: &dsSettings,
+		Logger: logger,
 		santabaClient: httpclient.SantabaClient{
+			DataSourceUID:  dsSettings.UID,
 			PluginSettings: &pluginSettings,
 			AuthSettings: &models.AuthSettings{
 				AccessKey:   dsSettings.DecryptedSecureJSONData[constants.AccessKey],
 				BearerToken: dsSettings.DecryptedSecureJSONData[constants.BearerToken],
 			},
-			Logger: logger,
-			Client: &http.Client{
-				Transport: &http.Transport{
-					TLSClientConfig: &tls.Config{
-						InsecureSkipVerify: pluginSettings.SkipTLSVarify,
-					},
-				},
-			},
+			Logger:        logger,
+			RequestLogger: httpclient.NewRequestLogger(&pluginSettings),
 		},
-	}, nil
+	}
+	if transport == nil {
+		transport = &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: pluginSettings.SkipTLSVarify,
+			},
+		}
+	}
+	ds.santabaClient.Client = &http.Client{Transport: transport}
+	ds.resourceHandler = httpadapter.New(ds.newRouter())
+	if pluginSettings.EnableCacheWarming {
+		ds.warmer = newCacheWarmer(ds)
+		ds.warmer.start()
+	}
+	return ds, nil
 }
 
 // Dispose here tells plugin SDK that plugin wants to clean up resources when a new instance
@@ -67,6 +92,9 @@ func LogicmonitorBackendDataSource(dsSettings backend.DataSourceInstanceSettings
 // be disposed and a new one will be created using LogicmonitorBackendDataSource factory function.
 func (ds *LogicmonitorDataSource) Dispose() {
 	// Clean up datasource instance resources.
+	if ds.warmer != nil {
+		ds.warmer.stop()
+	}
 }
 
 // QueryData handles multiple queries and returns multiple responses.
@@ -77,9 +105,17 @@ func (ds *LogicmonitorDataSource) Dispose() {
 func (ds *LogicmonitorDataSource) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) { //nolint:lll
 	// create response struct
 	response := backend.NewQueryDataResponse()
+	ctx, span := tracing.Start(tracing.ContextWithGrafanaTrace(ctx), "QueryData",
+		attribute.Int("query.count", len(req.Queries)))
+	defer span.End()
+	santabaClient := ds.santabaClient
+	santabaClient.Ctx = ctx
 	// loop over queries and execute them individually.
 	for _, q := range req.Queries {
-		res := logicmonitor.Query(ds.santabaClient, req.PluginContext, q)
+		res := logicmonitor.Query(santabaClient, req.PluginContext, q)
+		if ds.warmer != nil && res.Error == nil {
+			ds.warmer.learnQuery(req.PluginContext, q)
+		}
 
 		// save the response in a hashmap
 		// based on with RefID as identifier
@@ -99,37 +135,28 @@ func (ds *LogicmonitorDataSource) CheckHealth(_ context.Context, req *backend.Ch
 		return healthRequest, nil
 	}
 
-	requestURL := utils.BuildURLReplacingQueryParams(constants.HealthCheckReq, nil, 0, 0, models.MetaData{})
-	if requestURL == "" {
-		healthRequest.Message = constants.HealthAPIURLErrMsg
-		healthRequest.Status = backend.HealthStatusError
-
-		return healthRequest, nil
-	}
-	respByte, err := ds.santabaClient.Get(requestURL, constants.HealthCheckReq)
-	if err != nil {
-		healthRequest.Message = err.Error()
-		healthRequest.Status = backend.HealthStatusError
-
-		return healthRequest, nil //nolint:nilerr
-	}
-	var res models.ErrResponse
-	err = json.Unmarshal(respByte, &res)
-	if err != nil {
-		ds.Logger.Error(constants.ErrorUnmarshallingErrorData+"ErrResponse =>", err)
-		healthRequest.Message = err.Error()
-		healthRequest.Status = backend.HealthStatusError
-		return healthRequest, nil //nolint:nilerr
+	report := logicmonitor.CheckHealth(ds.santabaClient)
+	healthRequest.JSONDetails, _ = json.Marshal(report)
+	var failed, warned []string
+	for _, check := range report.Checks {
+		switch check.Status {
+		case constants.HealthFail:
+			failed = append(failed, check.Name+": "+check.Message)
+		case constants.HealthWarn:
+			warned = append(warned, check.Name+": "+check.Message)
+		}
 	}
-
-	if res.Errmsg != "" && res.Errmsg != "OK" {
-		healthRequest.Message = res.Errmsg
+	switch {
+	case len(failed) > 0:
 		healthRequest.Status = backend.HealthStatusError
-		return healthRequest, nil //nolint:nilerr
+		healthRequest.Message = fmt.Sprintf(constants.HealthFailedMsg, strings.Join(failed, "; "))
+	case len(warned) > 0:
+		healthRequest.Status = backend.HealthStatusOk
+		healthRequest.Message = fmt.Sprintf(constants.HealthWarningsMsg, strings.Join(warned, "; "))
+	default:
+		healthRequest.Status = backend.HealthStatusOk
+		healthRequest.Message = constants.AuthSuccessMsg
 	}
-
-	healthRequest.Status = backend.HealthStatusOk
-	healthRequest.Message = constants.AuthSuccessMsg
 	return healthRequest, nil
 }
 
@@ -180,41 +207,7 @@ func (ds *LogicmonitorDataSource) validatePluginSettings(logger log.Logger) *bac
 	return checkHealthResult
 }
 
+// CallResource serves resource calls through the REST router, see resources.go for the routes
 func (ds *LogicmonitorDataSource) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error { //nolint:lll
-	var queryModel models.QueryModel
-
-	err := json.Unmarshal(req.Body, &queryModel)
-	if err != nil {
-		ds.Logger.Error(constants.ErrorUnmarshallingErrorData+"QueryModel =>", err.Error())
-
-		return sender.Send(&backend.CallResourceResponse{ //nolint:wrapcheck,exhaustivestruct
-			Status: http.StatusInternalServerError,
-			Body:   []byte(fmt.Sprintf(constants.InternalServerErrorJsonErrMessage, constants.ErrorUnmarshallingErrorData+"QueryModel")),
-		})
-	}
-
-	requestURL := utils.BuildURLReplacingQueryParams(req.Path, &queryModel, 0, 0, models.MetaData{})
-	if requestURL == "" {
-		ds.Logger.Error(constants.URLConfigurationErrMsg)
-
-		return sender.Send(&backend.CallResourceResponse{ //nolint:wrapcheck,exhaustivestruct
-			Status: http.StatusInternalServerError,
-			Body:   []byte(fmt.Sprintf(constants.InternalServerErrorJsonErrMessage, constants.URLConfigurationErrMsg)),
-		})
-	}
-
-	respByte, err := ds.santabaClient.Get(requestURL, req.Path)
-	if err != nil {
-		ds.Logger.Info(" Error from server => ", err)
-
-		return sender.Send(&backend.CallResourceResponse{ //nolint:wrapcheck,exhaustivestruct
-			Status: http.StatusInternalServerError,
-			Body:   []byte(fmt.Sprintf(constants.InternalServerErrorJsonErrMessage, err.Error())),
-		})
-	}
-
-	return sender.Send(&backend.CallResourceResponse{ //nolint:wrapcheck,exhaustivestruct
-		Status: http.StatusOK,
-		Body:   respByte, //nolint:unconvert
-	})
+	return ds.resourceHandler.CallResource(ctx, req, sender) //nolint:wrapcheck
 }
@@ -0,0 +1,68 @@
+package datasource
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+)
+
+var exportContentTypes = map[string]string{ //nolint:gochecknoglobals
+	constants.ExportCSV:     constants.CSVContentType,
+	constants.ExportNDJSON:  constants.NDJSONContentType,
+	constants.ExportParquet: constants.ParquetContentType,
+}
+
+/*
+exportData streams raw samples of query in body as csv, ndjson or parquet, format defaults to csv. Errors before the first row is
+written are sent as json error, later errors can only end the stream, they are logged
+*/
+func (ds *LogicmonitorDataSource) exportData(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var exportRequest models.ExportRequest
+	if err := readBody(r, &exportRequest); err != nil {
+		ds.Logger.Error(constants.ErrorUnmarshallingErrorData+"ExportRequest =>", err)
+		writeError(w, http.StatusBadRequest, constants.ErrorUnmarshallingErrorData+"ExportRequest")
+		return
+	}
+	if exportRequest.Format == "" {
+		exportRequest.Format = constants.ExportCSV
+	}
+	out := &exportResponseWriter{ResponseWriter: w, format: exportRequest.Format}
+	err := logicmonitor.ExportData(exportRequest, ds.clientFor(r), httpadapter.PluginConfigFromContext(r.Context()), out)
+	if err == nil {
+		return
+	}
+	ds.Logger.Error("Export failed", "format", exportRequest.Format, "error", err)
+	if !out.started {
+		writeError(w, errorStatus(err), err.Error())
+	}
+}
+
+/*
+Headers of the export are sent with the first bytes written, so that errors found before can still be sent as json. Flush sends
+bytes written so far as a chunk of the resource response, it does nothing before the first bytes
+*/
+type exportResponseWriter struct {
+	http.ResponseWriter
+	format  string
+	started bool
+}
+
+func (e *exportResponseWriter) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.Header().Set(constants.ContentType, exportContentTypes[e.format])
+		e.Header().Set(constants.ContentDisposition, fmt.Sprintf(constants.ExportFileNameFormat, e.format))
+		e.WriteHeader(http.StatusOK)
+	}
+	return e.ResponseWriter.Write(p) //nolint:wrapcheck
+}
+
+func (e *exportResponseWriter) Flush() {
+	if flusher, ok := e.ResponseWriter.(http.Flusher); ok && e.started {
+		flusher.Flush()
+	}
+}
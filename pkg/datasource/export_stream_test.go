@@ -0,0 +1,126 @@
+package datasource_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+const exportWindows = 3
+
+// export window of query with collect interval of a minute
+const exportWindow = time.Duration(constants.MaxNumberOfRecordsPerApiCall*60) * time.Second
+
+// exportRawData has a sample in each export window, newest first
+func exportRawData(from time.Time) string {
+	var times, values []string
+	for i := exportWindows - 1; i >= 0; i-- {
+		times = append(times, fmt.Sprint(from.Add(time.Duration(i)*exportWindow+time.Minute).UnixMilli()))
+		values = append(values, fmt.Sprintf("[%d]", i))
+	}
+	return fmt.Sprintf(`{"errmsg":"OK","status":200,"data":{"dataSourceName":"CPU","dataPoints":["idle"],
+		"instances":{"CPU-0":{"time":[%s],"values":[%s]}}}}`, strings.Join(times, ","), strings.Join(values, ","))
+}
+
+// exportChunks makes export resource call, returning chunks of the streamed response
+func exportChunks(t *testing.T, format string) []*backend.CallResourceResponse {
+	t.Helper()
+	from := time.Now().Add(-exportWindows * exportWindow).Truncate(time.Minute)
+	stub := (&testutil.SantabaStub{}).Route("devices/10/devicedatasources/11/data", http.StatusOK, exportRawData(from))
+	ds, pluginContext := newDataSource(t, stub, "")
+	body, err := json.Marshal(map[string]interface{}{
+		"format": format, "from": from.UnixMilli(), "to": time.Now().UnixMilli(),
+		"query": map[string]interface{}{
+			"schemaVersion": 1, "hostSelected": map[string]interface{}{"label": "server", "value": "10"}, "hdsSelected": 11,
+			"dataSourceSelected": map[string]interface{}{"ds": 5, "label": "CPU"},
+			"dataPointSelected":  []interface{}{map[string]interface{}{"label": "idle"}},
+			"instanceSelectBy":   "Regex", "instanceRegex": ".*", "validInstanceRegex": true, "collectInterval": 60,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []*backend.CallResourceResponse
+	request := &backend.CallResourceRequest{PluginContext: pluginContext, Method: http.MethodPost, Path: "export", URL: "export",
+		Body: body}
+	err = ds.CallResource(context.Background(), request, senderFunc(func(response *backend.CallResourceResponse) error {
+		chunks = append(chunks, response)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) == 0 || chunks[0].Status != http.StatusOK {
+		t.Fatalf("export answered %v", chunks)
+	}
+	return chunks
+}
+
+func joinChunks(chunks []*backend.CallResourceResponse) []byte {
+	var body []byte
+	for _, chunk := range chunks {
+		body = append(body, chunk.Body...)
+	}
+	return body
+}
+
+func TestExportStreamsWindows(t *testing.T) {
+	for _, format := range []string{constants.ExportCSV, constants.ExportNDJSON} {
+		chunks := exportChunks(t, format)
+		if len(chunks) < exportWindows {
+			t.Errorf("%s export sent in %d chunks, want a chunk per window", format, len(chunks))
+		}
+		lines := strings.Split(strings.TrimSpace(string(joinChunks(chunks))), "\n")
+		want := exportWindows
+		if format == constants.ExportCSV {
+			want++
+		}
+		if len(lines) != want {
+			t.Errorf("%s export has %d lines, want %d: %v", format, len(lines), want, lines)
+		}
+	}
+}
+
+// bytesFile reads parquet file from memory
+type bytesFile struct {
+	*bytes.Reader
+	data []byte
+}
+
+func (f *bytesFile) Write([]byte) (int, error) { return 0, errors.New("read only") }
+func (f *bytesFile) Close() error              { return nil }
+func (f *bytesFile) Open(string) (source.ParquetFile, error) {
+	return &bytesFile{Reader: bytes.NewReader(f.data), data: f.data}, nil
+}
+func (f *bytesFile) Create(string) (source.ParquetFile, error) { return nil, errors.New("read only") }
+
+func TestExportParquetRowGroupPerWindow(t *testing.T) {
+	chunks := exportChunks(t, constants.ExportParquet)
+	if len(chunks) < exportWindows {
+		t.Errorf("parquet export sent in %d chunks, want a chunk per window", len(chunks))
+	}
+	body := joinChunks(chunks)
+	parquetReader, err := reader.NewParquetReader(&bytesFile{Reader: bytes.NewReader(body), data: body}, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parquetReader.ReadStop()
+	if rowGroups := len(parquetReader.Footer.RowGroups); rowGroups != exportWindows {
+		t.Errorf("parquet file has %d row groups, want one per window", rowGroups)
+	}
+	if rows := parquetReader.GetNumRows(); rows != exportWindows {
+		t.Errorf("parquet file has %d rows, want %d", rows, exportWindows)
+	}
+}
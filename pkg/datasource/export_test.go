@@ -0,0 +1,49 @@
+package datasource
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// WarmInterval is warmInterval, for tests of datasource_test package
+var WarmInterval = warmInterval //nolint:gochecknoglobals
+
+// NewDataSourceWithTransport creates datasource of settings calling santaba through transport, for tests of datasource_test package
+func NewDataSourceWithTransport(settings backend.DataSourceInstanceSettings, transport http.RoundTripper) (*LogicmonitorDataSource, error) {
+	return newDataSource(settings, transport)
+}
+
+// NewCacheWarmer creates cache warmer of datasource without starting it, for tests of datasource_test package
+func NewCacheWarmer(ds *LogicmonitorDataSource) *cacheWarmer {
+	return newCacheWarmer(ds)
+}
+
+func (w *cacheWarmer) WarmDueQueries() {
+	w.warmDueQueries()
+}
+
+func (w *cacheWarmer) LearnQuery(pluginContext backend.PluginContext, query backend.DataQuery) {
+	w.learnQuery(pluginContext, query)
+}
+
+// DueQueries returns number of queries due at now, forgetting learned queries not seen for long
+func (w *cacheWarmer) DueQueries(now time.Time) int {
+	return len(w.dueQueries(now))
+}
+
+// Queries returns number of queries kept warm
+func (w *cacheWarmer) Queries() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.queries)
+}
+
+// Warms tells if query is kept warm for a time range of rangeSeconds
+func (w *cacheWarmer) Warms(query backend.DataQuery, rangeSeconds int64) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, ok := w.queries[warmKey(query, rangeSeconds)]
+	return ok
+}
@@ -0,0 +1,57 @@
+package datasource_test
+
+import (
+	"context"
+	"testing"
+
+	plugin "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/datasource"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// newDataSource creates datasource with uid of the test, json data is merged into settings of a bearer token datasource
+func newDataSource(t *testing.T, stub *testutil.SantabaStub, jsonData string) (*plugin.LogicmonitorDataSource, backend.PluginContext) {
+	t.Helper()
+	merged := `{"path":"portal","isBearerEnabled":true}`
+	if jsonData != "" && jsonData != "{}" {
+		merged = merged[:len(merged)-1] + "," + jsonData[1:]
+	}
+	settings := backend.DataSourceInstanceSettings{
+		UID:                     t.Name(),
+		JSONData:                []byte(merged),
+		DecryptedSecureJSONData: map[string]string{"bearer_token": "token"},
+	}
+	ds, err := plugin.NewDataSourceWithTransport(settings, stub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ds.Dispose)
+	return ds, backend.PluginContext{DataSourceInstanceSettings: &settings}
+}
+
+type senderFunc func(response *backend.CallResourceResponse) error
+
+func (f senderFunc) Send(response *backend.CallResourceResponse) error {
+	return f(response)
+}
+
+// callResource makes resource call as grafana user of role, returning status and body of the response
+func callResource(t *testing.T, ds *plugin.LogicmonitorDataSource, pluginContext backend.PluginContext, role string,
+	method string, path string, body string) (int, string) {
+	t.Helper()
+	pluginContext.User = &backend.User{Login: "user", Role: role}
+	request := &backend.CallResourceRequest{PluginContext: pluginContext, Method: method, Path: path, URL: path, Body: []byte(body)}
+	status, responseBody := 0, ""
+	err := ds.CallResource(context.Background(), request, senderFunc(
+		func(response *backend.CallResourceResponse) error {
+			if status == 0 {
+				status = response.Status
+			}
+			responseBody += string(response.Body)
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status, responseBody
+}
@@ -0,0 +1,235 @@
+package datasource
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/tracing"
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+)
+
+// metadataEndpoint is a GET route answered by a santaba request, query model is built from path and query parameters
+type metadataEndpoint struct {
+	pattern    string
+	request    string
+	queryModel func(params map[string]string, query url.Values) (models.QueryModel, error)
+}
+
+var metadataEndpoints = []metadataEndpoint{ //nolint:gochecknoglobals
+	{pattern: "/groups/autocomplete", request: constants.AutoCompleteGroupReq,
+		queryModel: func(params map[string]string, query url.Values) (models.QueryModel, error) {
+			return models.QueryModel{GroupSelected: models.LabelIntValue{Label: query.Get("query")}}, nil
+		}},
+	{pattern: "/groups", request: constants.ServiceOrDeviceGroupReq,
+		queryModel: func(params map[string]string, query url.Values) (models.QueryModel, error) {
+			groupType := query.Get("type")
+			if groupType != constants.NormalGroupType && groupType != constants.BizServiceGroupType {
+				return models.QueryModel{}, fmt.Errorf(constants.InvalidParameterErrMsg, "type", groupType)
+			}
+			return models.QueryModel{TypeSelected: groupType, GroupSelected: models.LabelIntValue{Label: query.Get("query")}}, nil
+		}},
+	{pattern: "/hosts/autocomplete", request: constants.AutoCompleteHostReq,
+		queryModel: func(params map[string]string, query url.Values) (models.QueryModel, error) {
+			return models.QueryModel{
+				GroupSelected: models.LabelIntValue{Label: query.Get("group")},
+				HostSelected:  models.LabelStringValue{Label: query.Get("query")},
+			}, nil
+		}},
+	{pattern: "/hosts", request: constants.AllHostReq,
+		queryModel: func(params map[string]string, query url.Values) (models.QueryModel, error) {
+			return models.QueryModel{}, nil
+		}},
+	{pattern: "/hosts/{hostId}/datasources", request: constants.DataSourceReq,
+		queryModel: func(params map[string]string, query url.Values) (models.QueryModel, error) {
+			hostId, err := idParam(params, "hostId")
+			return models.QueryModel{HostSelected: models.LabelStringValue{Value: strconv.FormatInt(hostId, 10)}}, err
+		}},
+	{pattern: "/hosts/{hostId}/datasources/{dataSourceId}", request: constants.HostDataSourceReq,
+		queryModel: func(params map[string]string, query url.Values) (models.QueryModel, error) {
+			hostId, err := idParam(params, "hostId")
+			if err != nil {
+				return models.QueryModel{}, err
+			}
+			dataSourceId, err := idParam(params, "dataSourceId")
+			return models.QueryModel{
+				HostSelected:       models.LabelStringValue{Value: strconv.FormatInt(hostId, 10)},
+				DataSourceSelected: models.DataSource{Ds: dataSourceId},
+			}, err
+		}},
+	{pattern: "/hosts/{hostId}/hostdatasources/{hdsId}/instances", request: constants.AllInstanceReq,
+		queryModel: func(params map[string]string, query url.Values) (models.QueryModel, error) {
+			hostId, err := idParam(params, "hostId")
+			if err != nil {
+				return models.QueryModel{}, err
+			}
+			hdsId, err := idParam(params, "hdsId")
+			return models.QueryModel{HostSelected: models.LabelStringValue{Value: strconv.FormatInt(hostId, 10)}, HdsSelected: hdsId}, err
+		}},
+	{pattern: "/instances/autocomplete", request: constants.AutoCompleteInstanceReq,
+		queryModel: func(params map[string]string, query url.Values) (models.QueryModel, error) {
+			return models.QueryModel{
+				InstanceSearch:     query.Get("query"),
+				GroupSelected:      models.LabelIntValue{Label: query.Get("group")},
+				HostSelected:       models.LabelStringValue{Label: query.Get("host")},
+				DataSourceSelected: models.DataSource{Label: query.Get("datasource")},
+			}, nil
+		}},
+	{pattern: "/datasources/{dataSourceId}/datapoints", request: constants.DataPointReq,
+		queryModel: func(params map[string]string, query url.Values) (models.QueryModel, error) {
+			dataSourceId, err := idParam(params, "dataSourceId")
+			return models.QueryModel{DataSourceSelected: models.DataSource{Ds: dataSourceId}}, err
+		}},
+}
+
+/*
+Routes of resource calls. Typed GET routes take their inputs from path and query parameters. Routes named after request
+constants, taking query model as POST body, are kept for query editors of existing dashboards
+*/
+func (ds *LogicmonitorDataSource) newRouter() *router {
+	rt := &router{}
+	for _, endpoint := range metadataEndpoints {
+		endpoint := endpoint
+		rt.handle(http.MethodGet, endpoint.pattern, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+			queryModel, err := endpoint.queryModel(params, r.URL.Query())
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			ds.getMetadata(w, r, endpoint.request, queryModel)
+		})
+	}
+	rt.handle(http.MethodPost, "/dashboards/import", ds.importDashboard)
+	rt.handle(http.MethodPost, "/alerts/ack", ds.actionHandler(constants.AckAlertReq))
+	rt.handle(http.MethodPost, "/sdts", ds.actionHandler(constants.CreateSdtReq))
+	rt.handle(http.MethodDelete, "/sdts/{sdtId}", ds.actionHandler(constants.DeleteSdtReq))
+	rt.handle(http.MethodDelete, "/cache/metadata", ds.adminOnly(ds.invalidateMetadata))
+	rt.handle(http.MethodPost, "/export", ds.exportData)
+	rt.handle(http.MethodGet, "/cache/entries", ds.adminOnly(ds.listCacheEntries))
+	rt.handle(http.MethodDelete, "/cache/entries", ds.adminOnly(ds.invalidateCacheEntries))
+
+	rt.handle(http.MethodPost, "/"+constants.ImportDashboardReq, ds.importDashboard)
+	rt.handle(http.MethodPost, "/"+constants.AckAlertReq, ds.actionHandler(constants.AckAlertReq))
+	rt.handle(http.MethodPost, "/"+constants.CreateSdtReq, ds.actionHandler(constants.CreateSdtReq))
+	rt.handle(http.MethodPost, "/"+constants.DeleteSdtReq, ds.actionHandler(constants.DeleteSdtReq))
+	rt.handle(http.MethodPost, "/"+constants.InvalidateMetadataReq, ds.adminOnly(ds.invalidateMetadata))
+	rt.handle(http.MethodPost, "/{request}", ds.legacyMetadata)
+	return rt
+}
+
+// Requests query editor makes with query model as body
+var editorRequests = map[string]bool{ //nolint:gochecknoglobals
+	constants.AutoCompleteGroupReq:    true,
+	constants.ServiceOrDeviceGroupReq: true,
+	constants.AutoCompleteHostReq:     true,
+	constants.AutoCompleteInstanceReq: true,
+	constants.AllHostReq:              true,
+	constants.AllInstanceReq:          true,
+	constants.DataSourceReq:           true,
+	constants.HostDataSourceReq:       true,
+	constants.DataPointReq:            true,
+}
+
+func (ds *LogicmonitorDataSource) legacyMetadata(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	request := params["request"]
+	if !editorRequests[request] {
+		writeError(w, http.StatusNotFound, constants.ResourceNotFoundErrMsg)
+		return
+	}
+	var queryModel models.QueryModel
+	err := readBody(r, &queryModel)
+	if err == nil {
+		err = models.MigrateQueryModel(&queryModel)
+	}
+	if err != nil {
+		ds.Logger.Error(constants.ErrorUnmarshallingErrorData+"QueryModel =>", err.Error())
+		writeError(w, http.StatusBadRequest, constants.ErrorUnmarshallingErrorData+"QueryModel")
+		return
+	}
+	ds.getMetadata(w, r, request, queryModel)
+}
+
+func (ds *LogicmonitorDataSource) getMetadata(w http.ResponseWriter, r *http.Request, request string, queryModel models.QueryModel) {
+	santabaClient := ds.clientFor(r)
+	requestURL := utils.BuildURLReplacingQueryParams(request, &queryModel, 0, 0, models.MetaData{})
+	fetch := func(santabaClient httpclient.SantabaClient) ([]byte, error) {
+		if utils.IsListRequest(request) {
+			return santabaClient.GetAllPages(requestURL, request)
+		}
+		return santabaClient.Get(requestURL, request)
+	}
+	respByte, err := cache.GetMetadata(santabaClient, request, requestURL, fetch)
+	if err != nil {
+		ds.Logger.Info(" Error from server => ", err)
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, respByte)
+}
+
+func (ds *LogicmonitorDataSource) invalidateMetadata(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	cache.InvalidateMetadata(ds.santabaClient)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// importDashboard translates LM dashboard given in request body to grafana dashboard model, ready to be imported
+func (ds *LogicmonitorDataSource) importDashboard(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var importRequest models.ImportDashboardRequest
+	err := readBody(r, &importRequest)
+	if err != nil || importRequest.DashboardId == 0 {
+		ds.Logger.Error(constants.ErrorUnmarshallingErrorData+"ImportDashboardRequest =>", err)
+		writeError(w, http.StatusBadRequest, constants.ErrorUnmarshallingErrorData+"ImportDashboardRequest")
+		return
+	}
+
+	var dataSourceUID string
+	if settings := httpadapter.PluginConfigFromContext(r.Context()).DataSourceInstanceSettings; settings != nil {
+		dataSourceUID = settings.UID
+	}
+
+	result, err := logicmonitor.ImportDashboard(importRequest.DashboardId, dataSourceUID, ds.clientFor(r))
+	if err != nil {
+		ds.Logger.Info(" Error from server => ", err)
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+
+	respByte, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, respByte)
+}
+
+// Santaba calls of a resource call are traced under the trace of grafana
+func (ds *LogicmonitorDataSource) clientFor(r *http.Request) httpclient.SantabaClient {
+	santabaClient := ds.santabaClient
+	santabaClient.Ctx = tracing.ContextWithGrafanaTrace(r.Context())
+	return santabaClient
+}
+
+func readBody(r *http.Request, v interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	return json.Unmarshal(body, v) //nolint:wrapcheck
+}
+
+func idParam(params map[string]string, name string) (int64, error) {
+	id, err := strconv.ParseInt(params[name], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf(constants.InvalidParameterErrMsg, name, params[name])
+	}
+	return id, nil
+}
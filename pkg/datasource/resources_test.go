@@ -0,0 +1,26 @@
+package datasource_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+)
+
+func TestMetadataInvalidationIsAdminOnly(t *testing.T) {
+	ds, pluginContext := newDataSource(t, &testutil.SantabaStub{}, "")
+
+	for _, path := range []string{"cache/metadata", constants.InvalidateMetadataReq} {
+		method := http.MethodDelete
+		if path == constants.InvalidateMetadataReq {
+			method = http.MethodPost
+		}
+		if status, _ := callResource(t, ds, pluginContext, constants.EditorRole, method, path, ""); status != http.StatusForbidden {
+			t.Errorf("%s %s as editor answered %d, want %d", method, path, status, http.StatusForbidden)
+		}
+		if status, _ := callResource(t, ds, pluginContext, constants.AdminRole, method, path, ""); status != http.StatusNoContent {
+			t.Errorf("%s %s as admin answered %d, want %d", method, path, status, http.StatusNoContent)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package datasource
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+)
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request, params map[string]string)
+
+type route struct {
+	method   string
+	segments []string
+	handler  handlerFunc
+}
+
+/*
+router matches method and path of resource calls, path segments in braces like {hostId} are parameters.
+Routes are matched in the order they are added. Unknown paths answer 404, known paths with another method 405. Routes made only
+of parameters, like the legacy POST /{request}, do not make a path known, so GET of a single unknown segment is still 404
+*/
+type router struct {
+	routes []route
+}
+
+func (rt *router) handle(method string, pattern string, handler handlerFunc) {
+	rt.routes = append(rt.routes, route{method: method, segments: splitPath(pattern), handler: handler})
+}
+
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := splitPath(r.URL.Path)
+	pathMatched := false
+	for _, route := range rt.routes {
+		params, ok := route.match(segments)
+		if !ok {
+			continue
+		}
+		pathMatched = pathMatched || !route.onlyParams()
+		if route.method == r.Method {
+			route.handler(w, r, params)
+			return
+		}
+	}
+	if pathMatched {
+		writeError(w, http.StatusMethodNotAllowed, constants.MethodNotAllowedErrMsg)
+		return
+	}
+	writeError(w, http.StatusNotFound, constants.ResourceNotFoundErrMsg)
+}
+
+func (r route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (r route) onlyParams() bool {
+	for _, segment := range r.segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			return false
+		}
+	}
+	return true
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set(constants.ContentType, constants.ApplicationJSON)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	writeJSON(w, status, body)
+}
+
+// Errors of the request itself are 400, rate limit of LM is passed on as 429, any other LM error is a bad gateway
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, logicmonitor.ErrInvalidAction), errors.Is(err, logicmonitor.ErrInvalidExport):
+		return http.StatusBadRequest
+	case errors.Is(err, httpclient.ErrRateLimit):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusBadGateway
+	}
+}
@@ -0,0 +1,48 @@
+package datasource_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+)
+
+func TestRouterStatusCodes(t *testing.T) {
+	stub := (&testutil.SantabaStub{}).
+		Route("device/devices/7/devicedatasources", http.StatusTooManyRequests, `{"errorMessage":"too many requests"}`).
+		Route("setting/datasources/9", http.StatusInternalServerError, `{"errorMessage":"internal error"}`).
+		Route("device/devices/8/devicedatasources", http.StatusOK, `{"total":1,"items":[{"id":11}]}`)
+	ds, pluginContext := newDataSource(t, stub, "")
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		error  string
+	}{
+		{http.MethodGet, "groups?type=unknown", http.StatusBadRequest, "Invalid type = unknown"},
+		{http.MethodGet, "hosts/abc/datasources", http.StatusBadRequest, "Invalid hostId = abc"},
+		{http.MethodGet, "no/such/route", http.StatusNotFound, constants.ResourceNotFoundErrMsg},
+		{http.MethodGet, "nosuchthing", http.StatusNotFound, constants.ResourceNotFoundErrMsg},
+		{http.MethodDelete, constants.AckAlertReq, http.StatusMethodNotAllowed, constants.MethodNotAllowedErrMsg},
+		{http.MethodGet, "sdts/5", http.StatusMethodNotAllowed, constants.MethodNotAllowedErrMsg},
+		{http.MethodPost, "UnknownReq", http.StatusNotFound, constants.ResourceNotFoundErrMsg},
+		{http.MethodPut, "hosts", http.StatusMethodNotAllowed, constants.MethodNotAllowedErrMsg},
+		{http.MethodGet, "hosts/7/datasources", http.StatusTooManyRequests, constants.RateLimitErrMsg},
+		// failure is answered from negative cache
+		{http.MethodGet, "hosts/7/datasources", http.StatusTooManyRequests, constants.RateLimitErrMsg},
+		{http.MethodGet, "datasources/9/datapoints", http.StatusBadGateway, "internal error"},
+		{http.MethodGet, "hosts/8/datasources", http.StatusOK, `"items":[{"id":11}]`},
+	}
+	for _, c := range cases {
+		status, body := callResource(t, ds, pluginContext, constants.ViewerRole, c.method, c.path, "")
+		if status != c.status || !strings.Contains(body, c.error) {
+			t.Errorf("%s %s answered %d %s, want %d containing %q", c.method, c.path, status, body, c.status, c.error)
+		}
+		if status != http.StatusOK && !strings.HasPrefix(body, `{"error":`) {
+			t.Errorf("%s %s answered error body %s, want JSON error", c.method, c.path, body)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package datasource_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func rawDataResponse(now time.Time) string {
+	return fmt.Sprintf(`{"errmsg":"OK","status":200,"data":{"dataSourceName":"CPU","dataPoints":["idle"],
+		"instances":{"CPU-0":{"time":[%d],"values":[[12.5]]}}}}`, now.Add(-5*time.Minute).UnixMilli())
+}
+
+func hostQuery(t *testing.T, now time.Time) backend.DataQuery {
+	t.Helper()
+	queryJSON, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 1, "hostSelected": map[string]interface{}{"label": "server", "value": "10"}, "hdsSelected": 11,
+		"dataSourceSelected": map[string]interface{}{"ds": 5, "label": "CPU"},
+		"dataPointSelected":  []interface{}{map[string]interface{}{"label": "idle"}},
+		"instanceSelectBy":   "Regex", "instanceRegex": ".*", "validInstanceRegex": true, "collectInterval": 60,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend.DataQuery{RefID: "A", JSON: queryJSON, TimeRange: backend.TimeRange{From: now.Add(-time.Hour), To: now}}
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestQueryDataSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	now := time.Now()
+	stub := (&testutil.SantabaStub{}).Route("devices/10/devicedatasources/11/data", http.StatusOK, rawDataResponse(now))
+	ds, pluginContext := newDataSource(t, stub, "")
+	request := &backend.QueryDataRequest{PluginContext: pluginContext, Queries: []backend.DataQuery{hostQuery(t, now)}}
+
+	response, err := ds.QueryData(context.Background(), request)
+	if err != nil || response.Responses["A"].Error != nil {
+		t.Fatal(err, response.Responses["A"].Error)
+	}
+	spans := recorder.Ended()
+	byID := make(map[string]sdktrace.ReadOnlySpan)
+	var apiCall sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		byID[span.SpanContext().SpanID().String()] = span
+		if span.Name() == "SantabaClient.GET" && apiCall == nil {
+			apiCall = span
+		}
+	}
+	if apiCall == nil {
+		t.Fatal("no span of santaba call")
+	}
+	var chain []string
+	for span := apiCall; span != nil; span = byID[span.Parent().SpanID().String()] {
+		chain = append(chain, span.Name())
+	}
+	if got := fmt.Sprint(chain); got != "[SantabaClient.GET Query QueryData]" {
+		t.Errorf("span chain = %s", got)
+	}
+	for _, span := range spans {
+		if span.Name() == "cache.GetTimeRanges" {
+			if hit, ok := spanAttribute(span, "lm.cache_hit"); !ok || hit.AsBool() {
+				t.Errorf("first query has lm.cache_hit = %v, %v", hit.AsBool(), ok)
+			}
+			if span.EndTime().After(apiCall.StartTime()) {
+				t.Errorf("cache.GetTimeRanges ended at %v, after API call started at %v", span.EndTime(), apiCall.StartTime())
+			}
+		}
+	}
+
+	recorder = recordSpans(t)
+	if _, err = ds.QueryData(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	hits := 0
+	for _, span := range recorder.Ended() {
+		if hit, ok := spanAttribute(span, "lm.cache_hit"); ok && span.Name() == "cache.GetTimeRanges" && hit.AsBool() {
+			hits++
+		}
+	}
+	if hits != 1 {
+		t.Errorf("repeated query has %d cache hit spans, want 1", hits)
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
 )
 
+// ErrRateLimit is the error of calls denied by rate limit of LM or by api calls budget of the datasource
+var ErrRateLimit = errors.New(constants.RateLimitErrMsg) //nolint:gochecknoglobals
+
 func handleException(response *http.Response, respByte []byte, err error) error {
 	if err != nil {
 		if strings.Contains(err.Error(), constants.NoSuchHostError) {
@@ -30,10 +33,11 @@ func handleException(response *http.Response, respByte []byte, err error) error
 	}
 
 	if response.StatusCode == http.StatusTooManyRequests {
-		return errors.New(constants.RateLimitErrMsg)
+		return ErrRateLimit
 	}
 
-	if response.StatusCode != http.StatusOK {
+	// writes answer with 201 Created or 204 No Content
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
 		return errors.New(fmt.Sprintf(constants.HttpNotOk, response.StatusCode, string(respByte)))
 	}
 
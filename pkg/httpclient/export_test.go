@@ -0,0 +1,4 @@
+package httpclient
+
+// Unexported functions used by tests of httpclient_test package
+var SignLMv1 = signLMv1
@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+)
+
+// ErrTooManyPages is wrapped by the error of paging stopped at constants.MaxPages, items read until then are incomplete
+var ErrTooManyPages = errors.New("list truncated") //nolint:gochecknoglobals
+
+// listPage is a page of list endpoint, searchId is set by LM when paging through a filtered search
+type listPage struct {
+	Total    int               `json:"total"`
+	Items    []json.RawMessage `json:"items"`
+	SearchId string            `json:"searchId"`
+}
+
+/*
+ForEachPage requests a list endpoint page by page and calls onItems with raw items of every page, so callers can decode items
+without holding the whole list. Size in the URL is replaced by the page size. Paging is by offset, or by searchId cursor when LM
+returns one. When rate limit headroom is low, next page waits for the rate limit window. Returns total number of items read,
+with ErrTooManyPages when the list has more than constants.MaxPages pages
+*/
+func (santabaClient SantabaClient) ForEachPage(requestURL string, request string, onItems func(items []json.RawMessage) error) (int, error) {
+	read := 0
+	searchId := ""
+	for page := 0; page < constants.MaxPages; page++ {
+		pageURL := setQueryParam(requestURL, "size", strconv.Itoa(constants.PageSize))
+		pageURL = setQueryParam(pageURL, "offset", strconv.Itoa(read))
+		if searchId != "" {
+			pageURL = setQueryParam(pageURL, "searchId", searchId)
+		}
+		respByte, resp, err := santabaClient.doRequest(http.MethodGet, pageURL, request, nil)
+		if err != nil {
+			return read, err
+		}
+		listPage, err := unmarshalPage(respByte)
+		if err != nil {
+			return read, err
+		}
+		if len(listPage.Items) > 0 {
+			if err = onItems(listPage.Items); err != nil {
+				return read, err
+			}
+		}
+		read += len(listPage.Items)
+		searchId = listPage.SearchId
+		// negative total means LM does not know the total yet, last page is the one not filled up
+		if len(listPage.Items) < constants.PageSize || (listPage.Total > 0 && read >= listPage.Total) {
+			return read, nil
+		}
+		santabaClient.throttlePage(resp)
+	}
+	return read, fmt.Errorf("%w: "+constants.TooManyPages, ErrTooManyPages, constants.MaxPages, requestURL)
+}
+
+/*
+GetAllPages reads all pages of a list endpoint and returns them merged in a single response of the same shape as a page,
+enveloped for v1 requests and plain for x-version 3 requests, so it can replace Get for list endpoints
+*/
+func (santabaClient SantabaClient) GetAllPages(requestURL string, request string) ([]byte, error) {
+	items := []json.RawMessage{}
+	total, err := santabaClient.ForEachPage(requestURL, request, func(page []json.RawMessage) error {
+		items = append(items, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	merged := listPage{Total: total, Items: items}
+	if request == constants.HostDataSourceReq {
+		return json.Marshal(merged) //nolint:wrapcheck
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return json.Marshal(santabaResponse{Data: data, Errmsg: "OK", Status: http.StatusOK}) //nolint:wrapcheck
+}
+
+func unmarshalPage(respByte []byte) (listPage, error) {
+	var page listPage
+	var envelope santabaResponse
+	if err := json.Unmarshal(respByte, &envelope); err != nil {
+		return page, err //nolint:wrapcheck
+	}
+	if len(envelope.Data) == 0 {
+		return page, json.Unmarshal(respByte, &page) //nolint:wrapcheck
+	}
+	if envelope.Status != 0 && envelope.Status != http.StatusOK {
+		return page, errors.New(envelope.Errmsg)
+	}
+	return page, json.Unmarshal(envelope.Data, &page) //nolint:wrapcheck
+}
+
+func (santabaClient SantabaClient) throttlePage(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get(constants.RateLimitRemainingHeader))
+	if err != nil || remaining > constants.PageThrottleRemaining {
+		return
+	}
+	window, err := strconv.Atoi(resp.Header.Get(constants.RateLimitWindowHeader))
+	if err != nil || window <= 0 || window > constants.MaxPageThrottleSeconds {
+		window = constants.MaxPageThrottleSeconds
+	}
+	santabaClient.Logger.Warn(fmt.Sprintf(constants.PageThrottled, remaining, window))
+	select {
+	case <-time.After(time.Duration(window) * time.Second):
+	case <-santabaClient.context().Done():
+	}
+}
+
+// Replaces value of query parameter, adding it when missing. Other parameters are kept as they are, unencoded like LM expects
+func setQueryParam(requestURL string, key string, value string) string {
+	regex := regexp.MustCompile(`([?&])` + regexp.QuoteMeta(key) + `=[^&]*`)
+	if regex.MatchString(requestURL) {
+		return regex.ReplaceAllString(requestURL, "${1}"+key+"="+value)
+	}
+	if strings.Contains(requestURL, "?") {
+		return requestURL + "&" + key + "=" + value
+	}
+	return requestURL + "?" + key + "=" + value
+}
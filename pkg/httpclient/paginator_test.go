@@ -0,0 +1,49 @@
+package httpclient_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+)
+
+// fullPage is a page filled up with items of LM not knowing the total, so paging goes on until a page is not filled up
+func fullPage() string {
+	return testutil.Envelope(`{"total":-1,"items":[` + strings.Repeat(`1,`, constants.PageSize-1) + `1]}`)
+}
+
+func TestForEachPageReadsUntilPageIsNotFilled(t *testing.T) {
+	stub := (&testutil.SantabaStub{}).
+		Route("offset=2000", http.StatusOK, testutil.Envelope(`{"total":-1,"items":[1,1]}`)).
+		Route("device/devices", http.StatusOK, fullPage())
+	pages := 0
+	read, err := stub.Client(t).ForEachPage("device/devices?size=-1", "", func(items []json.RawMessage) error {
+		pages++
+		return nil
+	})
+	if err != nil || read != 2*constants.PageSize+2 || pages != 3 {
+		t.Errorf("read %d items of %d pages, error %v", read, pages, err)
+	}
+}
+
+func TestForEachPageFailsAfterMaxPages(t *testing.T) {
+	stub := (&testutil.SantabaStub{}).Route("device/devices", http.StatusOK, fullPage())
+	read, err := stub.Client(t).ForEachPage("device/devices?size=-1", "", func(items []json.RawMessage) error {
+		return nil
+	})
+	if !errors.Is(err, httpclient.ErrTooManyPages) {
+		t.Errorf("paging endless list returned error %v, want %v", err, httpclient.ErrTooManyPages)
+	}
+	if read != constants.MaxPages*constants.PageSize || len(stub.Requests("")) != constants.MaxPages {
+		t.Errorf("read %d items in %d requests", read, len(stub.Requests("")))
+	}
+
+	if _, err = stub.Client(t).GetAllPages("device/devices?size=-1", ""); !errors.Is(err, httpclient.ErrTooManyPages) {
+		t.Errorf("getting all pages of endless list returned error %v, want %v", err, httpclient.ErrTooManyPages)
+	}
+}
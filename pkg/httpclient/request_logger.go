@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// Headers carrying credentials, their value is never logged
+var sensitiveHeaders = map[string]bool{ //nolint:gochecknoglobals
+	constants.Authorization: true,
+	"Cookie":                true,
+	"Set-Cookie":            true,
+	"X-Api-Key":             true,
+}
+
+/*
+RequestLogger logs santaba calls with credentials redacted. Level is per datasource, info makes calls of one datasource visible
+without turning on debug logs of grafana. As settings change recreates datasource instance, level is changed without restart.
+With sample rate N only every Nth call is logged, failed calls are always logged. Response bodies are logged truncated when
+body bytes is set
+*/
+type RequestLogger struct {
+	level      string
+	bodyBytes  int
+	sampleRate int64
+	count      int64
+}
+
+func NewRequestLogger(pluginSettings *models.PluginSettings) *RequestLogger {
+	requestLogger := &RequestLogger{
+		level:      pluginSettings.RequestLogLevel,
+		bodyBytes:  pluginSettings.RequestLogBodyBytes,
+		sampleRate: pluginSettings.RequestLogSampleRate,
+	}
+	if requestLogger.level != constants.RequestLogOff && requestLogger.level != constants.RequestLogInfo {
+		requestLogger.level = constants.RequestLogDebug
+	}
+	if requestLogger.sampleRate < 1 {
+		requestLogger.sampleRate = 1
+	}
+	return requestLogger
+}
+
+func (l *RequestLogger) Log(logger log.Logger, req *http.Request, resp *http.Response, respByte []byte, elapsed time.Duration, err error) {
+	if l == nil {
+		l = &RequestLogger{level: constants.RequestLogDebug, sampleRate: 1}
+	}
+	if l.level == constants.RequestLogOff && err == nil {
+		return
+	}
+	if err == nil && atomic.AddInt64(&l.count, 1)%l.sampleRate != 0 {
+		return
+	}
+	args := []interface{}{
+		"method", req.Method,
+		"url", req.URL.String(),
+		"headers", redactHeaders(req.Header),
+		"durationMs", elapsed.Milliseconds(),
+		"responseBytes", len(respByte),
+	}
+	if resp != nil {
+		args = append(args, "status", resp.StatusCode)
+	}
+	if l.bodyBytes > 0 && len(respByte) > 0 {
+		args = append(args, "response", truncate(string(respByte), l.bodyBytes))
+	}
+	switch {
+	case err != nil:
+		logger.Warn("Santaba request failed", append(args, "error", err.Error())...)
+	case l.level == constants.RequestLogInfo:
+		logger.Info("Santaba request", args...)
+	default:
+		logger.Debug("Santaba request", args...)
+	}
+}
+
+// Auth scheme is kept, it tells which auth mode was used
+func redactHeaders(header http.Header) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			scheme := ""
+			if idx := strings.IndexByte(value, ' '); idx > 0 && name == constants.Authorization {
+				scheme = value[:idx+1]
+			}
+			value = scheme + constants.Redacted
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,166 @@
+package httpclient_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// loggedClient sends requests to a stub answering with status, logging them with settings of request logging
+func loggedClient(t *testing.T, pluginSettings models.PluginSettings, status *int) (httpclient.SantabaClient, *testutil.Logger) {
+	t.Helper()
+	logger := &testutil.Logger{}
+	pluginSettings.Path = "portal"
+	return httpclient.SantabaClient{
+		PluginSettings: &pluginSettings,
+		AuthSettings:   &models.AuthSettings{AccessKey: "accessKey123", BearerToken: "bearerSecret"},
+		Logger:         logger,
+		RequestLogger:  httpclient.NewRequestLogger(&pluginSettings),
+		Client: &http.Client{Transport: testutil.RoundTripFunc(func(request *http.Request) (*http.Response, error) {
+			return testutil.Response(request, *status, `{"status":200,"data":{"items":[]}}`), nil
+		})},
+	}, logger
+}
+
+func loggedHeaders(t *testing.T, entry testutil.LogEntry) map[string]string {
+	t.Helper()
+	headers, ok := entry.Arg("headers")
+	if !ok {
+		t.Fatalf("%s logged without headers", entry.Message)
+	}
+	return headers.(map[string]string)
+}
+
+func TestRequestLogRedactsAuthorization(t *testing.T) {
+	cases := []struct {
+		name           string
+		pluginSettings models.PluginSettings
+		authorization  string
+		secrets        []string
+	}{
+		{"lmv1", models.PluginSettings{IsLMV1Enabled: true, AccessID: "accessId", RequestLogLevel: constants.RequestLogInfo},
+			"LMv1 " + constants.Redacted, []string{"accessId", "accessKey123"}},
+		{"bearer", models.PluginSettings{IsBearerEnabled: true, RequestLogLevel: constants.RequestLogInfo},
+			constants.BearerTokenPrefix + constants.Redacted, []string{"bearerSecret"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			status := http.StatusOK
+			client, logger := loggedClient(t, c.pluginSettings, &status)
+			if _, err := client.Get("device/devices?size=1", ""); err != nil {
+				t.Fatal(err)
+			}
+			logged := logger.Logged("Santaba request")
+			if len(logged) != 1 {
+				t.Fatalf("logged %d requests, want 1", len(logged))
+			}
+			if got := loggedHeaders(t, logged[0])[constants.Authorization]; got != c.authorization {
+				t.Errorf("logged authorization %s, want %s", got, c.authorization)
+			}
+			for _, secret := range c.secrets {
+				if args := fmt.Sprint(logged[0].Args...); strings.Contains(args, secret) {
+					t.Errorf("logged %s in %s", secret, args)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestLogRedactsCookies(t *testing.T) {
+	logger := &testutil.Logger{}
+	request, _ := http.NewRequest(http.MethodGet, "https://portal.logicmonitor.com/santaba/rest/device/devices", nil)
+	request.Header.Set("Cookie", "JSESSIONID=sessionSecret")
+	request.Header.Set("X-Api-Key", "apiKeySecret")
+	request.Header.Set(constants.Authorization, "sessionSecret")
+	request.Header.Set(constants.Accept, constants.ApplicationJSON)
+
+	httpclient.NewRequestLogger(&models.PluginSettings{}).Log(logger, request, nil, nil, time.Millisecond, nil)
+	headers := loggedHeaders(t, logger.Logged("Santaba request")[0])
+	want := map[string]string{"Cookie": constants.Redacted, "X-Api-Key": constants.Redacted, constants.Authorization: constants.Redacted,
+		constants.Accept: constants.ApplicationJSON}
+	if fmt.Sprint(headers) != fmt.Sprint(want) {
+		t.Errorf("logged headers %v, want %v", headers, want)
+	}
+}
+
+func TestRequestLogSamplesSuccessfulCalls(t *testing.T) {
+	status := http.StatusOK
+	client, logger := loggedClient(t, models.PluginSettings{IsBearerEnabled: true, RequestLogLevel: constants.RequestLogInfo,
+		RequestLogSampleRate: 3}, &status)
+	for i := 0; i < 9; i++ {
+		if _, err := client.Get("device/devices?size=1", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logged := logger.Logged("Santaba request")
+	if len(logged) != 3 {
+		t.Errorf("logged %d of 9 calls sampled every 3rd, want 3", len(logged))
+	}
+	for _, entry := range logged {
+		if entry.Level != log.Info {
+			t.Errorf("logged at level %d, want info", entry.Level)
+		}
+	}
+}
+
+func TestRequestLogAlwaysLogsFailedCalls(t *testing.T) {
+	for _, pluginSettings := range []models.PluginSettings{
+		{IsBearerEnabled: true, RequestLogLevel: constants.RequestLogOff},
+		{IsBearerEnabled: true, RequestLogLevel: constants.RequestLogInfo, RequestLogSampleRate: 100},
+	} {
+		t.Run(pluginSettings.RequestLogLevel, func(t *testing.T) {
+			status := http.StatusInternalServerError
+			client, logger := loggedClient(t, pluginSettings, &status)
+			for i := 0; i < 3; i++ {
+				if _, err := client.Get("device/devices?size=1", ""); err == nil {
+					t.Fatal("call answered 500 must fail")
+				}
+			}
+			status = http.StatusOK
+			if _, err := client.Get("device/devices?size=1", ""); err != nil {
+				t.Fatal(err)
+			}
+			failed := logger.Logged("Santaba request failed")
+			if len(failed) != 3 {
+				t.Fatalf("logged %d of 3 failed calls", len(failed))
+			}
+			if got, _ := failed[0].Arg("status"); got != http.StatusInternalServerError {
+				t.Errorf("failed call logged with status %v", got)
+			}
+			if _, ok := failed[0].Arg("error"); !ok {
+				t.Error("failed call logged without error")
+			}
+			if logged := logger.Logged("Santaba request"); len(logged) > 0 {
+				t.Errorf("logged %d successful calls, want none", len(logged))
+			}
+		})
+	}
+
+	logger := &testutil.Logger{}
+	request, _ := http.NewRequest(http.MethodGet, "https://portal.logicmonitor.com/santaba/rest/device/devices", nil)
+	httpclient.NewRequestLogger(&models.PluginSettings{RequestLogLevel: constants.RequestLogOff}).
+		Log(logger, request, nil, nil, time.Millisecond, errors.New("connection refused"))
+	if len(logger.Logged("Santaba request failed")) != 1 {
+		t.Error("call failing without response is not logged")
+	}
+}
+
+func TestRequestLogTruncatesResponse(t *testing.T) {
+	status := http.StatusOK
+	client, logger := loggedClient(t, models.PluginSettings{IsBearerEnabled: true, RequestLogBodyBytes: 10}, &status)
+	if _, err := client.Get("device/devices?size=1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if response, _ := logger.Logged("Santaba request")[0].Arg("response"); response != `{"status":...` {
+		t.Errorf("logged response %v, want first 10 bytes", response)
+	}
+}
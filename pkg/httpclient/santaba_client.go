@@ -1,6 +1,8 @@
 package httpclient
 
 import (
+	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	b64 "encoding/base64"
@@ -8,45 +10,86 @@ import (
 	"errors"
 	"fmt"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"io"
 	"io/ioutil"
 	"net/http"
-	"net/http/httputil"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SantabaResource interface {
 	Get(requestURL string, request string) ([]byte, error)
+	Do(method string, requestURL string, body []byte) ([]byte, error)
 }
 
 type SantabaClient struct {
+	// DataSourceUID is uid of grafana datasource the client is of
+	DataSourceUID  string
 	PluginSettings *models.PluginSettings
 	AuthSettings   *models.AuthSettings
 	Client         *http.Client
 	Logger         log.Logger
+	// Ctx carries trace of the request being served, calls are made in spans under it
+	Ctx context.Context
+	// RequestLogger is shared by all copies of the client of a datasource, nil logs requests at debug level
+	RequestLogger *RequestLogger
 }
 
 func (santabaClient SantabaClient) Get(requestURL string, request string) ([]byte, error) { //nolint:lll
+	respByte, _, err := santabaClient.doRequest(http.MethodGet, requestURL, request, nil)
+	return respByte, err
+}
+
+// GetWithResponse is Get also returning http response, for callers needing headers or TLS state. Response body is already read
+func (santabaClient SantabaClient) GetWithResponse(requestURL string, request string) ([]byte, *http.Response, error) {
+	return santabaClient.doRequest(http.MethodGet, requestURL, request, nil)
+}
+
+// Do calls santaba API with any method. Body is sent as json and is part of the LMv1 signature
+func (santabaClient SantabaClient) Do(method string, requestURL string, body []byte) ([]byte, error) {
+	respByte, _, err := santabaClient.doRequest(method, requestURL, "", body)
+	return respByte, err
+}
+
+func (santabaClient SantabaClient) doRequest(method string, requestURL string, request string, body []byte) ([]byte, *http.Response, error) {
+	ctx, span := tracing.Start(santabaClient.Ctx, "SantabaClient."+method,
+		attribute.String("lm.request", request),
+		attribute.String("lm.portal", santabaClient.PluginSettings.Path),
+	)
+	respByte, resp, err := santabaClient.send(ctx, method, requestURL, request, body)
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	}
+	tracing.End(span, err)
+	return respByte, resp, err
+}
+
+func (santabaClient SantabaClient) send(ctx context.Context, method string, requestURL string, request string, body []byte) ([]byte, *http.Response, error) {
 	url := fmt.Sprintf(constants.RootURL, santabaClient.PluginSettings.Path) + requestURL
-	httpRequest, err := http.NewRequest(http.MethodGet, url, nil)
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
 		santabaClient.Logger.Error(constants.ErrorCreatingHttpRequest, err)
 
-		return nil, err //nolint:wrapcheck
+		return nil, nil, err //nolint:wrapcheck
 	}
 
 	resourcePath := strings.ReplaceAll(httpRequest.URL.Path, constants.SantabaRestPath, "")
-
-	// todo
-	santabaClient.Logger.Debug("The resource path is ", resourcePath)
-	santabaClient.Logger.Debug("The httpRequest.URL.Path is ", httpRequest.URL.Path)
-	santabaClient.Logger.Debug("The full path is ", requestURL)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("lm.resource_path", resourcePath))
 
 	if santabaClient.PluginSettings.IsLMV1Enabled {
-		httpRequest.Header.Add(constants.Authorization, getLMv1(santabaClient.PluginSettings.AccessID, santabaClient.AuthSettings.AccessKey, resourcePath)) //nolint:lll
+		httpRequest.Header.Add(constants.Authorization, getLMv1(santabaClient.PluginSettings.AccessID, santabaClient.AuthSettings.AccessKey,
+			method, body, resourcePath))
 	}
 
 	if santabaClient.PluginSettings.IsBearerEnabled {
@@ -54,20 +97,16 @@ func (santabaClient SantabaClient) Get(requestURL string, request string) ([]byt
 	}
 
 	httpRequest.Header.Add(constants.UserAgent, buildGrafanaUserAgent(santabaClient.PluginSettings))
+	httpRequest.Header.Add(constants.Accept, constants.ApplicationJSON)
+	if body != nil {
+		httpRequest.Header.Add(constants.ContentType, constants.ApplicationJSON)
+	}
 
 	if resourcePath == constants.AutoCompleteNamesPath || request == constants.HostDataSourceReq {
 		httpRequest.Header.Add(constants.XVersion, constants.XVersionValue3)
 	}
 
-	//	//todo remove this
-	reqDump, err := httputil.DumpRequest(httpRequest, true)
-	if err != nil {
-		santabaClient.Logger.Error(err.Error())
-		return nil, err
-	}
-
-	santabaClient.Logger.Debug("Hitting HTTP request with headers => ", string(reqDump), err)
-
+	start := time.Now()
 	newResp, err := santabaClient.Client.Do(httpRequest)
 	var respByte []byte
 	if err != nil {
@@ -77,26 +116,42 @@ func (santabaClient SantabaClient) Get(requestURL string, request string) ([]byt
 		respByte, err = ioutil.ReadAll(newResp.Body)
 		if err != nil {
 			santabaClient.Logger.Error(constants.ErrorReadingResponseBody, err)
-			return nil, errors.New(constants.ErrorReadingResponseBody)
+			return nil, newResp, errors.New(constants.ErrorReadingResponseBody)
 		}
 	}
 	err = handleException(newResp, respByte, err)
+	santabaClient.RequestLogger.Log(santabaClient.Logger, httpRequest, newResp, respByte, time.Since(start), err)
 	if err != nil {
-		return nil, err
+		return nil, newResp, err
 	}
 
-	// todo high priority
-
-	//	todo remove this
-	// resDump, err := httputil.DumpResponse(newResp, true)
-	// if err != nil {
-	// 	logger.Error(err.Error())
-	// 	return nil, err
-	// }
+	return respByte, newResp, nil
+}
 
-	// logger.Info("HTTP response => "+string(resDump), err)
+func (santabaClient SantabaClient) context() context.Context {
+	if santabaClient.Ctx == nil {
+		return context.Background()
+	}
+	return santabaClient.Ctx
+}
 
-	return respByte, err //nolint:wrapcheck
+/*
+CacheScope identifies portal, datasource and credentials of the client. Cache keys start with it, so that datasources of the same
+portal with differently scoped credentials never share cached data. Credentials are only in as a truncated hash
+*/
+func (santabaClient SantabaClient) CacheScope() string {
+	h := sha256.New()
+	parts := []string{strconv.FormatBool(santabaClient.PluginSettings.IsLMV1Enabled), santabaClient.PluginSettings.AccessID,
+		strconv.FormatBool(santabaClient.PluginSettings.IsBearerEnabled)}
+	if santabaClient.AuthSettings != nil {
+		parts = append(parts, santabaClient.AuthSettings.AccessKey, santabaClient.AuthSettings.BearerToken)
+	}
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	fingerprint := hex.EncodeToString(h.Sum(nil))[:constants.CredentialFingerprintLength]
+	return santabaClient.PluginSettings.Path + "|" + santabaClient.DataSourceUID + "|" + fingerprint
 }
 
 func buildBearerToken(authSettings *models.AuthSettings) string {
@@ -107,10 +162,14 @@ func buildGrafanaUserAgent(pluginSettings *models.PluginSettings) string {
 	return fmt.Sprintf(constants.GrafanaUserAgent, pluginSettings.Path, pluginSettings.Version)
 }
 
-func getLMv1(accessID, accessKey, resourcePath string) string {
-	epoch := time.Now().UnixMilli()
-	getEpoch := fmt.Sprintf("%s%d", http.MethodGet, epoch)
-	data := getEpoch + resourcePath
+// LMv1 signature is HMAC-SHA256 of method + epoch + body + resource path
+func getLMv1(accessID, accessKey, method string, body []byte, resourcePath string) string {
+	return signLMv1(accessID, accessKey, method, time.Now().UnixMilli(), body, resourcePath)
+}
+
+func signLMv1(accessID, accessKey, method string, epoch int64, body []byte, resourcePath string) string {
+	methodEpoch := fmt.Sprintf("%s%d", method, epoch)
+	data := methodEpoch + string(body) + resourcePath
 	h := hmac.New(sha256.New, []byte(accessKey))
 	h.Write([]byte(data))
 	sha := hex.EncodeToString(h.Sum(nil))
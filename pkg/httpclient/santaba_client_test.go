@@ -0,0 +1,62 @@
+package httpclient_test
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func TestSignLMv1(t *testing.T) {
+	tests := []struct {
+		method       string
+		body         string
+		resourcePath string
+		want         string
+	}{
+		{method: http.MethodGet, resourcePath: "/device/devices",
+			want: "LMv1 accessId:OTIwMTlhMzEzYjJjZGQwNGFiYmU1MzJjOWJkZjkyOGU4ODI4Y2NlZGFmZTc2Y2YzNTRlY2I4YjM2MGY3NWM0OQ==:1700000000000"}, //nolint:lll
+		{method: http.MethodPost, body: `{"type":"DeviceSDT","deviceId":10}`, resourcePath: "/sdt/sdts",
+			want: "LMv1 accessId:ZmMyY2QzYWY2NjMyZmVkZGM1ZmE4ZTU1NzIzNGI0MDg5YjhjZDIwZTgzNzZhMTIzMzExYWI0ODQ2ZjA0NGQ1Yw==:1700000000000"}, //nolint:lll
+	}
+	for _, test := range tests {
+		var body []byte
+		if test.body != "" {
+			body = []byte(test.body)
+		}
+		if got := httpclient.SignLMv1("accessId", "accessKey123", test.method, 1700000000000, body, test.resourcePath); got != test.want {
+			t.Errorf("%s %s signed %s, want %s", test.method, test.resourcePath, got, test.want)
+		}
+	}
+}
+
+// Resource path signed is the path without /santaba/rest and without query
+func TestRequestIsSignedWithResourcePath(t *testing.T) {
+	var authorization string
+	client := httpclient.SantabaClient{
+		PluginSettings: &models.PluginSettings{Path: "portal", AccessID: "accessId", IsLMV1Enabled: true},
+		AuthSettings:   &models.AuthSettings{AccessKey: "accessKey123"},
+		Logger:         log.DefaultLogger,
+		Client: &http.Client{Transport: testutil.RoundTripFunc(func(request *http.Request) (*http.Response, error) {
+			authorization = request.Header.Get("Authorization")
+			return testutil.Response(request, http.StatusOK, `{"status":200,"data":{}}`), nil
+		})},
+	}
+	body := []byte(`{"type":"DeviceSDT","deviceId":10}`)
+	if _, err := client.Do(http.MethodPost, "sdt/sdts?format=json", body); err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(authorization, ":")
+	epoch, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil {
+		t.Fatalf("authorization %s has no epoch", authorization)
+	}
+	if want := httpclient.SignLMv1("accessId", "accessKey123", http.MethodPost, epoch, body, "/sdt/sdts"); authorization != want {
+		t.Errorf("authorization = %s, want %s", authorization, want)
+	}
+}
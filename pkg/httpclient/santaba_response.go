@@ -0,0 +1,30 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// santabaResponse is the envelope of santaba response when x-version header is not set. Errors may come with http status 200,
+// status in the envelope tells if request was successful
+type santabaResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errmsg string          `json:"errmsg"`
+	Status int             `json:"status"`
+}
+
+// UnmarshalResponse unmarshals data of enveloped santaba response into v
+func UnmarshalResponse(respByte []byte, v interface{}) error {
+	var response santabaResponse
+	if err := json.Unmarshal(respByte, &response); err != nil {
+		return err //nolint:wrapcheck
+	}
+	if response.Status != 0 && response.Status != http.StatusOK {
+		return errors.New(response.Errmsg)
+	}
+	if len(response.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Data, v) //nolint:wrapcheck
+}
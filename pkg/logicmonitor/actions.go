@@ -0,0 +1,75 @@
+package logicmonitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+// ErrInvalidAction is wrapped by errors caused by the action request itself, as opposed to errors from LM
+var ErrInvalidAction = errors.New("invalid action request") //nolint:gochecknoglobals
+
+// AckAlert acknowledges alert with comment, comment is mandatory in LM
+func AckAlert(request models.AckAlertRequest, santabaClient httpclient.SantabaClient) ([]byte, error) {
+	if request.AlertId == "" {
+		return nil, fmt.Errorf("%w: "+constants.MissingActionField, ErrInvalidAction, "alertId")
+	}
+	if request.Comment == "" {
+		return nil, fmt.Errorf("%w: "+constants.MissingActionField, ErrInvalidAction, "comment")
+	}
+	body, err := json.Marshal(models.AckPayload{AckComment: request.Comment})
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return santabaClient.Do(http.MethodPost, fmt.Sprintf(constants.AckAlertURL, url.PathEscape(request.AlertId)), body) //nolint:wrapcheck
+}
+
+// CreateSdt schedules one time SDT on a device, device group or instance
+func CreateSdt(request models.CreateSdtRequest, santabaClient httpclient.SantabaClient) ([]byte, error) {
+	payload := models.SdtPayload{
+		SdtType:       constants.OneTimeSdt,
+		StartDateTime: request.StartDateTime,
+		EndDateTime:   request.EndDateTime,
+		Comment:       request.Comment,
+	}
+	var id int64
+	var idField string
+	switch request.Scope {
+	case constants.DeviceSdtScope:
+		payload.Type, payload.DeviceId = constants.DeviceSdtType, request.DeviceId
+		id, idField = request.DeviceId, "deviceId"
+	case constants.GroupSdtScope:
+		payload.Type, payload.DeviceGroupId = constants.GroupSdtType, request.DeviceGroupId
+		id, idField = request.DeviceGroupId, "deviceGroupId"
+	case constants.InstanceSdtScope:
+		payload.Type, payload.DataSourceInstanceId = constants.InstanceSdtType, request.DataSourceInstanceId
+		id, idField = request.DataSourceInstanceId, "dataSourceInstanceId"
+	default:
+		return nil, fmt.Errorf("%w: "+constants.InvalidSdtScope, ErrInvalidAction, request.Scope)
+	}
+	if id == 0 {
+		return nil, fmt.Errorf("%w: "+constants.MissingActionField, ErrInvalidAction, idField)
+	}
+	if request.EndDateTime <= request.StartDateTime {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAction, constants.InvalidSdtTimeRange)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	return santabaClient.Do(http.MethodPost, constants.SdtURL, body) //nolint:wrapcheck
+}
+
+// DeleteSdt removes SDT, used to end a downtime early
+func DeleteSdt(request models.DeleteSdtRequest, santabaClient httpclient.SantabaClient) ([]byte, error) {
+	if request.SdtId == "" {
+		return nil, fmt.Errorf("%w: "+constants.MissingActionField, ErrInvalidAction, "sdtId")
+	}
+	return santabaClient.Do(http.MethodDelete, fmt.Sprintf(constants.SdtByIdURL, url.PathEscape(request.SdtId)), nil) //nolint:wrapcheck
+}
@@ -0,0 +1,122 @@
+package logicmonitor_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+)
+
+func TestActionsRejectInvalidRequests(t *testing.T) {
+	cases := []struct {
+		name   string
+		action func(client httpclient.SantabaClient) ([]byte, error)
+		error  string
+	}{
+		{"ack without alert", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.AckAlert(models.AckAlertRequest{Comment: "on it"}, client)
+		}, "alertId is required"},
+		{"ack without comment", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.AckAlert(models.AckAlertRequest{AlertId: "LMA1"}, client)
+		}, "comment is required"},
+		{"sdt of unknown scope", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.CreateSdt(models.CreateSdtRequest{Scope: "website", DeviceId: 10, StartDateTime: 1, EndDateTime: 2}, client)
+		}, "invalid SDT scope = website"},
+		{"device sdt without device", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.CreateSdt(models.CreateSdtRequest{Scope: constants.DeviceSdtScope, DeviceGroupId: 3, StartDateTime: 1,
+				EndDateTime: 2}, client)
+		}, "deviceId is required"},
+		{"group sdt without group", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.CreateSdt(models.CreateSdtRequest{Scope: constants.GroupSdtScope, DeviceId: 10, StartDateTime: 1,
+				EndDateTime: 2}, client)
+		}, "deviceGroupId is required"},
+		{"instance sdt without instance", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.CreateSdt(models.CreateSdtRequest{Scope: constants.InstanceSdtScope, DeviceId: 10, StartDateTime: 1,
+				EndDateTime: 2}, client)
+		}, "dataSourceInstanceId is required"},
+		{"sdt ending at start", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.CreateSdt(models.CreateSdtRequest{Scope: constants.DeviceSdtScope, DeviceId: 10, StartDateTime: 2,
+				EndDateTime: 2}, client)
+		}, constants.InvalidSdtTimeRange},
+		{"sdt ending before start", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.CreateSdt(models.CreateSdtRequest{Scope: constants.DeviceSdtScope, DeviceId: 10, StartDateTime: 2,
+				EndDateTime: 1}, client)
+		}, constants.InvalidSdtTimeRange},
+		{"delete without sdt", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.DeleteSdt(models.DeleteSdtRequest{}, client)
+		}, "sdtId is required"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stub := &testutil.SantabaStub{}
+			_, err := c.action(stub.Client(t))
+			if !errors.Is(err, logicmonitor.ErrInvalidAction) || !strings.Contains(err.Error(), c.error) {
+				t.Errorf("error = %v, want invalid action containing %q", err, c.error)
+			}
+			if requests := stub.Requests(""); len(requests) > 0 {
+				t.Errorf("invalid action requested %v", requests)
+			}
+		})
+	}
+}
+
+func TestActionsRequestLM(t *testing.T) {
+	cases := []struct {
+		name   string
+		action func(client httpclient.SantabaClient) ([]byte, error)
+		method string
+		url    string
+		body   map[string]interface{}
+	}{
+		{"ack", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.AckAlert(models.AckAlertRequest{AlertId: "LMA1", Comment: "on it"}, client)
+		}, http.MethodPost, "alert/alerts/LMA1/ack", map[string]interface{}{"ackComment": "on it"}},
+		{"device sdt", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.CreateSdt(models.CreateSdtRequest{Scope: constants.DeviceSdtScope, DeviceId: 10, DeviceGroupId: 3,
+				StartDateTime: 1000, EndDateTime: 2000, Comment: "patching"}, client)
+		}, http.MethodPost, "sdt/sdts", map[string]interface{}{"type": constants.DeviceSdtType, "sdtType": constants.OneTimeSdt,
+			"deviceId": 10.0, "startDateTime": 1000.0, "endDateTime": 2000.0, "comment": "patching"}},
+		{"group sdt", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.CreateSdt(models.CreateSdtRequest{Scope: constants.GroupSdtScope, DeviceId: 10, DeviceGroupId: 3,
+				StartDateTime: 1000, EndDateTime: 2000}, client)
+		}, http.MethodPost, "sdt/sdts", map[string]interface{}{"type": constants.GroupSdtType, "sdtType": constants.OneTimeSdt,
+			"deviceGroupId": 3.0, "startDateTime": 1000.0, "endDateTime": 2000.0, "comment": ""}},
+		{"instance sdt", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.CreateSdt(models.CreateSdtRequest{Scope: constants.InstanceSdtScope, DataSourceInstanceId: 77,
+				StartDateTime: 1000, EndDateTime: 2000}, client)
+		}, http.MethodPost, "sdt/sdts", map[string]interface{}{"type": constants.InstanceSdtType, "sdtType": constants.OneTimeSdt,
+			"dataSourceInstanceId": 77.0, "startDateTime": 1000.0, "endDateTime": 2000.0, "comment": ""}},
+		{"delete sdt", func(client httpclient.SantabaClient) ([]byte, error) {
+			return logicmonitor.DeleteSdt(models.DeleteSdtRequest{SdtId: "D_5"}, client)
+		}, http.MethodDelete, "sdt/sdts/D_5", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stub := (&testutil.SantabaStub{}).Route(c.url, http.StatusOK, testutil.Envelope(`{}`))
+			if _, err := c.action(stub.Client(t)); err != nil {
+				t.Fatal(err)
+			}
+			requests := stub.Requests("")
+			if len(requests) != 1 || requests[0].Method != c.method || !strings.HasSuffix(requests[0].URL, c.url) {
+				t.Fatalf("requested %v, want %s %s", requests, c.method, c.url)
+			}
+			var body map[string]interface{}
+			if requests[0].Body != "" {
+				if err := json.Unmarshal([]byte(requests[0].Body), &body); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual(body, c.body) {
+				t.Errorf("body = %v, want %v", body, c.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package logicmonitor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var alertSeverities = map[int]string{2: "warning", 3: "error", 4: "critical"} //nolint:gochecknoglobals
+
+/*
+GetAnnotations gets alerts, opsnotes and sdts of selected device (or group when no device is selected) for query time range
+and returns them as single annotation frame with time, timeEnd, title, text and tags fields.
+Each annotation type is cached separately so that changing selected types does not call API for the types already fetched
+*/
+func GetAnnotations(query backend.DataQuery, queryModel models.QueryModel, santabaClient httpclient.SantabaClient,
+	pluginContext backend.PluginContext) backend.DataResponse {
+	response := backend.DataResponse{}
+	diagnostics := &models.QueryDiagnostics{}
+	byDevice := queryModel.HostSelected.Value != ""
+	if !byDevice && queryModel.GroupSelected.Value == 0 {
+		response.Error = errors.New(constants.AnnotationScopeMissing)
+		return response
+	}
+	// truncate to minute so that refreshes within a minute hit the cache
+	from := utils.UnixTruncateToNearestMinute(query.TimeRange.From.Unix(), 60)
+	to := utils.UnixTruncateToNearestMinute(query.TimeRange.To.Unix(), 60) + 59
+	annotationTypes := queryModel.AnnotationTypes
+	if len(annotationTypes) == 0 {
+		annotationTypes = []string{constants.AlertAnnotation, constants.OpsNoteAnnotation, constants.SdtAnnotation}
+	}
+	var annotations []models.Annotation
+	for _, annotationType := range annotationTypes {
+		key := getAnnotationKey(annotationType, byDevice, from, to, &queryModel, santabaClient)
+		fetched, ok := cache.GetAnnotations(key)
+		diagnostics.AddTimeRange(from, to, annotationType, ok)
+		if !ok {
+			var err error
+			fetched, err = fetchAnnotations(annotationType, byDevice, from, to, &queryModel, santabaClient, diagnostics)
+			if err != nil {
+				response.Error = err
+				return setDiagnostics(response, diagnostics, pluginContext)
+			}
+			cache.StoreAnnotations(key, fetched)
+		}
+		annotations = append(annotations, fetched...)
+	}
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].Time < annotations[j].Time
+	})
+	response.Frames = append(response.Frames, buildAnnotationFrame(query.RefID, annotations))
+	return setDiagnostics(response, diagnostics, pluginContext)
+}
+
+func getAnnotationKey(annotationType string, byDevice bool, from int64, to int64, queryModel *models.QueryModel,
+	santabaClient httpclient.SantabaClient) string {
+	scope := strconv.FormatInt(queryModel.GroupSelected.Value, 10)
+	if byDevice {
+		scope = queryModel.HostSelected.Value
+	}
+	return strings.Join([]string{santabaClient.CacheScope(), annotationType, strconv.FormatBool(byDevice), scope,
+		strconv.FormatInt(from, 10), strconv.FormatInt(to, 10)}, "|")
+}
+
+func fetchAnnotations(annotationType string, byDevice bool, from int64, to int64, queryModel *models.QueryModel,
+	santabaClient httpclient.SantabaClient, diagnostics *models.QueryDiagnostics) ([]models.Annotation, error) {
+	switch annotationType {
+	case constants.AlertAnnotation:
+		// LM filters can not OR fields, active and cleared alerts overlapping the range are read separately
+		var active, cleared models.Alerts
+		if err := getAnnotationItems(pickRequest(byDevice, constants.DeviceActiveAlertReq, constants.GroupActiveAlertReq), from, to,
+			queryModel, santabaClient, diagnostics, &active); err != nil {
+			return nil, err
+		}
+		if err := getAnnotationItems(pickRequest(byDevice, constants.DeviceAlertReq, constants.GroupAlertReq), from, to,
+			queryModel, santabaClient, diagnostics, &cleared); err != nil {
+			return nil, err
+		}
+		return alertsToAnnotations(append(active.Items, cleared.Items...), from, to), nil
+	case constants.OpsNoteAnnotation:
+		var opsNotes models.OpsNotes
+		if err := getAnnotationItems(pickRequest(byDevice, constants.DeviceOpsNoteReq, constants.GroupOpsNoteReq), from, to,
+			queryModel, santabaClient, diagnostics, &opsNotes); err != nil {
+			return nil, err
+		}
+		return opsNotesToAnnotations(opsNotes.Items), nil
+	case constants.SdtAnnotation:
+		var sdts models.Sdts
+		if err := getAnnotationItems(pickRequest(byDevice, constants.DeviceSdtReq, constants.GroupSdtReq), from, to,
+			queryModel, santabaClient, diagnostics, &sdts); err != nil {
+			return nil, err
+		}
+		return sdtsToAnnotations(sdts.Items, from, to), nil
+	default:
+		return nil, fmt.Errorf(constants.InvalidAnnotationType, annotationType)
+	}
+}
+
+func pickRequest(byDevice bool, deviceRequest string, groupRequest string) string {
+	if byDevice {
+		return deviceRequest
+	}
+	return groupRequest
+}
+
+func getAnnotationItems(request string, from int64, to int64, queryModel *models.QueryModel, santabaClient httpclient.SantabaClient,
+	diagnostics *models.QueryDiagnostics, items interface{}) error {
+	fullPath := utils.BuildURLReplacingQueryParams(request, queryModel, from, to, models.MetaData{})
+	santabaClient.Logger.Info("Calling API  => ", santabaClient.PluginSettings.Path, fullPath)
+	diagnostics.AddCalledURL(fullPath)
+	respByte, err := santabaClient.GetAllPages(fullPath, request)
+	if err != nil {
+		santabaClient.Logger.Error("Error from server => ", err)
+		return err
+	}
+	err = httpclient.UnmarshalResponse(respByte, items)
+	if err != nil {
+		santabaClient.Logger.Error(constants.ErrorUnmarshallingErrorData+request+" => ", err)
+	}
+	return err //nolint:wrapcheck
+}
+
+/*
+Active alerts are shown as a region till the end of the time range. Alerts are kept when they overlap the time range, also when
+started before it, and only once when an alert cleared between the two reads is in both
+*/
+func alertsToAnnotations(alerts []models.Alert, from int64, to int64) []models.Annotation {
+	annotations := make([]models.Annotation, 0, len(alerts))
+	seen := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		if seen[alert.Id] || alert.StartEpoch > to || (alert.Cleared && alert.EndEpoch > 0 && alert.EndEpoch < from) {
+			continue
+		}
+		seen[alert.Id] = true
+		severity, ok := alertSeverities[alert.Severity]
+		if !ok {
+			severity = strconv.Itoa(alert.Severity)
+		}
+		state := "active"
+		endEpoch := to
+		if alert.Cleared && alert.EndEpoch > 0 {
+			state = "cleared"
+			endEpoch = alert.EndEpoch
+		}
+		annotations = append(annotations, models.Annotation{
+			Time:    alert.StartEpoch * 1000,
+			TimeEnd: endEpoch * 1000,
+			Title:   fmt.Sprintf("%s alert on %s", severity, alert.MonitorObjectName),
+			Text: fmt.Sprintf("%s %s = %s, threshold = %s", alert.InstanceName, alert.DataPointName,
+				alert.AlertValue, alert.Threshold),
+			Tags: []string{constants.AlertAnnotation, severity, state, alert.InstanceName},
+		})
+	}
+	return annotations
+}
+
+func opsNotesToAnnotations(opsNotes []models.OpsNote) []models.Annotation {
+	annotations := make([]models.Annotation, 0, len(opsNotes))
+	for _, opsNote := range opsNotes {
+		tags := []string{constants.OpsNoteAnnotation}
+		for _, tag := range opsNote.Tags {
+			tags = append(tags, tag.Name)
+		}
+		annotations = append(annotations, models.Annotation{
+			Time:    opsNote.HappenOnInSec * 1000,
+			TimeEnd: opsNote.HappenOnInSec * 1000,
+			Title:   "OpsNote by " + opsNote.CreatedBy,
+			Text:    opsNote.Note,
+			Tags:    tags,
+		})
+	}
+	return annotations
+}
+
+// sdt API has no time filter, only sdts overlapping with time range are kept
+func sdtsToAnnotations(sdts []models.Sdt, from int64, to int64) []models.Annotation {
+	annotations := make([]models.Annotation, 0, len(sdts))
+	for _, sdt := range sdts {
+		if sdt.StartDateTime > to*1000 || sdt.EndDateTime < from*1000 {
+			continue
+		}
+		annotations = append(annotations, models.Annotation{
+			Time:    sdt.StartDateTime,
+			TimeEnd: sdt.EndDateTime,
+			Title:   "SDT by " + sdt.Admin,
+			Text:    sdt.Comment,
+			Tags:    []string{constants.SdtAnnotation, sdt.Type},
+		})
+	}
+	return annotations
+}
+
+func buildAnnotationFrame(refID string, annotations []models.Annotation) *data.Frame {
+	frame := data.NewFrame(constants.AnnotationsStr,
+		data.NewField(constants.TimeStr, nil, []time.Time{}),
+		data.NewField("timeEnd", nil, []time.Time{}),
+		data.NewField("title", nil, []string{}),
+		data.NewField("text", nil, []string{}),
+		data.NewField("tags", nil, []string{}),
+	)
+	frame.RefID = refID
+	for _, annotation := range annotations {
+		frame.AppendRow(time.UnixMilli(annotation.Time), time.UnixMilli(annotation.TimeEnd), annotation.Title,
+			annotation.Text, strings.Join(annotation.Tags, ","))
+	}
+	return frame
+}
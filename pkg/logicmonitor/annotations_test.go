@@ -0,0 +1,64 @@
+package logicmonitor_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestAlertSpanningWindowStart(t *testing.T) {
+	from := time.Unix(1_700_000_040, 0)
+	to := from.Add(time.Hour)
+	stub := (&testutil.SantabaStub{}).
+		Route("cleared:false", http.StatusOK, testutil.Envelope(`{"total":2,"items":[
+			{"id":"spanning","startEpoch":1699990000,"cleared":false,"severity":4,"monitorObjectName":"server"},
+			{"id":"cleared-meanwhile","startEpoch":1700000100,"endEpoch":1700000200,"cleared":true,"severity":2}]}`)).
+		Route("cleared:true", http.StatusOK, testutil.Envelope(`{"total":2,"items":[
+			{"id":"cleared-meanwhile","startEpoch":1700000100,"endEpoch":1700000200,"cleared":true,"severity":2},
+			{"id":"cleared-before","startEpoch":1699990000,"endEpoch":1699990100,"cleared":true,"severity":3}]}`))
+	queryModel := models.QueryModel{HostSelected: models.LabelStringValue{Label: "server", Value: "10"},
+		AnnotationTypes: []string{constants.AlertAnnotation}}
+
+	response := logicmonitor.GetAnnotations(backend.DataQuery{RefID: "A", TimeRange: backend.TimeRange{From: from, To: to}},
+		queryModel, stub.Client(t), backend.PluginContext{})
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+
+	filters := []string{"filter=startEpoch<:1700003699,cleared:false", "filter=startEpoch<:1700003699,endEpoch>:1700000040,cleared:true"}
+	for _, filter := range filters {
+		if len(stub.Requests(filter)) != 1 {
+			t.Errorf("alerts are not requested with %s, requested %v", filter, stub.Requests("alerts"))
+		}
+	}
+	frame := response.Frames[0]
+	if frame.Rows() != 2 {
+		t.Fatalf("got %d annotations, want spanning alert and cleared alert once", frame.Rows())
+	}
+	if start := frame.Fields[0].At(0).(time.Time); !start.Equal(time.Unix(1699990000, 0)) {
+		t.Errorf("spanning alert starts at %v", start)
+	}
+	if end := frame.Fields[1].At(0).(time.Time); !end.Equal(time.Unix(1700003699, 0)) {
+		t.Errorf("active alert ends at %v, want end of time range", end)
+	}
+}
+
+func TestAnnotationKeysOfDevicesStaySeparate(t *testing.T) {
+	client := newClient("uid", "id", "key")
+	// device id and range of both concatenate to 12345678
+	first := logicmonitor.GetAnnotationKey(constants.AlertAnnotation, true, 345, 678,
+		&models.QueryModel{HostSelected: models.LabelStringValue{Value: "12"}}, client)
+	second := logicmonitor.GetAnnotationKey(constants.AlertAnnotation, true, 3456, 78,
+		&models.QueryModel{HostSelected: models.LabelStringValue{Value: "12"}}, client)
+	third := logicmonitor.GetAnnotationKey(constants.AlertAnnotation, true, 45, 678,
+		&models.QueryModel{HostSelected: models.LabelStringValue{Value: "123"}}, client)
+	if first == second || first == third || second == third {
+		t.Errorf("annotation keys of different devices or ranges collide: %s, %s, %s", first, second, third)
+	}
+}
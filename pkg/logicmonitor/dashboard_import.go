@@ -0,0 +1,308 @@
+package logicmonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+var expressionTokenRegex = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*|\d+(\.\d+)?|[-+*/()]|\s+`) //nolint:gochecknoglobals
+
+/*
+ImportDashboard gets LM dashboard with its widgets and translates them to a grafana dashboard using this datasource.
+Custom graph widgets become time series panels with one query per device/datasource/instance selection and virtual datapoints
+become math expressions. Text widgets become text panels. Widgets or parts of widgets that could not be translated are reported
+*/
+func ImportDashboard(dashboardId int64, dataSourceUID string, santabaClient httpclient.SantabaClient) (models.ImportDashboardResponse, error) {
+	var result models.ImportDashboardResponse
+	var lmDashboard models.LMDashboard
+	if err := getDashboardResource(fmt.Sprintf(constants.DashboardURL, dashboardId), constants.ImportDashboardReq, santabaClient,
+		&lmDashboard); err != nil {
+		return result, err
+	}
+	var widgets models.Widgets
+	if err := getDashboardResource(fmt.Sprintf(constants.DashboardWidgetsURL, dashboardId), constants.DashboardWidgetsReq, santabaClient,
+		&widgets); err != nil {
+		return result, err
+	}
+	result.Dashboard = models.GrafanaDashboard{
+		Title:         lmDashboard.Name,
+		Description:   lmDashboard.Description,
+		Tags:          []string{"logicmonitor"},
+		Panels:        []models.GrafanaPanel{},
+		SchemaVersion: 30,
+		Time:          map[string]string{"from": "now-6h", "to": "now"},
+	}
+	result.Untranslated = []models.UntranslatedWidget{}
+	sort.SliceStable(widgets.Items, func(i, j int) bool { return widgets.Items[i].Id < widgets.Items[j].Id })
+	for _, widget := range widgets.Items {
+		panel, reasons := translateWidget(widget, dataSourceUID, santabaClient)
+		if panel != nil {
+			panel.Id = len(result.Dashboard.Panels) + 1
+			panel.GridPos = getGridPos(lmDashboard.WidgetsConfig[strconv.FormatInt(widget.Id, 10)])
+			result.Dashboard.Panels = append(result.Dashboard.Panels, *panel)
+		}
+		if len(reasons) > 0 {
+			result.Untranslated = append(result.Untranslated, models.UntranslatedWidget{
+				WidgetId: widget.Id, Name: widget.Name, Type: widget.Type, Reason: strings.Join(reasons, "; ")})
+		}
+	}
+	return result, nil
+}
+
+// Widgets are a list, read page by page
+func getDashboardResource(requestURL string, request string, santabaClient httpclient.SantabaClient, v interface{}) error {
+	santabaClient.Logger.Info("Calling API  => ", santabaClient.PluginSettings.Path, requestURL)
+	var respByte []byte
+	var err error
+	if utils.IsListRequest(request) {
+		respByte, err = santabaClient.GetAllPages(requestURL, request)
+	} else {
+		respByte, err = santabaClient.Get(requestURL, request)
+	}
+	if err != nil {
+		santabaClient.Logger.Error("Error from server => ", err)
+		return err //nolint:wrapcheck
+	}
+	err = httpclient.UnmarshalResponse(respByte, v)
+	if err != nil {
+		santabaClient.Logger.Error(constants.ErrorUnmarshallingErrorData+"dashboard => ", err)
+	}
+	return err
+}
+
+// LM dashboards have 12 columns, grafana has 24. Height of a LM row is taken as 4 grafana rows
+func getGridPos(config models.WidgetConfig) models.GridPos {
+	gridPos := models.GridPos{X: (config.Col - 1) * 2, Y: (config.Row - 1) * 4, W: config.SizeX * 2, H: config.SizeY * 4}
+	if gridPos.X < 0 {
+		gridPos.X = 0
+	}
+	if gridPos.Y < 0 {
+		gridPos.Y = 0
+	}
+	if gridPos.W <= 0 {
+		gridPos.W = 12
+	}
+	if gridPos.H <= 0 {
+		gridPos.H = 8
+	}
+	return gridPos
+}
+
+func translateWidget(widget models.Widget, dataSourceUID string, santabaClient httpclient.SantabaClient) (*models.GrafanaPanel, []string) {
+	switch widget.Type {
+	case constants.TextWidget:
+		return &models.GrafanaPanel{Type: "text", Title: widget.Name, Description: widget.Description,
+			Options: map[string]interface{}{"mode": "html", "content": widget.Content}}, nil
+	case constants.CustomGraphWidget:
+		if widget.GraphInfo == nil || len(widget.GraphInfo.DataPoints) == 0 {
+			return nil, []string{constants.WidgetHasNoDataPoints}
+		}
+		panel := &models.GrafanaPanel{Type: "timeseries", Title: widget.Name, Description: widget.Description,
+			DataSource: &models.PanelDataSource{Type: constants.PluginId, Uid: dataSourceUID}}
+		reasons := translateCustomGraph(widget.GraphInfo, panel, santabaClient)
+		if len(panel.Targets) == 0 {
+			return nil, reasons
+		}
+		return panel, reasons
+	default:
+		return nil, []string{fmt.Sprintf(constants.WidgetTypeNotSupported, widget.Type)}
+	}
+}
+
+/*
+Datapoints of custom graph having same device, datasource and instance selection are grouped in single query as
+a query can have multiple datapoints. Virtual datapoints are translated when every datapoint they refer to is alone in its query,
+otherwise expression would apply on all series of the query
+*/
+func translateCustomGraph(graphInfo *models.GraphInfo, panel *models.GrafanaPanel, santabaClient httpclient.SantabaClient) []string {
+	var reasons []string
+	var queryKeys []string
+	queries := make(map[string]*models.QueryModel)
+	dataPointQuery := make(map[string]string)
+	for _, dataPoint := range graphInfo.DataPoints {
+		if dataPoint.DeviceDisplayName.IsGlob && strings.ContainsAny(dataPoint.DeviceDisplayName.Value, "*?") {
+			reasons = append(reasons, fmt.Sprintf(constants.MultiDeviceGlobNotSupported, dataPoint.DeviceDisplayName.Value))
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%d|%s|%t", dataPoint.DeviceGroupFullPath.Value, dataPoint.DeviceDisplayName.Value,
+			dataPoint.DataSourceId, dataPoint.InstanceName.Value, dataPoint.InstanceName.IsGlob)
+		queryModel, ok := queries[key]
+		if !ok {
+			var err error
+			queryModel, err = newImportedQueryModel(dataPoint, santabaClient)
+			queries[key] = queryModel
+			if err != nil {
+				reasons = append(reasons, err.Error())
+				continue
+			}
+			queryKeys = append(queryKeys, key)
+		}
+		if queryModel == nil {
+			continue
+		}
+		queryModel.DataPointSelected = append(queryModel.DataPointSelected,
+			models.LabelIntValue{Label: dataPoint.DataPointName, Value: dataPoint.DataPointId})
+		dataPointQuery[dataPoint.Name] = key
+	}
+	refIds := make(map[string]string)
+	for _, key := range queryKeys {
+		refId := getRefId(len(panel.Targets))
+		target, err := toTarget(queries[key], refId, panel.DataSource)
+		if err != nil {
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		refIds[key] = refId
+		panel.Targets = append(panel.Targets, target)
+	}
+	// datapoint name to refId, only for datapoints that are alone in the query
+	operands := make(map[string]string)
+	for name, key := range dataPointQuery {
+		if refId, ok := refIds[key]; ok && len(queries[key].DataPointSelected) == 1 {
+			operands[name] = "$" + refId
+		}
+	}
+	for _, virtualDataPoint := range graphInfo.VirtualDataPoints {
+		expression, ok := translateExpression(virtualDataPoint.Rpn, operands)
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf(constants.VirtualDataPointNotTranslated, virtualDataPoint.Name, virtualDataPoint.Rpn))
+			continue
+		}
+		panel.Targets = append(panel.Targets, map[string]interface{}{
+			"refId":      getRefId(len(panel.Targets)),
+			"datasource": map[string]string{"type": "__expr__", "uid": "__expr__"},
+			"type":       "math",
+			"expression": expression,
+		})
+	}
+	return reasons
+}
+
+// Resolves device id and host datasource id of widget datapoint, these are required for raw data API
+func newImportedQueryModel(dataPoint models.WidgetDataPoint, santabaClient httpclient.SantabaClient) (*models.QueryModel, error) {
+	queryModel := models.QueryModel{
+		SchemaVersion:      models.CurrentQueryModelVersion,
+		TypeSelected:       constants.NormalGroupType,
+		GroupSelected:      models.LabelIntValue{Label: dataPoint.DeviceGroupFullPath.Value},
+		HostSelected:       models.LabelStringValue{Label: dataPoint.DeviceDisplayName.Value},
+		DataSourceSelected: models.DataSource{Ds: dataPoint.DataSourceId, Label: dataPoint.DataSourceFullName},
+		InstanceSelectBy:   constants.Select,
+		QueryMode:          constants.TimeSeriesQueryMode,
+	}
+	response := backend.DataResponse{}
+	queryModel, response = cache.InterpolateHostDetails(santabaClient, queryModel, response)
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	queryModel, response = cache.InterpolateHostDataSourceDetails(santabaClient, queryModel, response)
+	if response.Error != nil {
+		return nil, response.Error
+	}
+	queryModel.DataSourceSelected.Value = queryModel.HdsSelected
+	if definition, err := cache.GetDataSourceDefinition(santabaClient, queryModel); err == nil {
+		queryModel.CollectInterval = definition.CollectInterval
+	}
+	instance := dataPoint.InstanceName.Value
+	if dataPoint.InstanceName.IsGlob || strings.ContainsAny(instance, "*?") {
+		queryModel.InstanceSelectBy = constants.Regex
+		queryModel.InstanceRegex = utils.GlobToRegex(instance)
+		queryModel.ValidInstanceRegex = true
+	} else {
+		queryModel.InstanceSelected = []models.LabelStringValue{{Label: instance, Value: instance}}
+	}
+	return &queryModel, nil
+}
+
+// Target is the query model as json object, with refId and datasource of the panel
+func toTarget(queryModel *models.QueryModel, refId string, dataSource *models.PanelDataSource) (map[string]interface{}, error) {
+	queryJSON, err := json.Marshal(queryModel)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	target := make(map[string]interface{})
+	if err := json.Unmarshal(queryJSON, &target); err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+	target["refId"] = refId
+	target["datasource"] = dataSource
+	return target, nil
+}
+
+// A..Z, then AA, AB...
+func getRefId(i int) string {
+	refId := ""
+	for i >= 0 {
+		refId = string(rune('A'+i%26)) + refId
+		i = i/26 - 1
+	}
+	return refId
+}
+
+/*
+Translates virtual datapoint expression to grafana math expression. Comma separated expressions are RPN, anything else is
+taken as infix. Datapoints are replaced by query refIds, expressions referring to unknown names are not translated
+*/
+func translateExpression(expression string, operands map[string]string) (string, bool) {
+	if strings.Contains(expression, ",") {
+		var stack []string
+		for _, token := range strings.Split(expression, ",") {
+			token = strings.TrimSpace(token)
+			switch token {
+			case "+", "-", "*", "/":
+				if len(stack) < 2 {
+					return "", false
+				}
+				left, right := stack[len(stack)-2], stack[len(stack)-1]
+				stack = append(stack[:len(stack)-2], "("+left+" "+token+" "+right+")")
+			default:
+				operand, ok := translateOperand(token, operands)
+				if !ok {
+					return "", false
+				}
+				stack = append(stack, operand)
+			}
+		}
+		if len(stack) != 1 {
+			return "", false
+		}
+		return stack[0], true
+	}
+	var translated strings.Builder
+	tokens := expressionTokenRegex.FindAllString(expression, -1)
+	if strings.Join(tokens, "") != expression {
+		return "", false
+	}
+	for _, token := range tokens {
+		if strings.TrimSpace(token) == "" || strings.ContainsAny(token, "+-*/()") {
+			translated.WriteString(token)
+			continue
+		}
+		operand, ok := translateOperand(token, operands)
+		if !ok {
+			return "", false
+		}
+		translated.WriteString(operand)
+	}
+	return translated.String(), true
+}
+
+func translateOperand(token string, operands map[string]string) (string, bool) {
+	if operand, ok := operands[token]; ok {
+		return operand, true
+	}
+	if _, err := strconv.ParseFloat(token, 64); err == nil {
+		return token, true
+	}
+	return "", false
+}
@@ -0,0 +1,168 @@
+package logicmonitor_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+)
+
+func textWidgets(from int, to int) string {
+	widgets := make([]string, 0, to-from)
+	for id := from; id < to; id++ {
+		widgets = append(widgets, fmt.Sprintf(`{"id":%d,"name":"note %d","type":"text","content":"text"}`, id, id))
+	}
+	return strings.Join(widgets, ",")
+}
+
+func TestImportDashboardPagesWidgets(t *testing.T) {
+	stub := (&testutil.SantabaStub{}).
+		Route("widgets?format=json&size=1000&offset=1000", http.StatusOK,
+			testutil.Envelope(`{"total":1001,"items":[`+textWidgets(1000, 1001)+`]}`)).
+		Route("widgets?format=json&size=1000&offset=0", http.StatusOK, testutil.Envelope(`{"total":1001,"items":[`+textWidgets(0, 1000)+`]}`)).
+		Route("dashboard/dashboards/3?", http.StatusOK, testutil.Envelope(`{"id":3,"name":"Notes","widgetsConfig":{}}`))
+
+	result, err := logicmonitor.ImportDashboard(3, "uid", stub.Client(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Dashboard.Panels) != 1001 {
+		t.Errorf("imported %d panels, want widgets of both pages", len(result.Dashboard.Panels))
+	}
+	if urls := stub.Requests("size=-1"); len(urls) > 0 {
+		t.Errorf("unbounded page requested: %v", urls)
+	}
+}
+
+func TestTranslateExpression(t *testing.T) {
+	operands := map[string]string{"in": "$A", "out": "$B"}
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{expression: "in,out,+", want: "($A + $B)"},
+		{expression: " in , 8 ,*, out,8,*,+", want: "(($A * 8) + ($B * 8))"},
+		{expression: "in,out,-,out,/", want: "(($A - $B) / $B)"},
+		{expression: "in+out", want: "$A+$B"},
+		{expression: "(in + out) * 8 / 1000", want: "($A + $B) * 8 / 1000"},
+		{expression: "in*0.5", want: "$A*0.5"},
+		{expression: "in,+"},
+		{expression: "in,out"},
+		{expression: "in,total,-"},
+		{expression: "total*2"},
+		{expression: "in^2"},
+		{expression: "if(in,1,0)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, ok := logicmonitor.TranslateExpression(test.expression, operands)
+			if ok != (test.want != "") || got != test.want {
+				t.Errorf("got %q, %v, want %q", got, ok, test.want)
+			}
+		})
+	}
+}
+
+func widgetDataPoint(name string, device models.GlobMatchToggle, instance models.GlobMatchToggle) models.WidgetDataPoint {
+	return models.WidgetDataPoint{Name: name, DataPointName: name, DataSourceId: 5, DataSourceFullName: "Interfaces",
+		DeviceGroupFullPath: models.GlobMatchToggle{Value: "Prod"}, DeviceDisplayName: device, InstanceName: instance}
+}
+
+func TestImportDashboardTranslatesWidgets(t *testing.T) {
+	host := models.GlobMatchToggle{Value: "web-1"}
+	eth0, eth1 := models.GlobMatchToggle{Value: "eth0"}, models.GlobMatchToggle{Value: "eth1"}
+	widgets := []models.Widget{
+		{Id: 1, Name: "Notes", Type: constants.TextWidget, Content: "<b>read me</b>"},
+		{Id: 2, Name: "Traffic", Type: constants.CustomGraphWidget, GraphInfo: &models.GraphInfo{
+			DataPoints: []models.WidgetDataPoint{widgetDataPoint("in", host, eth0), widgetDataPoint("out", host, eth1),
+				widgetDataPoint("errors", host, models.GlobMatchToggle{Value: "eth*", IsGlob: true})},
+			VirtualDataPoints: []models.VirtualDataPoint{{Name: "total", Rpn: "in,out,+"}, {Name: "bad", Rpn: "missing*2"}},
+		}},
+		{Id: 3, Name: "Fleet", Type: constants.CustomGraphWidget, GraphInfo: &models.GraphInfo{
+			DataPoints: []models.WidgetDataPoint{widgetDataPoint("in", models.GlobMatchToggle{Value: "web-*", IsGlob: true}, eth0)},
+		}},
+		{Id: 4, Name: "Empty", Type: constants.CustomGraphWidget},
+		{Id: 5, Name: "Alerts", Type: "alert"},
+		{Id: 6, Name: "Same query", Type: constants.CustomGraphWidget, GraphInfo: &models.GraphInfo{
+			DataPoints:        []models.WidgetDataPoint{widgetDataPoint("in", host, eth0), widgetDataPoint("out", host, eth0)},
+			VirtualDataPoints: []models.VirtualDataPoint{{Name: "total", Rpn: "in,out,+"}},
+		}},
+	}
+	widgetsJSON, _ := json.Marshal(models.Widgets{Total: len(widgets), Items: widgets})
+	stub := (&testutil.SantabaStub{}).
+		Route("widgets?format=json&size=1000&offset=0", http.StatusOK, testutil.Envelope(string(widgetsJSON))).
+		Route("dashboard/dashboards/3?", http.StatusOK,
+			testutil.Envelope(`{"id":3,"name":"Network","widgetsConfig":{"1":{"col":2,"row":1,"sizex":6,"sizey":2}}}`)).
+		Route("autocomplete/names", http.StatusOK, `{"items":["1:web-1"]}`).
+		Route("devices/1/devicedatasources?", http.StatusOK, `{"total":1,"items":[{"id":11}]}`).
+		Route("setting/datasources/5?", http.StatusOK, testutil.Envelope(`{"collectInterval":60,"dataPoints":[]}`))
+
+	result, err := logicmonitor.ImportDashboard(3, "uid", stub.Client(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	panels := result.Dashboard.Panels
+	if len(panels) != 3 || panels[0].Type != "text" || panels[1].Title != "Traffic" || panels[2].Title != "Same query" {
+		t.Fatalf("panels = %+v, want text panel and time series of widgets 2 and 6", panels)
+	}
+	if panels[0].Options["content"] != "<b>read me</b>" || panels[0].GridPos != (models.GridPos{X: 2, Y: 0, W: 12, H: 8}) {
+		t.Errorf("text panel = %+v", panels[0])
+	}
+
+	targets := panels[1].Targets
+	if len(targets) != 4 {
+		t.Fatalf("targets = %v, want query per instance and translated virtual datapoint", targets)
+	}
+	for i, want := range []struct{ refId, selectBy, instance, dataPoint string }{
+		{"A", constants.Select, "eth0", "in"}, {"B", constants.Select, "eth1", "out"}, {"C", constants.Regex, "", "errors"},
+	} {
+		targetJSON, _ := json.Marshal(targets[i])
+		var queryModel models.QueryModel
+		if err := json.Unmarshal(targetJSON, &queryModel); err != nil {
+			t.Fatal(err)
+		}
+		if targets[i]["refId"] != want.refId || queryModel.InstanceSelectBy != want.selectBy || queryModel.HostSelected.Value != "1" ||
+			queryModel.HdsSelected != 11 || queryModel.CollectInterval != 60 || queryModel.DataPointSelected[0].Label != want.dataPoint {
+			t.Errorf("target %d = %s", i, targetJSON)
+		}
+		if want.instance != "" && (len(queryModel.InstanceSelected) != 1 || queryModel.InstanceSelected[0].Label != want.instance) {
+			t.Errorf("target %d selects %v, want %s", i, queryModel.InstanceSelected, want.instance)
+		}
+		if want.selectBy == constants.Regex && queryModel.InstanceRegex != utils.GlobToRegex("eth*") {
+			t.Errorf("target %d regex = %s, want instance glob", i, queryModel.InstanceRegex)
+		}
+	}
+	if targets[3]["type"] != "math" || targets[3]["expression"] != "($A + $B)" {
+		t.Errorf("virtual datapoint target = %v", targets[3])
+	}
+	if len(panels[2].Targets) != 1 {
+		t.Errorf("datapoints of same selection are in %d queries, want 1", len(panels[2].Targets))
+	}
+
+	reasons := make(map[int64]string)
+	for _, untranslated := range result.Untranslated {
+		reasons[untranslated.WidgetId] = untranslated.Reason
+	}
+	want := map[int64]string{
+		2: fmt.Sprintf(constants.VirtualDataPointNotTranslated, "bad", "missing*2"),
+		3: fmt.Sprintf(constants.MultiDeviceGlobNotSupported, "web-*"),
+		4: constants.WidgetHasNoDataPoints,
+		5: fmt.Sprintf(constants.WidgetTypeNotSupported, "alert"),
+		6: fmt.Sprintf(constants.VirtualDataPointNotTranslated, "total", "in,out,+"),
+	}
+	if len(reasons) != len(want) {
+		t.Errorf("untranslated = %v, want %v", reasons, want)
+	}
+	for id, reason := range want {
+		if reasons[id] != reason {
+			t.Errorf("widget %d untranslated because %q, want %q", id, reasons[id], reason)
+		}
+	}
+}
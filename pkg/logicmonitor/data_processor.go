@@ -4,18 +4,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
 	"regexp"
+	"sort"
 	"time"
 
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/tracing"
 	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 /*
@@ -39,6 +42,36 @@ type Job struct {
 
 func GetData(query backend.DataQuery, queryModel models.QueryModel, metaData models.MetaData, santabaClient httpclient.SantabaClient,
 	pluginContext backend.PluginContext) backend.DataResponse {
+	response := getFramesWithTimeShift(query, queryModel, metaData, santabaClient, pluginContext)
+	response = finalizeFrames(response, query, queryModel, santabaClient)
+	return setDiagnostics(response, metaData.Diagnostics, pluginContext)
+}
+
+/*
+Ranking, null policy, downsampling and field config are applied on frames of all devices of the query together,
+so that ranking is across devices and is done on full resolution data as LM sent it, before missing values are replaced
+*/
+func finalizeFrames(response backend.DataResponse, query backend.DataQuery, queryModel models.QueryModel,
+	santabaClient httpclient.SantabaClient) backend.DataResponse {
+	if len(response.Frames) == 0 {
+		return response
+	}
+	if queryModel.QueryMode == constants.RankingQueryMode && queryModel.RankFormat == constants.RankTableFormat {
+		return rankAsTable(response, queryModel)
+	}
+	if queryModel.QueryMode == constants.RankingQueryMode {
+		response = rankSeries(response, queryModel)
+	}
+	for i, frame := range response.Frames {
+		response.Frames[i] = applyNullPolicy(frame, queryModel)
+	}
+	response = downsampleResponse(response, query, queryModel)
+	return setFieldConfig(response, queryModel, santabaClient)
+}
+
+// Gets frames of single device, from cache and API calls for the time ranges not present in cache
+func getFrames(query backend.DataQuery, queryModel models.QueryModel, metaData models.MetaData, santabaClient httpclient.SantabaClient,
+	pluginContext backend.PluginContext) backend.DataResponse {
 
 	response := backend.DataResponse{}
 	finalData := make(map[int]*models.MultiInstanceRawData)
@@ -51,15 +84,32 @@ func GetData(query backend.DataQuery, queryModel models.QueryModel, metaData mod
 		1. wait time is over/requets then calculate time range. Expect a new data if timeRangeForApiCall has entry
 		2. Caclulate time range for rate limits records, multiple call will be made to each time range
 	*/
+	// span covers only the calculation of time ranges, API calls fetching them are siblings of it
 	_, entryPresentInCache := cache.GetData(metaData)
-	if queryModel.EnableStrategicApiCallFeature || !entryPresentInCache {
+	_, span := tracing.Start(santabaClient.Ctx, "cache.GetTimeRanges", attribute.Bool("lm.cache_hit", entryPresentInCache))
+	if queryModel.IncrementalCache() || !entryPresentInCache {
 		response, prependTimeRangeForApiCall, appendTimeRangeForApiCall, metaData = cache.GetTimeRanges(query, queryModel, metaData, pluginContext,
 			response, santabaClient.Logger)
 	}
+	span.SetAttributes(attribute.Int("lm.prepend_ranges", len(prependTimeRangeForApiCall)),
+		attribute.Int("lm.append_ranges", len(appendTimeRangeForApiCall)))
+	tracing.End(span, response.Error)
+	for _, timeRange := range prependTimeRangeForApiCall {
+		metaData.Diagnostics.AddTimeRange(timeRange.From, timeRange.To, prependRange, false)
+	}
+	if from, to, ok := cache.GetCachedTimeRange(metaData); ok && entryPresentInCache {
+		metaData.Diagnostics.AddTimeRange(from, to, cachedRange, true)
+	}
+	for _, timeRange := range appendTimeRangeForApiCall {
+		metaData.Diagnostics.AddTimeRange(timeRange.From, timeRange.To, appendRange, false)
+	}
 
 	// Validate with Single call first for any Errors
 	finalData, response, queryModel = validateWithFirstCall(finalData, queryModel, metaData, santabaClient, pluginContext,
 		response, prependTimeRangeForApiCall, appendTimeRangeForApiCall, false, santabaClient.Logger)
+	if response.Error == nil {
+		metaData.InstanceFilter, response.Error = getInstanceFilter(queryModel, santabaClient)
+	}
 	if response.Error != nil {
 		return response
 	}
@@ -67,7 +117,7 @@ func GetData(query backend.DataQuery, queryModel models.QueryModel, metaData mod
 	/*
 		Get earlier data than what is already in the cache
 	*/
-	finalData = initApiCallsAndAccomulateResponse(prependTimeRangeForApiCall, finalData, queryModel, metaData, santabaClient)
+	finalData = tracedApiCalls("prepend", prependTimeRangeForApiCall, finalData, queryModel, metaData, santabaClient)
 	santabaClient.Logger.Debug("Prepend Nr Of Entries", getNrOfEntries(finalData, 0))
 	/*
 		Get data from cache
@@ -84,7 +134,7 @@ func GetData(query backend.DataQuery, queryModel models.QueryModel, metaData mod
 		Get latest data. expected more data than in cache
 	*/
 	lenTillCached := len(finalData)
-	finalData = initApiCallsAndAccomulateResponse(appendTimeRangeForApiCall, finalData, queryModel, metaData, santabaClient)
+	finalData = tracedApiCalls("append", appendTimeRangeForApiCall, finalData, queryModel, metaData, santabaClient)
 	santabaClient.Logger.Debug("Append Number of entries", getNrOfEntries(finalData, lenTillCached))
 	santabaClient.Logger.Debug("Total Number of entries", getNrOfEntries(finalData, 0))
 	if len(finalData) == 0 {
@@ -92,7 +142,10 @@ func GetData(query backend.DataQuery, queryModel models.QueryModel, metaData mod
 			response.Error = errors.New(constants.NoDataFromLM)
 		}
 	} else {
+		_, span = tracing.Start(santabaClient.Ctx, "processFinalData", attribute.Int("lm.raw_data_entries", len(finalData)))
 		response = processFinalData(queryModel, metaData, query.TimeRange.From.Unix(), query.TimeRange.To.Unix(), finalData, response, santabaClient.Logger)
+		span.SetAttributes(attribute.Int("lm.frames", len(response.Frames)))
+		tracing.End(span, response.Error)
 		santabaClient.Logger.Debug("size of data in bytes", cache.GetRealSize(metaData))
 	}
 
@@ -125,6 +178,19 @@ func validateWithFirstCall(finalData map[int]*models.MultiInstanceRawData, query
 	return finalData, response, queryModel
 }
 
+// Api calls of a time range list are made in a span, so that calls made in parallel show up under it
+func tracedApiCalls(position string, timeRangeForApiCall []models.PendingTimeRange, rawDataMap map[int]*models.MultiInstanceRawData,
+	queryModel models.QueryModel, metaData models.MetaData, santabaClient httpclient.SantabaClient) map[int]*models.MultiInstanceRawData {
+	if len(timeRangeForApiCall) == 0 {
+		return rawDataMap
+	}
+	var span trace.Span
+	santabaClient.Ctx, span = tracing.Start(santabaClient.Ctx, "initApiCallsAndAccomulateResponse",
+		attribute.String("lm.position", position), attribute.Int("lm.api_calls", len(timeRangeForApiCall)))
+	defer span.End()
+	return initApiCallsAndAccomulateResponse(timeRangeForApiCall, rawDataMap, queryModel, metaData, santabaClient)
+}
+
 /*
 Initiate goroutines to call API for each time range caclulated
 */
@@ -167,6 +233,7 @@ func call(jobId int, fromTime int64, toTime int64, santabaClient httpclient.Sant
 	rawData.ToTime = toTime
 	fullPath := utils.BuildURLReplacingQueryParams(constants.RawDataMultiInstanceReq, queryModel, rawData.FromTime, rawData.ToTime, metaData)
 	santabaClient.Logger.Info("Calling API  => ", santabaClient.PluginSettings.Path, fullPath)
+	metaData.Diagnostics.AddCalledURL(fullPath)
 	respByte, err := santabaClient.Get(fullPath, constants.RawDataMultiInstanceReq)
 	santabaClient.Logger.Info("Calling API Done  => ", santabaClient.PluginSettings.Path, fullPath)
 	if err != nil {
@@ -188,6 +255,9 @@ func processFinalData(queryModel models.QueryModel, metaData models.MetaData, fr
 	response backend.DataResponse, logger log.Logger) backend.DataResponse {
 	var dataFrameMap = make(map[string]*data.Frame)
 	finalDataMerged := make(map[string]models.ValuesAndTime)
+	matchedInstances := make(map[string]bool)
+	nullable := queryModel.NullPolicy == constants.NullAsNull
+	var conversionErr error
 	// Below loop gets the recent data first. So as to reduce the cost of sorting
 	for k := len(rawDataMap) - 1; k >= 0; k-- {
 		if rawDataMap[k].Error != "OK" {
@@ -198,6 +268,7 @@ func processFinalData(queryModel models.QueryModel, metaData models.MetaData, fr
 		for instanceName, valueAndTime := range rawDataMap[k].Data.Instances {
 			// Check if instance selected/regex matching
 			shortenInstance, matched := utils.IsInstanceMatched(metaData, &queryModel, rawDataMap[k].Data.DataSourceName, instanceName)
+			matchedInstances[instanceName] = matchedInstances[instanceName] || matched
 			if matched {
 				if len(valueAndTime.Time) > 0 {
 					cache.StoreLastTimeStamp(metaData, time.UnixMilli(valueAndTime.Time[0]).Unix())
@@ -205,7 +276,7 @@ func processFinalData(queryModel models.QueryModel, metaData models.MetaData, fr
 				metaData.MatchedInstances = true
 				var frame *data.Frame
 				dataPontMap := make(map[string]int)
-				frame = utils.GetFrame(dataFrameMap, shortenInstance, queryModel.DataPointSelected)
+				frame = utils.GetFrame(dataFrameMap, shortenInstance, queryModel.DataPointSelected, nullable)
 				// this dataPontMap is to keep indexs of datapoints so as to get value from Values array for selected datapoints
 				for i, v := range rawDataMap[k].Data.DataPoints {
 					dataPontMap[v] = i
@@ -220,11 +291,12 @@ func processFinalData(queryModel models.QueryModel, metaData models.MetaData, fr
 						vals[0] = time.UnixMilli(valueAndTime.Time[i])
 						for _, dp := range queryModel.DataPointSelected {
 							fieldIdx := dataPontMap[dp.Label]
-							if valueAndTime.Values[i][fieldIdx] == constants.NoData {
-								vals[idx] = math.NaN()
-							} else {
-								vals[idx] = valueAndTime.Values[i][fieldIdx]
+							value, ok, err := toFloat(valueAndTime.Values[i][fieldIdx])
+							if err != nil && conversionErr == nil {
+								conversionErr = fmt.Errorf(constants.NonNumericDataPoint, dp.Label, shortenInstance,
+									valueAndTime.Values[i][fieldIdx])
 							}
+							vals[idx] = rowValue(value, ok, nullable)
 							idx++
 						}
 						frame.AppendRow(vals...)
@@ -249,14 +321,25 @@ func processFinalData(queryModel models.QueryModel, metaData models.MetaData, fr
 			}
 		}
 	}
+	metaData.Diagnostics.AddInstanceCounts(countMatched(matchedInstances), len(matchedInstances))
+	if conversionErr != nil && response.Error == nil {
+		logger.Error(conversionErr.Error())
+		response.Error = conversionErr
+	}
 	// Check for errors, add frames to response and store data in cache
 	if !metaData.MatchedInstances && len(dataFrameMap) == 0 && response.Error == nil {
 		response.Error = errors.New(constants.InstancesNotMatchingWithHosts)
 	} else {
 		if len(dataFrameMap) > 0 {
 			response.Frames = nil
-			for _, frame := range dataFrameMap {
-				response.Frames = append(response.Frames, frame)
+			// frames are in natural order of instance name so that series keep their colours across refreshes
+			instances := make([]string, 0, len(dataFrameMap))
+			for instance := range dataFrameMap {
+				instances = append(instances, instance)
+			}
+			sort.Slice(instances, func(i, j int) bool { return utils.NaturalLess(instances[i], instances[j]) })
+			for _, instance := range instances {
+				response.Frames = append(response.Frames, sortFrame(dataFrameMap[instance]))
 			}
 		}
 		cache.StoreData(metaData, &models.MultiInstanceRawData{Data: models.MultiInstanceData{
@@ -268,6 +351,16 @@ func processFinalData(queryModel models.QueryModel, metaData models.MetaData, fr
 	return response
 }
 
+func countMatched(matchedInstances map[string]bool) int {
+	nrOfMatched := 0
+	for _, matched := range matchedInstances {
+		if matched {
+			nrOfMatched++
+		}
+	}
+	return nrOfMatched
+}
+
 func getNrOfEntries(data map[int]*models.MultiInstanceRawData, from int) int {
 	tot := 0
 	if len(data) > 0 {
@@ -287,3 +380,16 @@ func getNrForSingleEntry(instances map[string]models.ValuesAndTime) int {
 	}
 	return minNumOfEntriesFromInstances
 }
+
+// Instance properties are fetched only when query filters on them
+func getInstanceFilter(queryModel models.QueryModel, santabaClient httpclient.SantabaClient) (models.InstanceMatcher, error) {
+	var instanceProperties map[string]map[string]string
+	if queryModel.InstancePropertyFilter != "" {
+		var err error
+		instanceProperties, err = cache.GetInstanceProperties(santabaClient, queryModel)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+	}
+	return utils.NewInstanceFilter(&queryModel, instanceProperties) //nolint:wrapcheck
+}
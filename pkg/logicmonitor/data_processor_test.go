@@ -0,0 +1,94 @@
+package logicmonitor_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+func processQueryModel() models.QueryModel {
+	return models.QueryModel{
+		SchemaVersion:     models.CurrentQueryModelVersion,
+		DataPointSelected: []models.LabelIntValue{{Label: "idle"}},
+		InstanceSelectBy:  "Regex", InstanceRegex: ".*", ValidInstanceRegex: true,
+		CollectInterval: 60,
+	}
+}
+
+func processMetaData(t *testing.T) models.MetaData {
+	metaData := models.MetaData{Id: t.Name(), QueryId: t.Name(), CacheTTLInSeconds: 60, Diagnostics: &models.QueryDiagnostics{}}
+	t.Cleanup(func() { cache.Remove(metaData) })
+	return metaData
+}
+
+// chunk is raw data of one API call, samples are newest first as LM sends them
+func chunk(from int64, to int64, instances map[string][][2]float64) *models.MultiInstanceRawData {
+	rawData := &models.MultiInstanceRawData{Error: "OK", FromTime: from, ToTime: to,
+		Data: models.MultiInstanceData{DataSourceName: "CPU", DataPoints: []string{"idle"}, Instances: map[string]models.ValuesAndTime{}}}
+	for instance, samples := range instances {
+		var valuesAndTime models.ValuesAndTime
+		for _, sample := range samples {
+			valuesAndTime.Time = append(valuesAndTime.Time, int64(sample[0])*1000)
+			valuesAndTime.Values = append(valuesAndTime.Values, []interface{}{sample[1]})
+		}
+		rawData.Data.Instances[instance] = valuesAndTime
+	}
+	return rawData
+}
+
+func TestOverlappingChunksKeepMostRecentRow(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute).Unix()
+	// chunk 1 is the most recent API call, it overlaps older chunk 0 at base+120 and has the corrected value there
+	rawDataMap := map[int]*models.MultiInstanceRawData{
+		0: chunk(base, base+120, map[string][][2]float64{"CPU-0": {{float64(base + 120), 1}, {float64(base + 60), 1}, {float64(base), 1}}}),
+		1: chunk(base+120, base+240, map[string][][2]float64{"CPU-0": {{float64(base + 240), 2}, {float64(base + 180), 2}, {float64(base + 120), 2}}}),
+	}
+
+	response := logicmonitor.ProcessFinalData(processQueryModel(), processMetaData(t), base, base+240, rawDataMap,
+		backend.DataResponse{}, log.DefaultLogger)
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	if len(response.Frames) != 1 {
+		t.Fatalf("got %d frames, want 1", len(response.Frames))
+	}
+	frame := response.Frames[0]
+	want := []struct {
+		time  int64
+		value float64
+	}{{base, 1}, {base + 60, 1}, {base + 120, 2}, {base + 180, 2}, {base + 240, 2}}
+	if frame.Rows() != len(want) {
+		t.Fatalf("got %d rows, want %d with overlapping sample once", frame.Rows(), len(want))
+	}
+	for i, w := range want {
+		rowTime := frame.Fields[0].At(i).(time.Time).Unix()
+		value := frame.Fields[1].At(i).(float64)
+		if rowTime != w.time || value != w.value || math.IsNaN(value) {
+			t.Errorf("row %d is %d=%v, want %d=%v", i, rowTime, value, w.time, w.value)
+		}
+	}
+}
+
+func TestFramesInNaturalOrderOfInstance(t *testing.T) {
+	base := time.Now().Add(-time.Hour).Truncate(time.Minute).Unix()
+	sample := [][2]float64{{float64(base), 1}}
+	rawDataMap := map[int]*models.MultiInstanceRawData{
+		0: chunk(base, base+60, map[string][][2]float64{"CPU-eth10": sample, "CPU-eth2": sample, "CPU-eth1": sample}),
+	}
+
+	response := logicmonitor.ProcessFinalData(processQueryModel(), processMetaData(t), base, base+60, rawDataMap,
+		backend.DataResponse{}, log.DefaultLogger)
+	var got []string
+	for _, frame := range response.Frames {
+		got = append(got, frame.RefID)
+	}
+	if len(got) != 3 || got[0] != "eth1" || got[1] != "eth2" || got[2] != "eth10" {
+		t.Errorf("frames of instances %v, want [eth1 eth2 eth10]", got)
+	}
+}
@@ -0,0 +1,39 @@
+package logicmonitor
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	prependRange = "prepend"
+	cachedRange  = "cache"
+	appendRange  = "append"
+)
+
+/*
+Sets snapshot of query diagnostics on meta of first frame of response, URLs called are set as executed query string and rest as
+custom meta. Grafana query inspector shows meta of the first frame, response without frames has nothing to show it on
+*/
+func setDiagnostics(response backend.DataResponse, diagnostics *models.QueryDiagnostics, pluginContext backend.PluginContext) backend.DataResponse {
+	if diagnostics == nil || len(response.Frames) == 0 {
+		return response
+	}
+	snapshot := diagnostics.Snapshot()
+	if pluginContext.DataSourceInstanceSettings != nil {
+		snapshot.ApiCallsRemaining = constants.MaxApiCallsRateLimit -
+			cache.GetNrOfApiCalls(pluginContext.DataSourceInstanceSettings.UID).NrOfCalls
+	}
+	frame := response.Frames[0]
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	frame.Meta.Custom = snapshot
+	frame.Meta.ExecutedQueryString = strings.Join(snapshot.CalledURLs, "\n")
+	return response
+}
@@ -0,0 +1,92 @@
+package logicmonitor_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestDiagnostics(t *testing.T) {
+	now := time.Now()
+	sample := fmt.Sprintf(`{"time":[%d],"values":[[1]]}`, now.Add(-5*time.Minute).UnixMilli())
+	stub := (&testutil.SantabaStub{}).Route("devices/1/devicedatasources/11/data", http.StatusOK, fmt.Sprintf(`{"errmsg":"OK","status":200,
+		"data":{"dataSourceName":"CPU","dataPoints":["idle"],"instances":{"CPU-a0":%s,"CPU-a1":%s,"CPU-b0":%s}}}`, sample, sample, sample))
+	queryJSON, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 1, "groupSelected": map[string]interface{}{"label": "Prod", "value": 7},
+		"hostSelected":       map[string]interface{}{"label": "web-1", "value": "1"},
+		"hdsSelected":        11,
+		"dataSourceSelected": map[string]interface{}{"ds": 5, "label": "CPU"},
+		"dataPointSelected":  []interface{}{map[string]interface{}{"label": "idle"}},
+		"instanceSelectBy":   constants.Regex, "instanceRegex": "^a", "validInstanceRegex": true, "collectInterval": 60,
+	})
+	query := backend.DataQuery{RefID: "A", JSON: queryJSON, TimeRange: backend.TimeRange{From: now.Add(-30 * time.Minute), To: now}}
+	pluginContext := backend.PluginContext{DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{UID: t.Name()}}
+	diagnosticsOf := func(response backend.DataResponse) *models.QueryDiagnostics {
+		t.Helper()
+		if response.Error != nil {
+			t.Fatal(response.Error)
+		}
+		if len(response.Frames) != 2 {
+			t.Fatalf("got %d frames, want frame of each matched instance", len(response.Frames))
+		}
+		if response.Frames[0].Meta == nil {
+			t.Fatal("first frame has no meta")
+		}
+		diagnostics, ok := response.Frames[0].Meta.Custom.(*models.QueryDiagnostics)
+		if !ok {
+			t.Fatalf("custom meta of first frame is %T, want diagnostics", response.Frames[0].Meta.Custom)
+		}
+		for _, frame := range response.Frames[1:] {
+			if frame.Meta != nil && frame.Meta.Custom != nil {
+				t.Errorf("frame %s carries diagnostics, want them on first frame only", frame.Name)
+			}
+		}
+		if remaining := constants.MaxApiCallsRateLimit - cache.GetNrOfApiCalls(t.Name()).NrOfCalls; diagnostics.ApiCallsRemaining != remaining {
+			t.Errorf("api calls remaining = %d, want %d", diagnostics.ApiCallsRemaining, remaining)
+		}
+		return diagnostics
+	}
+
+	first := diagnosticsOf(logicmonitor.Query(stub.Client(t), pluginContext, query))
+	dataCalls := len(stub.Requests("/data?"))
+	if first.ApiCallsConsumed != dataCalls || len(first.CalledURLs) != dataCalls {
+		t.Errorf("first query reports %d calls of %v, want %d", first.ApiCallsConsumed, first.CalledURLs, dataCalls)
+	}
+	for _, timeRange := range first.TimeRanges {
+		if timeRange.CacheHit || timeRange.Source == "cache" {
+			t.Errorf("first query reports range %+v from cache", timeRange)
+		}
+		if timeRange.From < query.TimeRange.From.Unix() || timeRange.To > query.TimeRange.To.Unix() {
+			t.Errorf("range %+v is outside of query range", timeRange)
+		}
+	}
+	if first.MatchedInstances != 2 || first.TotalInstances != 3 {
+		t.Errorf("instances %d of %d, want 2 of 3", first.MatchedInstances, first.TotalInstances)
+	}
+
+	second := diagnosticsOf(logicmonitor.Query(stub.Client(t), pluginContext, query))
+	cacheHits := 0
+	for _, timeRange := range second.TimeRanges {
+		if timeRange.CacheHit && timeRange.Source == "cache" {
+			cacheHits++
+		}
+	}
+	if cacheHits != 1 {
+		t.Errorf("second query reports ranges %+v, want one range from cache", second.TimeRanges)
+	}
+	if calls := len(stub.Requests("/data?")) - dataCalls; second.ApiCallsConsumed != calls {
+		t.Errorf("second query reports %d calls, want %d", second.ApiCallsConsumed, calls)
+	}
+	if first.ApiCallsConsumed != dataCalls {
+		t.Error("diagnostics of first response changed with second query")
+	}
+}
@@ -0,0 +1,242 @@
+package logicmonitor
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+/*
+Downsamples frames of the response as per query interval and max data points. Raw data in cache is not affected,
+only frames sent to grafana are reduced. Nothing is done when bucket size is not greater than datasource collect interval,
+that is when each bucket would get a single sample anyway
+*/
+func downsampleResponse(response backend.DataResponse, query backend.DataQuery, queryModel models.QueryModel) backend.DataResponse {
+	bucketSize := getBucketSize(query, queryModel)
+	if bucketSize <= 0 {
+		return response
+	}
+	for i, frame := range response.Frames {
+		if len(frame.Fields) < 2 || frame.Rows() < 2 {
+			continue
+		}
+		if queryModel.DownsampleFunction == constants.DownsampleLttb {
+			threshold := int((query.TimeRange.To.Unix() - query.TimeRange.From.Unix()) / int64(bucketSize.Seconds()))
+			response.Frames[i] = lttbFrame(frame, threshold)
+		} else {
+			response.Frames[i] = bucketFrame(frame, bucketSize, queryModel.DownsampleFunction)
+		}
+	}
+	return response
+}
+
+func getBucketSize(query backend.DataQuery, queryModel models.QueryModel) time.Duration {
+	bucketSize := query.Interval.Truncate(time.Second)
+	if query.MaxDataPoints > 0 {
+		perPoint := time.Duration((query.TimeRange.To.Unix()-query.TimeRange.From.Unix())/query.MaxDataPoints) * time.Second
+		if perPoint > bucketSize {
+			bucketSize = perPoint
+		}
+	}
+	if bucketSize <= time.Duration(queryModel.CollectInterval)*time.Second {
+		return 0
+	}
+	return bucketSize
+}
+
+type bucket struct {
+	sum      []float64
+	count    []int
+	min      []float64
+	max      []float64
+	last     []float64
+	lastTime []int64
+}
+
+func newBucket(nrOfFields int) *bucket {
+	b := &bucket{sum: make([]float64, nrOfFields), count: make([]int, nrOfFields), min: make([]float64, nrOfFields),
+		max: make([]float64, nrOfFields), last: make([]float64, nrOfFields), lastTime: make([]int64, nrOfFields)}
+	for i := range b.min {
+		b.min[i] = math.Inf(1)
+		b.max[i] = math.Inf(-1)
+		b.lastTime[i] = math.MinInt64
+	}
+	return b
+}
+
+func (b *bucket) add(fieldIdx int, t int64, v float64) {
+	b.sum[fieldIdx] += v
+	b.count[fieldIdx]++
+	b.min[fieldIdx] = math.Min(b.min[fieldIdx], v)
+	b.max[fieldIdx] = math.Max(b.max[fieldIdx], v)
+	if t >= b.lastTime[fieldIdx] {
+		b.last[fieldIdx] = v
+		b.lastTime[fieldIdx] = t
+	}
+}
+
+func (b *bucket) value(fieldIdx int, function string) (float64, bool) {
+	if b.count[fieldIdx] == 0 {
+		return 0, false
+	}
+	switch function {
+	case constants.DownsampleMin:
+		return b.min[fieldIdx], true
+	case constants.DownsampleMax:
+		return b.max[fieldIdx], true
+	case constants.DownsampleLast:
+		return b.last[fieldIdx], true
+	default:
+		return b.sum[fieldIdx] / float64(b.count[fieldIdx]), true
+	}
+}
+
+// Groups rows in buckets of bucketSize and reduces each value field with given function. Rows of result are sorted by time
+func bucketFrame(frame *data.Frame, bucketSize time.Duration, function string) *data.Frame {
+	buckets := make(map[int64]*bucket)
+	for row := 0; row < frame.Rows(); row++ {
+		t, ok := frame.Fields[0].At(row).(time.Time)
+		if !ok {
+			return frame
+		}
+		bucketTime := t.Truncate(bucketSize).UnixMilli()
+		b, ok := buckets[bucketTime]
+		if !ok {
+			b = newBucket(len(frame.Fields))
+			buckets[bucketTime] = b
+		}
+		for fieldIdx := 1; fieldIdx < len(frame.Fields); fieldIdx++ {
+			if v, ok := floatAt(frame.Fields[fieldIdx], row); ok {
+				b.add(fieldIdx, t.UnixMilli(), v)
+			}
+		}
+	}
+	bucketTimes := make([]int64, 0, len(buckets))
+	for bucketTime := range buckets {
+		bucketTimes = append(bucketTimes, bucketTime)
+	}
+	sort.Slice(bucketTimes, func(i, j int) bool { return bucketTimes[i] < bucketTimes[j] })
+	downsampled := frame.EmptyCopy()
+	for _, bucketTime := range bucketTimes {
+		downsampled.Fields[0].Append(time.UnixMilli(bucketTime))
+		for fieldIdx := 1; fieldIdx < len(frame.Fields); fieldIdx++ {
+			v, ok := buckets[bucketTime].value(fieldIdx, function)
+			appendFloat(downsampled.Fields[fieldIdx], v, ok)
+		}
+	}
+	return downsampled
+}
+
+/*
+Largest-Triangle-Three-Buckets keeps the points that preserve shape of the series. Points are selected for each value field
+and rows selected for any field are kept, threshold is shared among fields so that result stays within max data points
+*/
+func lttbFrame(frame *data.Frame, threshold int) *data.Frame {
+	rows := make([]int, frame.Rows())
+	times := make([]int64, frame.Rows())
+	for row := range rows {
+		t, ok := frame.Fields[0].At(row).(time.Time)
+		if !ok {
+			return frame
+		}
+		rows[row] = row
+		times[row] = t.UnixMilli()
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return times[rows[i]] < times[rows[j]] })
+	threshold = threshold / (len(frame.Fields) - 1)
+	if threshold < 3 {
+		threshold = 3
+	}
+	selected := make(map[int]bool)
+	for fieldIdx := 1; fieldIdx < len(frame.Fields); fieldIdx++ {
+		var points []int
+		for _, row := range rows {
+			if _, ok := floatAt(frame.Fields[fieldIdx], row); ok {
+				points = append(points, row)
+			}
+		}
+		for _, row := range lttb(points, times, frame.Fields[fieldIdx], threshold) {
+			selected[row] = true
+		}
+	}
+	downsampled := frame.EmptyCopy()
+	for _, row := range rows {
+		if selected[row] {
+			downsampled.AppendRow(frame.RowCopy(row)...)
+		}
+	}
+	return downsampled
+}
+
+// Returns rows to keep out of points, points are row indexes sorted by time having a value for given field
+func lttb(points []int, times []int64, field *data.Field, threshold int) []int {
+	if threshold >= len(points) {
+		return points
+	}
+	selected := make([]int, 0, threshold)
+	selected = append(selected, points[0])
+	bucketSize := float64(len(points)-2) / float64(threshold-2)
+	a := 0
+	for i := 0; i < threshold-2; i++ {
+		// average point of next bucket
+		nextStart := int(float64(i+1)*bucketSize) + 1
+		nextEnd := int(float64(i+2)*bucketSize) + 1
+		if nextEnd > len(points) {
+			nextEnd = len(points)
+		}
+		var avgX, avgY float64
+		for _, row := range points[nextStart:nextEnd] {
+			v, _ := floatAt(field, row)
+			avgX += float64(times[row])
+			avgY += v
+		}
+		avgX /= float64(nextEnd - nextStart)
+		avgY /= float64(nextEnd - nextStart)
+		// point of current bucket forming largest triangle with selected point and next bucket average
+		start := int(float64(i)*bucketSize) + 1
+		end := int(float64(i+1)*bucketSize) + 1
+		ax := float64(times[points[a]])
+		ay, _ := floatAt(field, points[a])
+		maxArea := -1.0
+		next := start
+		for j := start; j < end; j++ {
+			v, _ := floatAt(field, points[j])
+			area := math.Abs((ax-avgX)*(v-ay) - (ax-float64(times[points[j]]))*(avgY-ay))
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+		selected = append(selected, points[next])
+		a = next
+	}
+	return append(selected, points[len(points)-1])
+}
+
+// Value of a numeric field at row, false when value is null or NaN
+func floatAt(field *data.Field, row int) (float64, bool) {
+	v, err := field.NullableFloatAt(row)
+	if err != nil || v == nil || math.IsNaN(*v) {
+		return 0, false
+	}
+	return *v, true
+}
+
+func appendFloat(field *data.Field, v float64, ok bool) {
+	if field.Nullable() {
+		if ok {
+			field.Append(&v)
+		} else {
+			field.Append(nil)
+		}
+	} else if ok {
+		field.Append(v)
+	} else {
+		field.Append(math.NaN())
+	}
+}
@@ -0,0 +1,173 @@
+package logicmonitor_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var downsampleStart = time.Unix(1_700_000_000, 0).Truncate(time.Hour) //nolint:gochecknoglobals
+
+// minuteFrame has a sample every minute from downsampleStart, nil values are nulls
+func minuteFrame(values ...*float64) *data.Frame {
+	times := make([]time.Time, len(values))
+	for i := range times {
+		times[i] = downsampleStart.Add(time.Duration(i) * time.Minute)
+	}
+	return data.NewFrame(constants.ResponseStr, data.NewField(constants.TimeStr, nil, times),
+		data.NewField("CPU-0 ~ idle", nil, values))
+}
+
+func floats(values ...float64) []*float64 {
+	pointers := make([]*float64, len(values))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	return pointers
+}
+
+func frameValues(frame *data.Frame) string {
+	var values []string
+	for row := 0; row < frame.Rows(); row++ {
+		if v, ok := frame.Fields[1].ConcreteAt(row); ok {
+			values = append(values, fmt.Sprint(v))
+		} else {
+			values = append(values, "null")
+		}
+	}
+	return fmt.Sprint(values)
+}
+
+func TestGetBucketSize(t *testing.T) {
+	hour := backend.TimeRange{From: downsampleStart, To: downsampleStart.Add(time.Hour)}
+	tests := []struct {
+		name            string
+		interval        time.Duration
+		maxDataPoints   int64
+		collectInterval int64
+		want            time.Duration
+	}{
+		{name: "interval", interval: 5 * time.Minute, collectInterval: 60, want: 5 * time.Minute},
+		{name: "interval truncated to seconds", interval: 5*time.Minute + 300*time.Millisecond, collectInterval: 60, want: 5 * time.Minute},
+		{name: "max data points wider than interval", interval: time.Minute, maxDataPoints: 6, collectInterval: 30, want: 10 * time.Minute},
+		{name: "interval wider than max data points", interval: 20 * time.Minute, maxDataPoints: 6, collectInterval: 60, want: 20 * time.Minute},
+		{name: "max data points only", maxDataPoints: 12, collectInterval: 60, want: 5 * time.Minute},
+		{name: "collect interval", interval: time.Minute, maxDataPoints: 60, collectInterval: 60, want: 0},
+		{name: "finer than collect interval", interval: 30 * time.Second, maxDataPoints: 1000, collectInterval: 60, want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query := backend.DataQuery{TimeRange: hour, Interval: test.interval, MaxDataPoints: test.maxDataPoints}
+			got := logicmonitor.GetBucketSize(query, models.QueryModel{CollectInterval: test.collectInterval})
+			if got != test.want {
+				t.Errorf("bucket size = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBucketFrame(t *testing.T) {
+	// buckets of 3 minutes: [4 nil 2] [nil nil nil] [1 5]
+	values := []*float64{float(4), nil, float(2), nil, nil, nil, float(1), float(5)}
+	tests := []struct {
+		function string
+		want     string
+	}{
+		{function: constants.DownsampleAvg, want: "[3 null 3]"},
+		{function: "", want: "[3 null 3]"},
+		{function: constants.DownsampleMin, want: "[2 null 1]"},
+		{function: constants.DownsampleMax, want: "[4 null 5]"},
+		{function: constants.DownsampleLast, want: "[2 null 5]"},
+	}
+	for _, test := range tests {
+		t.Run(test.function, func(t *testing.T) {
+			frame := logicmonitor.BucketFrame(minuteFrame(values...), 3*time.Minute, test.function)
+			if got := frameValues(frame); got != test.want {
+				t.Errorf("values = %s, want %s", got, test.want)
+			}
+			for row, want := range []time.Duration{0, 3 * time.Minute, 6 * time.Minute} {
+				if got := frame.Fields[0].At(row).(time.Time); !got.Equal(downsampleStart.Add(want)) {
+					t.Errorf("bucket %d is at %v, want start of bucket", row, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBucketFrameOfNonNullableField(t *testing.T) {
+	times := []time.Time{downsampleStart, downsampleStart.Add(time.Minute), downsampleStart.Add(2 * time.Minute)}
+	frame := data.NewFrame(constants.ResponseStr, data.NewField(constants.TimeStr, nil, times),
+		data.NewField("CPU-0 ~ idle", nil, []float64{math.NaN(), math.NaN(), 3}))
+	downsampled := logicmonitor.BucketFrame(frame, 2*time.Minute, constants.DownsampleAvg)
+	if downsampled.Rows() != 2 || !math.IsNaN(downsampled.Fields[1].At(0).(float64)) || downsampled.Fields[1].At(1).(float64) != 3 {
+		t.Errorf("values = %v, %v, want NaN for bucket without values", downsampled.Fields[1].At(0), downsampled.Fields[1].At(1))
+	}
+}
+
+func TestLttbFrame(t *testing.T) {
+	values := make([]float64, 100)
+	for i := range values {
+		values[i] = float64(i % 10)
+	}
+	values[42] = 1000
+	frame := logicmonitor.LttbFrame(minuteFrame(floats(values...)...), 10)
+	if frame.Rows() != 10 {
+		t.Fatalf("got %d rows, want threshold", frame.Rows())
+	}
+	if first := frame.Fields[0].At(0).(time.Time); !first.Equal(downsampleStart) {
+		t.Errorf("first row is at %v, want first sample", first)
+	}
+	if last := frame.Fields[0].At(frame.Rows() - 1).(time.Time); !last.Equal(downsampleStart.Add(99 * time.Minute)) {
+		t.Errorf("last row is at %v, want last sample", last)
+	}
+	peak := false
+	for row := 0; row < frame.Rows(); row++ {
+		peak = peak || *frame.Fields[1].At(row).(*float64) == 1000
+	}
+	if !peak {
+		t.Errorf("peak is not kept: %s", frameValues(frame))
+	}
+
+	withNulls := minuteFrame(nil, &values[1], nil, &values[3], nil)
+	if got := frameValues(logicmonitor.LttbFrame(withNulls, 3)); got != "[1 3]" {
+		t.Errorf("values = %s, want nulls dropped and rest kept when under threshold", got)
+	}
+}
+
+func TestDownsampleResponse(t *testing.T) {
+	values := make([]float64, 120)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	twoHours := backend.TimeRange{From: downsampleStart, To: downsampleStart.Add(2 * time.Hour)}
+	tests := []struct {
+		name     string
+		query    backend.DataQuery
+		function string
+		want     int
+	}{
+		{name: "avg by interval", query: backend.DataQuery{TimeRange: twoHours, Interval: 10 * time.Minute}, want: 12},
+		{name: "avg by max data points", query: backend.DataQuery{TimeRange: twoHours, Interval: time.Minute, MaxDataPoints: 8}, want: 8},
+		{name: "lttb threshold is range by bucket", query: backend.DataQuery{TimeRange: twoHours, Interval: 5 * time.Minute},
+			function: constants.DownsampleLttb, want: 24},
+		{name: "nothing at collect interval", query: backend.DataQuery{TimeRange: twoHours, Interval: time.Minute, MaxDataPoints: 1000},
+			want: 120},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queryModel := models.QueryModel{CollectInterval: 60, DownsampleFunction: test.function}
+			response := backend.DataResponse{Frames: data.Frames{minuteFrame(floats(values...)...)}}
+			response = logicmonitor.DownsampleResponse(response, test.query, queryModel)
+			if got := response.Frames[0].Rows(); got != test.want {
+				t.Errorf("got %d rows, want %d", got, test.want)
+			}
+		})
+	}
+}
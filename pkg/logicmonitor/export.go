@@ -0,0 +1,176 @@
+package logicmonitor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// ErrInvalidExport is wrapped by errors caused by the export request itself, as opposed to errors from LM
+var ErrInvalidExport = errors.New("invalid export request") //nolint:gochecknoglobals
+
+/*
+ExportData writes raw samples of query in time range of export request to w, in format of the request. Request is validated
+before anything is written to w. Time range is walked in windows of as many samples as
+single raw data API call returns, each window is fetched like a panel query, so api call throttler applies. Windows throttled are
+retried after a wait. Samples of each window are flushed to w, and through w when it is an http.Flusher, before the next window
+is fetched, so ranges far larger than a panel would request can be exported
+*/
+func ExportData(request models.ExportRequest, santabaClient httpclient.SantabaClient, pluginContext backend.PluginContext,
+	w io.Writer) (err error) {
+	if _, ok := exportFormats[request.Format]; !ok {
+		return fmt.Errorf("%w: "+constants.InvalidExportFormat, ErrInvalidExport, request.Format)
+	}
+	queryModel, err := models.UnmarshalQueryModel(request.Query)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidExport, err.Error())
+	}
+	if request.To <= request.From {
+		return fmt.Errorf("%w: %s", ErrInvalidExport, constants.InvalidExportTimeRange)
+	}
+	if queryModel.PropertyFilter != "" {
+		return fmt.Errorf("%w: %s", ErrInvalidExport, constants.ExportPropertyFilterUnsupported)
+	}
+	queryModel = exportQueryModel(queryModel)
+	if queryModel.IsQueryInterpolated {
+		response := backend.DataResponse{}
+		queryModel, response = cache.InterpolateHostDataSourceDetails(santabaClient, queryModel, response)
+		if response.Error != nil {
+			return response.Error
+		}
+	}
+	writer, err := NewExportWriter(request.Format, w)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	window := constants.MaxNumberOfRecordsPerApiCall * queryModel.CollectInterval
+	to := time.UnixMilli(request.To).Unix()
+	for from := time.UnixMilli(request.From).Unix(); from <= to; from += window {
+		windowTo := from + window - 1
+		if windowTo > to {
+			windowTo = to
+		}
+		frames, err := getExportWindow(queryModel, from, windowTo, santabaClient, pluginContext)
+		if err != nil {
+			return err
+		}
+		for _, frame := range frames {
+			if err := writeExportRows(frame, queryModel.HostSelected.Label, writer); err != nil {
+				return err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+// Samples are exported as LM sent them, without gap filling or downsampling
+func exportQueryModel(queryModel models.QueryModel) models.QueryModel {
+	queryModel.NullPolicy = constants.NullAsNull
+	queryModel.GapIntervals = 0
+	queryModel.TimeShift = ""
+	queryModel.CompareMode = ""
+	queryModel.CacheStrategy = constants.WindowCacheStrategy
+	if queryModel.CollectInterval <= 0 {
+		queryModel.CollectInterval = 60
+	}
+	return queryModel
+}
+
+func getExportWindow(queryModel models.QueryModel, from int64, to int64, santabaClient httpclient.SantabaClient,
+	pluginContext backend.PluginContext) (data.Frames, error) {
+	query := backend.DataQuery{
+		RefID:     constants.ExportStr,
+		TimeRange: backend.TimeRange{From: time.Unix(from, 0), To: time.Unix(to, 0)},
+	}
+	metaData := buildMetaData(&queryModel, &query, santabaClient)
+	// windows are read once, they are kept in cache only for as long as the window is being processed
+	metaData.Id = constants.ExportStr + metaData.QueryId + strconv.FormatInt(from, 10) + strconv.FormatInt(to, 10)
+	metaData.CacheTTLInSeconds = constants.ExportCacheTTLInSeconds
+	for retry := 0; ; retry++ {
+		metaData.Diagnostics = &models.QueryDiagnostics{}
+		response := getFrames(query, queryModel, metaData, santabaClient, pluginContext)
+		if response.Error == nil || response.Error.Error() == constants.InstancesNotMatchingWithHosts ||
+			response.Error.Error() == constants.NoDataFromLM {
+			return response.Frames, nil
+		}
+		if !isThrottled(response.Error) || retry >= constants.MaxExportThrottleRetries {
+			return nil, response.Error
+		}
+		santabaClient.Logger.Info("Export throttled, waiting before next window", response.Error)
+		if err := waitForExportThrottle(santabaClient); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Throttled by api call throttler of the plugin, or by rate limit of LM
+func isThrottled(err error) bool {
+	var pending int
+	if n, _ := fmt.Sscanf(err.Error(), constants.RateLimitExceeding, &pending); n == 1 {
+		return true
+	}
+	return errors.Is(err, httpclient.ErrRateLimit)
+}
+
+func waitForExportThrottle(santabaClient httpclient.SantabaClient) error {
+	timer := time.NewTimer(constants.ExportThrottleWaitSeconds * time.Second)
+	defer timer.Stop()
+	if santabaClient.Ctx == nil {
+		<-timer.C
+		return nil
+	}
+	select {
+	case <-timer.C:
+		return nil
+	case <-santabaClient.Ctx.Done():
+		return santabaClient.Ctx.Err() //nolint:wrapcheck
+	}
+}
+
+// Value fields of frame are named instance ~ datapoint, each field value is a row of export
+func writeExportRows(frame *data.Frame, host string, writer ExportWriter) error {
+	for fieldIdx := 1; fieldIdx < len(frame.Fields); fieldIdx++ {
+		field := frame.Fields[fieldIdx]
+		instance, dataPoint := frame.RefID, field.Name
+		if idx := strings.LastIndex(field.Name, constants.InstantAndDpDelim); idx >= 0 {
+			dataPoint = field.Name[idx+len(constants.InstantAndDpDelim):]
+		}
+		for row := 0; row < field.Len(); row++ {
+			exportRow := models.ExportRow{
+				Time:      frame.Fields[0].At(row).(time.Time),
+				Host:      host,
+				Instance:  instance,
+				DataPoint: dataPoint,
+			}
+			if v, ok := floatAt(field, row); ok {
+				exportRow.Value = &v
+			}
+			if err := writer.Write(exportRow); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
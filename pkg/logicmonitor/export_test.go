@@ -0,0 +1,29 @@
+package logicmonitor
+
+// Unexported functions used by tests of logicmonitor_test package
+var (
+	ReduceField = reduceField
+	RankAsTable = rankAsTable
+	RankSeries  = rankSeries
+
+	ParseTimeShift = parseTimeShift
+	ShiftFrame     = shiftFrame
+	CompareFrames  = compareFrames
+
+	ProcessFinalData = processFinalData
+	ToFloat          = toFloat
+	SortFrame        = sortFrame
+	ApplyNullPolicy  = applyNullPolicy
+
+	GetFieldConfig = getFieldConfig
+	GetThresholds  = getThresholds
+
+	TranslateExpression = translateExpression
+
+	GetAnnotationKey = getAnnotationKey
+
+	GetBucketSize      = getBucketSize
+	BucketFrame        = bucketFrame
+	LttbFrame          = lttbFrame
+	DownsampleResponse = downsampleResponse
+)
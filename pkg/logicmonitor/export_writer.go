@@ -0,0 +1,153 @@
+package logicmonitor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+/*
+ExportWriter writes exported rows in one of export formats. Flush writes rows buffered so far to the underlying writer, it is
+called after each export window. Close must be called to flush the rows written
+*/
+type ExportWriter interface {
+	Write(row models.ExportRow) error
+	Flush() error
+	Close() error
+}
+
+var exportHeader = []string{constants.TimeStr, constants.HostStr, constants.InstanceStr, constants.DataPointStr, constants.ValueStr} //nolint:gochecknoglobals,lll
+
+var exportFormats = map[string]bool{ //nolint:gochecknoglobals
+	constants.ExportCSV:     true,
+	constants.ExportNDJSON:  true,
+	constants.ExportParquet: true,
+}
+
+// NewExportWriter returns writer of format writing to w
+func NewExportWriter(format string, w io.Writer) (ExportWriter, error) {
+	switch format {
+	case constants.ExportCSV:
+		return &csvExportWriter{writer: csv.NewWriter(w)}, nil
+	case constants.ExportNDJSON:
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}, nil
+	case constants.ExportParquet:
+		parquetWriter, err := writer.NewParquetWriterFromWriter(w, new(parquetExportRow), 1)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		parquetWriter.CompressionType = parquet.CompressionCodec_SNAPPY
+		return &parquetExportWriter{writer: parquetWriter}, nil
+	default:
+		return nil, fmt.Errorf("%w: "+constants.InvalidExportFormat, ErrInvalidExport, format)
+	}
+}
+
+// Times are written in RFC 3339 with milliseconds in UTC, missing values are empty
+type csvExportWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (c *csvExportWriter) Write(row models.ExportRow) error {
+	if !c.headerWritten {
+		c.headerWritten = true
+		if err := c.writer.Write(exportHeader); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+	value := ""
+	if row.Value != nil {
+		value = strconv.FormatFloat(*row.Value, 'g', -1, 64)
+	}
+	return c.writer.Write([]string{formatExportTime(row.Time), row.Host, row.Instance, row.DataPoint, value}) //nolint:wrapcheck
+}
+
+func (c *csvExportWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error() //nolint:wrapcheck
+}
+
+func (c *csvExportWriter) Close() error {
+	if !c.headerWritten {
+		c.headerWritten = true
+		_ = c.writer.Write(exportHeader)
+	}
+	c.writer.Flush()
+	return c.writer.Error() //nolint:wrapcheck
+}
+
+// One json object per line, missing values are null
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+type ndjsonExportRow struct {
+	Time      string   `json:"time"`
+	Host      string   `json:"host"`
+	Instance  string   `json:"instance"`
+	DataPoint string   `json:"datapoint"`
+	Value     *float64 `json:"value"`
+}
+
+func (n *ndjsonExportWriter) Write(row models.ExportRow) error {
+	return n.encoder.Encode(ndjsonExportRow{ //nolint:wrapcheck
+		Time:      formatExportTime(row.Time),
+		Host:      row.Host,
+		Instance:  row.Instance,
+		DataPoint: row.DataPoint,
+		Value:     row.Value,
+	})
+}
+
+// rows are encoded straight to the underlying writer, nothing is buffered
+func (n *ndjsonExportWriter) Flush() error {
+	return nil
+}
+
+func (n *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// Parquet file has a row group per export window, written on Flush, columns are as in csv with time in epoch milliseconds
+type parquetExportWriter struct {
+	writer *writer.ParquetWriter
+}
+
+type parquetExportRow struct {
+	Time      int64    `parquet:"name=time, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Host      string   `parquet:"name=host, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Instance  string   `parquet:"name=instance, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	DataPoint string   `parquet:"name=datapoint, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Value     *float64 `parquet:"name=value, type=DOUBLE, repetitiontype=OPTIONAL"`
+}
+
+func (p *parquetExportWriter) Write(row models.ExportRow) error {
+	return p.writer.Write(parquetExportRow{ //nolint:wrapcheck
+		Time:      row.Time.UnixMilli(),
+		Host:      row.Host,
+		Instance:  row.Instance,
+		DataPoint: row.DataPoint,
+		Value:     row.Value,
+	})
+}
+
+func (p *parquetExportWriter) Flush() error {
+	return p.writer.Flush(true) //nolint:wrapcheck
+}
+
+func (p *parquetExportWriter) Close() error {
+	return p.writer.WriteStop() //nolint:wrapcheck
+}
+
+func formatExportTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
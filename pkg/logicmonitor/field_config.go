@@ -0,0 +1,148 @@
+package logicmonitor
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var alertExprRegex = regexp.MustCompile(`^\s*(>=|<=|>|<)\s*(.*)$`) //nolint:gochecknoglobals
+
+// Colors of warning, error and critical alert levels
+var thresholdColors = []string{"yellow", "orange", "red"} //nolint:gochecknoglobals
+
+// Units are inferred from datapoint name and description as LM has no unit on datapoint definition. First match wins
+var unitRules = []struct { //nolint:gochecknoglobals
+	keywords []string
+	unit     string
+	rateUnit string
+}{
+	{keywords: []string{"percent", "pct", "%"}, unit: "percent", rateUnit: "percent"},
+	{keywords: []string{"octets", "bytes"}, unit: "bytes", rateUnit: "Bps"},
+	{keywords: []string{"bits"}, unit: "bits", rateUnit: "bps"},
+	{keywords: []string{"milliseconds", "latency", "responsetime"}, unit: "ms", rateUnit: "ms"},
+	{keywords: []string{"seconds", "uptime"}, unit: "s", rateUnit: "s"},
+}
+
+/*
+Sets unit, description and thresholds from datapoint definitions on every value field of the response.
+Value fields are named instance ~ datapoint, first field being time. Definitions are fetched once per
+datasource and cached, query is not failed when definitions are not available
+*/
+func setFieldConfig(response backend.DataResponse, queryModel models.QueryModel, santabaClient httpclient.SantabaClient) backend.DataResponse {
+	if len(response.Frames) == 0 {
+		return response
+	}
+	definitions, err := cache.GetDataPointDefinitions(santabaClient, queryModel)
+	if err != nil {
+		santabaClient.Logger.Warn(constants.DataPointDefinitionErrMsg, err)
+		return response
+	}
+	for _, frame := range response.Frames {
+		if len(frame.Fields) < 2 {
+			continue
+		}
+		for _, field := range frame.Fields[1:] {
+			for _, dp := range queryModel.DataPointSelected {
+				if definition, ok := definitions[dp.Label]; ok && strings.HasSuffix(field.Name, constants.InstantAndDpDelim+dp.Label) {
+					field.SetConfig(getFieldConfig(definition))
+					break
+				}
+			}
+		}
+	}
+	return response
+}
+
+func getFieldConfig(definition models.DataPointDefinition) *data.FieldConfig {
+	config := &data.FieldConfig{
+		Description: definition.Description,
+		Unit:        getUnit(definition),
+	}
+	if isRate(definition) {
+		decimals := uint16(2)
+		config.Decimals = &decimals
+	}
+	if thresholds := getThresholds(definition.AlertExpr); thresholds != nil {
+		config.Thresholds = thresholds
+		config.Custom = map[string]interface{}{"thresholdsStyle": map[string]interface{}{"mode": "line"}}
+	}
+	return config
+}
+
+// counter and derive datapoints are reported by LM as rate per second
+func isRate(definition models.DataPointDefinition) bool {
+	return definition.Type == constants.CounterDataPoint || definition.Type == constants.DeriveDataPoint
+}
+
+func getUnit(definition models.DataPointDefinition) string {
+	text := strings.ToLower(definition.Name + " " + definition.Description)
+	for _, rule := range unitRules {
+		for _, keyword := range rule.keywords {
+			if strings.Contains(text, keyword) {
+				if isRate(definition) {
+					return rule.rateUnit
+				}
+				return rule.unit
+			}
+		}
+	}
+	return ""
+}
+
+/*
+Converts LM alert expression like "> 60 80 90" (warning, error and critical) to grafana threshold steps.
+For less than expressions, lower values are more severe, so steps are colored from red at the bottom to green at the top.
+Equality expressions cannot be shown as steps and are ignored
+*/
+func getThresholds(alertExpr string) *data.ThresholdsConfig {
+	match := alertExprRegex.FindStringSubmatch(alertExpr)
+	if match == nil {
+		return nil
+	}
+	type level struct {
+		value float64
+		color string
+	}
+	var levels []level
+	for i, v := range strings.Fields(match[2]) {
+		if i >= len(thresholdColors) {
+			break
+		}
+		value, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil
+		}
+		levels = append(levels, level{value: value, color: thresholdColors[i]})
+	}
+	if len(levels) == 0 {
+		return nil
+	}
+	sort.SliceStable(levels, func(i, j int) bool { return levels[i].value < levels[j].value })
+	steps := make([]data.Threshold, 0, len(levels)+1)
+	if strings.HasPrefix(match[1], ">") {
+		steps = append(steps, data.NewThreshold(math.Inf(-1), "green", ""))
+		for _, l := range levels {
+			steps = append(steps, data.NewThreshold(l.value, l.color, ""))
+		}
+	} else {
+		steps = append(steps, data.NewThreshold(math.Inf(-1), levels[0].color, ""))
+		for i, l := range levels {
+			color := "green"
+			if i+1 < len(levels) {
+				color = levels[i+1].color
+			}
+			steps = append(steps, data.NewThreshold(l.value, color, ""))
+		}
+	}
+	return &data.ThresholdsConfig{Mode: data.ThresholdsModeAbsolute, Steps: steps}
+}
@@ -0,0 +1,95 @@
+package logicmonitor_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+func TestFieldConfigUnit(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		dpType      int
+		unit        string
+		decimals    bool
+	}{
+		{name: "CPUBusyPercent", dpType: constants.GaugeDataPoint, unit: "percent"},
+		{name: "MemUsedPct", dpType: constants.GaugeDataPoint, unit: "percent"},
+		{name: "Used", description: "Used space in %", dpType: constants.GaugeDataPoint, unit: "percent"},
+		{name: "InOctets", dpType: constants.CounterDataPoint, unit: "Bps", decimals: true},
+		{name: "FreeBytes", dpType: constants.GaugeDataPoint, unit: "bytes"},
+		{name: "InBits", dpType: constants.DeriveDataPoint, unit: "bps", decimals: true},
+		{name: "OutBits", dpType: constants.GaugeDataPoint, unit: "bits"},
+		{name: "ResponseTime", dpType: constants.GaugeDataPoint, unit: "ms"},
+		{name: "PingLatency", dpType: constants.GaugeDataPoint, unit: "ms"},
+		{name: "QueryMilliseconds", dpType: constants.GaugeDataPoint, unit: "ms"},
+		{name: "UpTime", dpType: constants.GaugeDataPoint, unit: "s"},
+		{name: "WaitSeconds", dpType: constants.GaugeDataPoint, unit: "s"},
+		{name: "Requests", dpType: constants.CounterDataPoint, decimals: true},
+		{name: "Status", dpType: constants.GaugeDataPoint},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			definition := models.DataPointDefinition{Name: test.name, Description: test.description, Type: test.dpType}
+			config := logicmonitor.GetFieldConfig(definition)
+			if config.Unit != test.unit {
+				t.Errorf("unit = %q, want %q", config.Unit, test.unit)
+			}
+			if test.decimals && (config.Decimals == nil || *config.Decimals != 2) {
+				t.Errorf("decimals = %v, want 2 for rate", config.Decimals)
+			} else if !test.decimals && config.Decimals != nil {
+				t.Errorf("decimals = %d, want unset", *config.Decimals)
+			}
+			if config.Description != test.description {
+				t.Errorf("description = %q, want %q", config.Description, test.description)
+			}
+		})
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	tests := []struct {
+		alertExpr string
+		want      string
+	}{
+		{alertExpr: "> 60 80 90", want: "-Inf:green 60:yellow 80:orange 90:red"},
+		{alertExpr: ">= 90", want: "-Inf:green 90:yellow"},
+		{alertExpr: "  >90 80", want: "-Inf:green 80:orange 90:yellow"},
+		{alertExpr: "< 10 5 1", want: "-Inf:red 1:orange 5:yellow 10:green"},
+		{alertExpr: "<= 20", want: "-Inf:yellow 20:green"},
+		{alertExpr: "> 1 2 3 4", want: "-Inf:green 1:yellow 2:orange 3:red"},
+		{alertExpr: "= 1"},
+		{alertExpr: "!= 0"},
+		{alertExpr: "> high"},
+		{alertExpr: ">"},
+		{alertExpr: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.alertExpr, func(t *testing.T) {
+			thresholds := logicmonitor.GetThresholds(test.alertExpr)
+			var steps []string
+			if thresholds != nil {
+				for _, step := range thresholds.Steps {
+					steps = append(steps, fmt.Sprintf("%v:%s", float64(step.Value), step.Color))
+				}
+			}
+			if got := strings.Join(steps, " "); got != test.want {
+				t.Errorf("steps = %s, want %s", got, test.want)
+			}
+		})
+	}
+
+	config := logicmonitor.GetFieldConfig(models.DataPointDefinition{Name: "CPUBusyPercent", AlertExpr: "> 90"})
+	if config.Thresholds == nil || config.Custom["thresholdsStyle"] == nil {
+		t.Errorf("thresholds of alert expression are not shown as lines: %+v", config)
+	}
+	if !math.IsInf(float64(config.Thresholds.Steps[0].Value), -1) {
+		t.Errorf("base step = %v, want -Inf", config.Thresholds.Steps[0].Value)
+	}
+}
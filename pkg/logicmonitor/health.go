@@ -0,0 +1,150 @@
+package logicmonitor
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+var tlsVersions = map[uint16]string{ //nolint:gochecknoglobals
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+/*
+CheckHealth calls the portal and reports reachability and latency, auth mode, permissions of the key, rate limit headroom,
+clock skew against portal and TLS/proxy details. Reading devices is required, reading alerts and logs only warns when denied
+*/
+func CheckHealth(santabaClient httpclient.SantabaClient) models.HealthReport {
+	report := models.HealthReport{AuthMode: getAuthMode(santabaClient.PluginSettings)}
+	report.Checks = append(report.Checks, models.HealthCheckItem{Name: "auth", Status: constants.HealthPass, Message: report.AuthMode})
+
+	start := time.Now()
+	respByte, resp, err := santabaClient.GetWithResponse(constants.HealthCheckURL, constants.HealthCheckReq)
+	end := time.Now()
+	report.LatencyMs = end.Sub(start).Milliseconds()
+	if err == nil {
+		err = httpclient.UnmarshalResponse(respByte, &models.Devices{})
+	}
+	report.Checks = append(report.Checks, getReachabilityCheck(resp, err, report.LatencyMs))
+	report.Checks = append(report.Checks, getPermissionCheck("read devices", err, constants.HealthFail))
+	if resp == nil {
+		return report
+	}
+	report.Checks = append(report.Checks,
+		getPermissionCheck("read alerts", getHealthResource(santabaClient, constants.HealthAlertsURL), constants.HealthWarn),
+		getPermissionCheck("read logs", getHealthResource(santabaClient, constants.HealthLogsURL), constants.HealthWarn),
+		getRateLimitCheck(resp, &report),
+		getClockSkewCheck(resp, start, end, &report),
+		getTLSCheck(resp, santabaClient, &report),
+	)
+	return report
+}
+
+func getAuthMode(pluginSettings *models.PluginSettings) string {
+	if pluginSettings.IsBearerEnabled {
+		return "Bearer"
+	}
+	return constants.LMv1
+}
+
+func getHealthResource(santabaClient httpclient.SantabaClient, requestURL string) error {
+	respByte, err := santabaClient.Get(requestURL, constants.HealthCheckReq)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+	return httpclient.UnmarshalResponse(respByte, &struct{}{})
+}
+
+// Any http response means portal is reachable, even if request was denied
+func getReachabilityCheck(resp *http.Response, err error, latencyMs int64) models.HealthCheckItem {
+	check := models.HealthCheckItem{Name: "reachability", Status: constants.HealthPass, Message: fmt.Sprintf("%d ms", latencyMs)}
+	if resp == nil {
+		check.Status, check.Message = constants.HealthFail, err.Error()
+	} else if latencyMs > constants.HealthSlowLatencyMs {
+		check.Status, check.Message = constants.HealthWarn, fmt.Sprintf("slow response, %d ms", latencyMs)
+	}
+	return check
+}
+
+func getPermissionCheck(name string, err error, statusOnError string) models.HealthCheckItem {
+	if err != nil {
+		return models.HealthCheckItem{Name: name, Status: statusOnError, Message: err.Error()}
+	}
+	return models.HealthCheckItem{Name: name, Status: constants.HealthPass, Message: "allowed"}
+}
+
+func getRateLimitCheck(resp *http.Response, report *models.HealthReport) models.HealthCheckItem {
+	check := models.HealthCheckItem{Name: "rate limit", Status: constants.HealthWarn, Message: "not reported by portal"}
+	limit, err := strconv.ParseInt(resp.Header.Get(constants.RateLimitLimitHeader), 10, 64)
+	if err != nil || limit <= 0 {
+		return check
+	}
+	remaining, err := strconv.ParseInt(resp.Header.Get(constants.RateLimitRemainingHeader), 10, 64)
+	if err != nil {
+		return check
+	}
+	report.RateLimitLimit, report.RateLimitRemaining = limit, remaining
+	check.Message = fmt.Sprintf("%d of %d remaining", remaining, limit)
+	check.Status = constants.HealthPass
+	if remaining*100 < limit*constants.HealthRateLimitWarnPercent {
+		check.Status = constants.HealthWarn
+	}
+	return check
+}
+
+// Portal time is taken at the middle of the request, Date header has second precision
+func getClockSkewCheck(resp *http.Response, start time.Time, end time.Time, report *models.HealthReport) models.HealthCheckItem {
+	check := models.HealthCheckItem{Name: "clock skew", Status: constants.HealthWarn, Message: "portal did not send Date header"}
+	portalTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return check
+	}
+	localTime := start.Add(end.Sub(start) / 2)
+	report.ClockSkewMs = localTime.Sub(portalTime).Milliseconds()
+	skew := report.ClockSkewMs
+	if skew < 0 {
+		skew = -skew
+	}
+	check.Message = fmt.Sprintf("%d ms", report.ClockSkewMs)
+	switch {
+	case skew > constants.HealthClockSkewFailMs:
+		check.Status = constants.HealthFail
+	case skew > constants.HealthClockSkewWarnMs:
+		check.Status = constants.HealthWarn
+	default:
+		check.Status = constants.HealthPass
+	}
+	return check
+}
+
+func getTLSCheck(resp *http.Response, santabaClient httpclient.SantabaClient, report *models.HealthReport) models.HealthCheckItem {
+	check := models.HealthCheckItem{Name: "tls", Status: constants.HealthPass}
+	report.Proxy = "direct"
+	if transport, ok := santabaClient.Client.Transport.(*http.Transport); ok {
+		if transport.Proxy != nil && resp.Request != nil {
+			if proxyURL, err := transport.Proxy(resp.Request); err == nil && proxyURL != nil {
+				report.Proxy = proxyURL.Redacted()
+			}
+		}
+		if transport.TLSClientConfig != nil && transport.TLSClientConfig.InsecureSkipVerify {
+			check.Status = constants.HealthWarn
+			check.Message = "certificate verification is skipped. "
+		}
+	}
+	if resp.TLS == nil {
+		check.Status, check.Message = constants.HealthWarn, check.Message+"connection is not encrypted"
+		return check
+	}
+	report.TLSVersion = tlsVersions[resp.TLS.Version]
+	check.Message += fmt.Sprintf("%s, proxy %s", report.TLSVersion, report.Proxy)
+	return check
+}
@@ -1,7 +1,6 @@
 package logicmonitor
 
 import (
-	"encoding/json"
 	httpclient "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
 	"strconv"
 	"time"
@@ -9,49 +8,57 @@ import (
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
 	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/tracing"
 	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func Query(santabaClient httpclient.SantabaClient,
-	pluginContext backend.PluginContext, query backend.DataQuery) backend.DataResponse {
-	response := backend.DataResponse{} //nolint:exhaustivestruct
-
-	// Unmarshal the JSON into our queryModel.
-	var queryModel models.QueryModel
-	var metaData models.MetaData
+	pluginContext backend.PluginContext, query backend.DataQuery) (response backend.DataResponse) {
+	response = backend.DataResponse{} //nolint:exhaustivestruct
+	if santabaClient.Logger == nil {
+		santabaClient.Logger = log.DefaultLogger
+	}
+	var span trace.Span
+	santabaClient.Ctx, span = tracing.Start(santabaClient.Ctx, "Query",
+		attribute.String("query.ref_id", query.RefID),
+		attribute.String("query.time_from", query.TimeRange.From.UTC().Format(time.RFC3339)),
+		attribute.String("query.time_to", query.TimeRange.To.UTC().Format(time.RFC3339)),
+	)
+	defer func() { tracing.End(span, response.Error) }()
 
-	response.Error = json.Unmarshal(query.JSON, &queryModel)
+	// Unmarshal the JSON into our queryModel, queries saved by older versions are migrated to current schema
+	queryModel, err := models.UnmarshalQueryModel(query.JSON)
+	response.Error = err
+	span.SetAttributes(attribute.String("lm.query_mode", queryModel.QueryMode),
+		attribute.String("lm.host", queryModel.HostSelected.Label),
+		attribute.String("lm.datasource", queryModel.DataSourceSelected.Label))
+	if response.Error == nil && queryModel.QueryMode == constants.AnnotationQueryMode {
+		return GetAnnotations(query, queryModel, santabaClient, pluginContext)
+	}
 	if response.Error != nil || queryModel.DataPointSelected == nil {
 		santabaClient.Logger.Error(constants.ErrorUnmarshallingErrorData+"queryModel =>", response.Error)
 		return response
 	}
 	santabaClient.Logger.Debug("queryModel => ", queryModel)
 	// interpolatedQuery, when variable is added on dashboard, one variable on dashboard is hadled here. its considered to be host
-	if queryModel.EnableHostVariableFeature {
-		santabaClient.Logger.Debug("queryModel.interpolatedQuery? => ", queryModel.IsQueryInterpolated)
-		if queryModel.IsQueryInterpolated {
-			queryModel, response = cache.InterpolateHostDataSourceDetails(santabaClient, queryModel, response)
-		}
+	santabaClient.Logger.Debug("queryModel.interpolatedQuery? => ", queryModel.IsQueryInterpolated)
+	if queryModel.IsQueryInterpolated {
+		queryModel, response = cache.InterpolateHostDataSourceDetails(santabaClient, queryModel, response)
 	}
 
-	metaData.EditMode = checkIfCallFromQueryEditor(&queryModel)
-	metaData.Id, metaData.IsForLastXTime = getUniqueID(&queryModel, &query, santabaClient.PluginSettings, metaData)
-	metaData.QueryId = getQueryId(&queryModel, &query, santabaClient.PluginSettings)
-	if queryModel.MaxNumberOfApiCallPerQuery != 1 {
-		if queryModel.EnableStrategicApiCallFeature {
-			metaData.CacheTTLInSeconds = query.TimeRange.To.Unix() - query.TimeRange.From.Unix()
-		} else {
-			metaData.CacheTTLInSeconds = 120
-		}
-	} else {
-		metaData.CacheTTLInSeconds = 60
+	if queryModel.PropertyFilter != "" {
+		return GetDataForPropertyFilter(query, queryModel, santabaClient, pluginContext)
 	}
-	santabaClient.Logger.Debug("metaData.CacheTTLInSeconds = ", metaData.CacheTTLInSeconds)
-	metaData.InstanceSelectedMap = make(map[string]int)
-	for i, v := range queryModel.InstanceSelected {
-		metaData.InstanceSelectedMap[v.Label] = i
+	if queryModel.QueryMode == constants.RankingQueryMode && utils.IsGlob(queryModel.HostSelected.Label) {
+		return GetDataForGroup(query, queryModel, santabaClient, pluginContext)
 	}
+
+	metaData := buildMetaData(&queryModel, &query, santabaClient)
+	metaData.Diagnostics = &models.QueryDiagnostics{}
 	santabaClient.Logger.Debug("metaData ==> ", metaData)
 	return GetData(query, queryModel, metaData, santabaClient, pluginContext)
 	// go GetData(query, queryModel, metaData, authSettings, pluginSettings, pluginContext, logger)
@@ -68,39 +75,59 @@ func Query(santabaClient httpclient.SantabaClient,
 	// }
 }
 
-func getUniqueID(queryModel *models.QueryModel, query *backend.DataQuery, pluginSettings *models.PluginSettings, metaData models.MetaData) (string, bool) { //nolint:lll
-	if !queryModel.EnableStrategicApiCallFeature {
+// Builds cache identity and ttl of a query for the host selected in query model
+func buildMetaData(queryModel *models.QueryModel, query *backend.DataQuery, santabaClient httpclient.SantabaClient) models.MetaData {
+	var metaData models.MetaData
+	metaData.EditMode = checkIfCallFromQueryEditor(queryModel)
+	metaData.Id, metaData.IsForLastXTime = getUniqueID(queryModel, query, santabaClient, metaData)
+	metaData.QueryId = QueryId(queryModel, santabaClient)
+	metaData.DataSourceUID = santabaClient.DataSourceUID
+	metaData.Host = queryModel.HostSelected.Label
+	if queryModel.MaxNumberOfApiCallPerQuery != 1 {
+		if queryModel.IncrementalCache() {
+			metaData.CacheTTLInSeconds = query.TimeRange.To.Unix() - query.TimeRange.From.Unix()
+		} else {
+			metaData.CacheTTLInSeconds = 120
+		}
+	} else {
+		metaData.CacheTTLInSeconds = 60
+	}
+	santabaClient.Logger.Debug("metaData.CacheTTLInSeconds = ", metaData.CacheTTLInSeconds)
+	metaData.InstanceSelectedMap = make(map[string]int)
+	for i, v := range queryModel.InstanceSelected {
+		metaData.InstanceSelectedMap[v.Label] = i
+	}
+	return metaData
+}
+
+func getUniqueID(queryModel *models.QueryModel, query *backend.DataQuery, santabaClient httpclient.SantabaClient, metaData models.MetaData) (string, bool) { //nolint:lll
+	if !queryModel.IncrementalCache() {
 		//backword compatible
-		return getIDForOneMinute(queryModel, query, pluginSettings, metaData)
+		return getIDForOneMinute(queryModel, query, santabaClient, metaData)
 	}
 	if utils.UnixTruncateToNearestMinute(query.TimeRange.To.Unix(), 60) > (time.Now().Unix() - constants.LastXMunitesCheckForFrameIdCalculationInSec) { // LastXTime, return true in this case
 		if metaData.EditMode {
-			return getQueryId(queryModel, query, pluginSettings), true
+			return QueryId(queryModel, santabaClient), true
 		} else {
-			return getQueryId(queryModel, query, pluginSettings) + strconv.FormatInt(queryModel.LastQueryEditedTimeStamp, 10), true
+			return QueryId(queryModel, santabaClient) + strconv.FormatInt(queryModel.LastQueryEditedTimeStamp, 10), true
 		}
 	} else { // FixedTimeRange, returns false for the same
 		if metaData.EditMode {
-			return getQueryId(queryModel, query, pluginSettings), false
+			return QueryId(queryModel, santabaClient), false
 		} else {
-			return getQueryId(queryModel, query, pluginSettings) + strconv.FormatInt(queryModel.LastQueryEditedTimeStamp, 10), false
+			return QueryId(queryModel, santabaClient) + strconv.FormatInt(queryModel.LastQueryEditedTimeStamp, 10), false
 		}
 	}
 }
 
-func getQueryId(queryModel *models.QueryModel, query *backend.DataQuery, pluginSettings *models.PluginSettings) string {
-	return pluginSettings.Path + queryModel.TypeSelected + queryModel.GroupSelected.Label +
-		queryModel.HostSelected.Label + queryModel.DataSourceSelected.Label
-}
-
-func getIDForOneMinute(queryModel *models.QueryModel, query *backend.DataQuery, pluginSettings *models.PluginSettings, metaData models.MetaData) (string, bool) { //nolint:lll
+func getIDForOneMinute(queryModel *models.QueryModel, query *backend.DataQuery, santabaClient httpclient.SantabaClient, metaData models.MetaData) (string, bool) { //nolint:lll
 	FromTimeUnixTruncated := utils.UnixTruncateToNearestMinute(query.TimeRange.From.Unix(), 60)
 	ToTimeUnixTruncated := utils.UnixTruncateToNearestMinute(query.TimeRange.To.Unix(), 60)
 	if metaData.EditMode {
-		return getQueryId(queryModel, query, pluginSettings) +
+		return QueryId(queryModel, santabaClient) +
 			strconv.FormatInt(FromTimeUnixTruncated, 10) + strconv.FormatInt(ToTimeUnixTruncated, 10), true
 	} else {
-		return getQueryId(queryModel, query, pluginSettings) +
+		return QueryId(queryModel, santabaClient) +
 			strconv.FormatInt(FromTimeUnixTruncated, 10) + strconv.FormatInt(ToTimeUnixTruncated, 10) +
 			strconv.FormatInt(queryModel.LastQueryEditedTimeStamp, 10), false
 	}
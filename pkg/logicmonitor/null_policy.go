@@ -0,0 +1,150 @@
+package logicmonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+/*
+Converts a datapoint value from santaba response to float. ok is false when LM has no data for the sample.
+Numbers may come as json numbers or numeric strings, anything else is an error
+*/
+func toFloat(value interface{}) (float64, bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, false, nil
+	case float64:
+		return v, !math.IsNaN(v), nil
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil, err //nolint:wrapcheck
+	case string:
+		if v == constants.NoData || v == "" {
+			return 0, false, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil, err //nolint:wrapcheck
+	default:
+		return 0, false, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// Value to append in frame row, missing value is nil for nullable fields and NaN otherwise
+func rowValue(value float64, ok bool, nullable bool) interface{} {
+	if nullable {
+		if ok {
+			return &value
+		}
+		return (*float64)(nil)
+	}
+	if ok {
+		return value
+	}
+	return math.NaN()
+}
+
+// Sorts rows of frame by time and drops rows of duplicate time, which come when chunks overlap. Rows of recent chunks are appended
+// first, so the row kept is from the most recent chunk
+func sortFrame(frame *data.Frame) *data.Frame {
+	if len(frame.Fields) < 2 {
+		return frame
+	}
+	rows := make([]int, frame.Rows())
+	for i := range rows {
+		rows[i] = i
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return frame.Fields[0].At(rows[i]).(time.Time).Before(frame.Fields[0].At(rows[j]).(time.Time))
+	})
+	sorted := frame.EmptyCopy()
+	var lastTime time.Time
+	for idx, row := range rows {
+		vals := frame.RowCopy(row)
+		rowTime := vals[0].(time.Time)
+		if idx > 0 && rowTime.Equal(lastTime) {
+			continue
+		}
+		lastTime = rowTime
+		sorted.AppendRow(vals...)
+	}
+	return sorted
+}
+
+/*
+Inserts a row with missing values where samples of frame sorted by time are missing for more than configured number of collect
+intervals and replaces missing values as per null policy of the query.
+  - null/nan : missing values are kept as null/NaN
+  - zero     : missing values are replaced by 0
+  - previous : missing values are replaced by previous value of the same datapoint
+  - drop     : rows having any missing value are removed
+*/
+func applyNullPolicy(frame *data.Frame, queryModel models.QueryModel) *data.Frame {
+	if len(frame.Fields) < 2 {
+		return frame
+	}
+	nullable := frame.Fields[1].Nullable()
+	gap := time.Duration(queryModel.GapIntervals*queryModel.CollectInterval) * time.Second
+	previous := make([]interface{}, len(frame.Fields))
+	processed := frame.EmptyCopy()
+	var lastTime time.Time
+	for row := 0; row < frame.Rows(); row++ {
+		vals := frame.RowCopy(row)
+		rowTime := vals[0].(time.Time)
+		if gap > 0 && row > 0 && rowTime.Sub(lastTime) > gap {
+			gapVals := make([]interface{}, len(vals))
+			gapVals[0] = lastTime.Add(time.Duration(queryModel.CollectInterval) * time.Second)
+			for fieldIdx := 1; fieldIdx < len(vals); fieldIdx++ {
+				gapVals[fieldIdx] = rowValue(0, false, nullable)
+			}
+			processed = appendWithNullPolicy(processed, gapVals, previous, queryModel.NullPolicy, nullable)
+		}
+		lastTime = rowTime
+		processed = appendWithNullPolicy(processed, vals, previous, queryModel.NullPolicy, nullable)
+	}
+	return processed
+}
+
+// Appends row with missing values replaced, previous values are only taken from rows appended
+func appendWithNullPolicy(frame *data.Frame, vals []interface{}, previous []interface{}, nullPolicy string, nullable bool) *data.Frame {
+	if nullPolicy == constants.NullAsDrop {
+		for fieldIdx := 1; fieldIdx < len(vals); fieldIdx++ {
+			if isMissing(vals[fieldIdx]) {
+				return frame
+			}
+		}
+	}
+	for fieldIdx := 1; fieldIdx < len(vals); fieldIdx++ {
+		if !isMissing(vals[fieldIdx]) {
+			previous[fieldIdx] = vals[fieldIdx]
+			continue
+		}
+		switch nullPolicy {
+		case constants.NullAsZero:
+			vals[fieldIdx] = rowValue(0, true, nullable)
+		case constants.NullAsPrevious:
+			if previous[fieldIdx] != nil {
+				vals[fieldIdx] = previous[fieldIdx]
+			}
+		}
+	}
+	frame.AppendRow(vals...)
+	return frame
+}
+
+func isMissing(value interface{}) bool {
+	switch v := value.(type) {
+	case *float64:
+		return v == nil
+	case float64:
+		return math.IsNaN(v)
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package logicmonitor_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestToFloat(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    float64
+		ok      bool
+		invalid bool
+	}{
+		{value: nil},
+		{value: 12.5, want: 12.5, ok: true},
+		{value: math.NaN()},
+		{value: json.Number("42"), want: 42, ok: true},
+		{value: json.Number("4x"), invalid: true},
+		{value: constants.NoData},
+		{value: ""},
+		{value: "0.25", want: 0.25, ok: true},
+		{value: "-1e3", want: -1000, ok: true},
+		{value: "up", invalid: true},
+		{value: true, invalid: true},
+		{value: []interface{}{1.0}, invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T %v", test.value, test.value), func(t *testing.T) {
+			got, ok, err := logicmonitor.ToFloat(test.value)
+			if (err != nil) != test.invalid {
+				t.Fatalf("err = %v, want error %v", err, test.invalid)
+			}
+			if ok != test.ok || ok && got != test.want {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+// policyFrame has idle and busy datapoints, sample i being at offsets[i] seconds from shiftStart
+func policyFrame(offsets []int, idle []*float64, busy []*float64) *data.Frame {
+	frame := seriesFrame("CPU-0", offsets, idle)
+	frame.Fields = append(frame.Fields, data.NewField("CPU-0"+constants.InstantAndDpDelim+"busy", nil, busy))
+	return frame
+}
+
+// rows formats rows as offset=idle/busy
+func rows(frame *data.Frame) string {
+	var formatted []string
+	for row := 0; row < frame.Rows(); row++ {
+		offset := frame.Fields[0].At(row).(time.Time).Sub(shiftStart) / time.Second
+		formatted = append(formatted, fmt.Sprintf("%d=%v/%v", offset, deref(frame.Fields[1].At(row).(*float64)),
+			deref(frame.Fields[2].At(row).(*float64))))
+	}
+	return strings.Join(formatted, " ")
+}
+
+func TestApplyNullPolicy(t *testing.T) {
+	// unsorted as chunks are appended, duplicate of 120 from older chunk comes after the recent one
+	offsets := []int{120, 180, 60, 0, 120}
+	idle := []*float64{float(3), nil, nil, float(1), float(9)}
+	busy := []*float64{float(7), float(8), float(6), nil, float(9)}
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{policy: "", want: "0=1/<nil> 60=<nil>/6 120=3/7 180=<nil>/8"},
+		{policy: constants.NullAsNull, want: "0=1/<nil> 60=<nil>/6 120=3/7 180=<nil>/8"},
+		{policy: constants.NullAsZero, want: "0=1/0 60=0/6 120=3/7 180=0/8"},
+		{policy: constants.NullAsPrevious, want: "0=1/<nil> 60=1/6 120=3/7 180=3/8"},
+		{policy: constants.NullAsDrop, want: "120=3/7"},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			frame := logicmonitor.SortFrame(policyFrame(offsets, idle, busy))
+			frame = logicmonitor.ApplyNullPolicy(frame, models.QueryModel{NullPolicy: test.policy, CollectInterval: 60})
+			if got := rows(frame); got != test.want {
+				t.Errorf("rows = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyNullPolicyOfNonNullableFields(t *testing.T) {
+	times := []time.Time{shiftStart, shiftStart.Add(time.Minute)}
+	frame := data.NewFrame(constants.ResponseStr, data.NewField(constants.TimeStr, nil, times),
+		data.NewField("CPU-0 ~ idle", nil, []float64{2, math.NaN()}))
+	if got := logicmonitor.ApplyNullPolicy(frame, models.QueryModel{NullPolicy: constants.NullAsNaN}); !math.IsNaN(got.Fields[1].At(1).(float64)) {
+		t.Errorf("nan policy replaced missing value with %v", got.Fields[1].At(1))
+	}
+	if got := logicmonitor.ApplyNullPolicy(frame, models.QueryModel{NullPolicy: constants.NullAsZero}); got.Fields[1].At(1).(float64) != 0 {
+		t.Errorf("zero policy replaced missing value with %v", got.Fields[1].At(1))
+	}
+}
+
+func TestGapIntervals(t *testing.T) {
+	offsets := []int{0, 60, 300, 360}
+	values := []*float64{float(1), float(2), float(3), float(4)}
+	tests := []struct {
+		name         string
+		gapIntervals int64
+		policy       string
+		want         string
+	}{
+		{name: "no gap insertion", want: "0=1/1 60=2/2 300=3/3 360=4/4"},
+		{name: "gap wider than intervals", gapIntervals: 2, want: "0=1/1 60=2/2 120=<nil>/<nil> 300=3/3 360=4/4"},
+		{name: "gap within intervals", gapIntervals: 4, want: "0=1/1 60=2/2 300=3/3 360=4/4"},
+		{name: "gap of previous policy", gapIntervals: 2, policy: constants.NullAsPrevious,
+			want: "0=1/1 60=2/2 120=2/2 300=3/3 360=4/4"},
+		{name: "gap of drop policy", gapIntervals: 2, policy: constants.NullAsDrop, want: "0=1/1 60=2/2 300=3/3 360=4/4"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queryModel := models.QueryModel{NullPolicy: test.policy, CollectInterval: 60, GapIntervals: test.gapIntervals}
+			frame := logicmonitor.ApplyNullPolicy(policyFrame(offsets, values, values), queryModel)
+			if got := rows(frame); got != test.want {
+				t.Errorf("rows = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
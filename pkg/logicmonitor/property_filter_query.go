@@ -0,0 +1,118 @@
+package logicmonitor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+/*
+GetDataForPropertyFilter gets data of every device matching property filter of the query. Each device is queried as if it was
+selected in the query, so raw data cache and api call throttler apply per device. Series are labeled with device name and values
+of the properties used in the filter. Devices not having selected datasource or instances are skipped
+*/
+func GetDataForPropertyFilter(query backend.DataQuery, queryModel models.QueryModel, santabaClient httpclient.SantabaClient,
+	pluginContext backend.PluginContext) backend.DataResponse {
+	response := backend.DataResponse{}
+	filter, propertyNames, err := utils.ParsePropertyFilter(queryModel.PropertyFilter)
+	if err != nil {
+		response.Error = err
+		return response
+	}
+	devices, err := cache.ResolvePropertyFilter(santabaClient, queryModel, queryModel.PropertyFilter, filter)
+	if err != nil {
+		response.Error = err
+		return response
+	}
+	if len(devices) == 0 {
+		response.Error = fmt.Errorf(constants.NoDeviceMatchingPropertyFilter, queryModel.PropertyFilter)
+		return response
+	}
+	if len(devices) > constants.MaxDevicesPerPropertyFilter {
+		response.Error = fmt.Errorf(constants.TooManyDevicesForPropertyFilter, len(devices), constants.MaxDevicesPerPropertyFilter)
+		return response
+	}
+	return getDataForDevices(query, queryModel, devices, propertyNames, santabaClient, pluginContext)
+}
+
+/*
+GetDataForGroup gets data of the devices of selected group and its subgroups matching selected host, so that ranking query
+picks its top/bottom series across the group. Group and host selections are globs, * selecting all
+*/
+func GetDataForGroup(query backend.DataQuery, queryModel models.QueryModel, santabaClient httpclient.SantabaClient,
+	pluginContext backend.PluginContext) backend.DataResponse {
+	response := backend.DataResponse{}
+	filter, err := utils.GroupFilter(queryModel.GroupSelected.Label, queryModel.HostSelected.Label)
+	if err != nil {
+		response.Error = err
+		return response
+	}
+	filterKey := constants.SystemGroupsProperty + "|" + queryModel.GroupSelected.Label + "|" + queryModel.HostSelected.Label
+	devices, err := cache.ResolvePropertyFilter(santabaClient, queryModel, filterKey, filter)
+	if err != nil {
+		response.Error = err
+		return response
+	}
+	if len(devices) == 0 {
+		response.Error = fmt.Errorf(constants.NoDeviceInGroup, queryModel.HostSelected.Label, queryModel.GroupSelected.Label)
+		return response
+	}
+	if len(devices) > constants.MaxDevicesPerPropertyFilter {
+		response.Error = fmt.Errorf(constants.TooManyDevicesInGroup, len(devices), constants.MaxDevicesPerPropertyFilter)
+		return response
+	}
+	return getDataForDevices(query, queryModel, devices, nil, santabaClient, pluginContext)
+}
+
+// Queries every device as if it was selected, series are labeled with device name and values of propertyNames
+func getDataForDevices(query backend.DataQuery, queryModel models.QueryModel, devices []models.ResolvedDevice, propertyNames []string,
+	santabaClient httpclient.SantabaClient, pluginContext backend.PluginContext) backend.DataResponse {
+	response := backend.DataResponse{}
+	sort.SliceStable(devices, func(i, j int) bool { return utils.NaturalLess(devices[i].DisplayName, devices[j].DisplayName) })
+	diagnostics := &models.QueryDiagnostics{}
+	var deviceErr error
+	for _, device := range devices {
+		deviceQueryModel := queryModel
+		deviceQueryModel.HostSelected = models.LabelStringValue{Label: device.DisplayName, Value: device.Id}
+		deviceResponse := backend.DataResponse{}
+		deviceQueryModel, deviceResponse = cache.InterpolateHostDataSourceDetails(santabaClient, deviceQueryModel, deviceResponse)
+		if deviceResponse.Error == nil {
+			metaData := buildMetaData(&deviceQueryModel, &query, santabaClient)
+			metaData.Diagnostics = diagnostics
+			deviceResponse = getFramesWithTimeShift(query, deviceQueryModel, metaData, santabaClient, pluginContext)
+		}
+		if deviceResponse.Error != nil {
+			santabaClient.Logger.Warn("Skipping device "+device.DisplayName, deviceResponse.Error)
+			if deviceErr == nil {
+				deviceErr = fmt.Errorf("%s: %w", device.DisplayName, deviceResponse.Error)
+			}
+		}
+		labels := getDeviceLabels(device, propertyNames)
+		for _, frame := range deviceResponse.Frames {
+			for _, field := range frame.Fields[1:] {
+				field.Labels = labels.Copy()
+			}
+		}
+		response.Frames = append(response.Frames, deviceResponse.Frames...)
+	}
+	if len(response.Frames) == 0 {
+		response.Error = deviceErr
+	}
+	response = finalizeFrames(response, query, queryModel, santabaClient)
+	return setDiagnostics(response, diagnostics, pluginContext)
+}
+
+func getDeviceLabels(device models.ResolvedDevice, propertyNames []string) data.Labels {
+	labels := data.Labels{constants.DeviceLabel: device.DisplayName}
+	for _, name := range propertyNames {
+		labels[name] = device.Properties[name]
+	}
+	return labels
+}
@@ -0,0 +1,79 @@
+package logicmonitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+// queryShape holds query fields deciding which raw data is fetched and how it is filtered, selections are sorted so that the
+// order of selection does not change the identity
+type queryShape struct {
+	Type                   string   `json:"type"`
+	Group                  string   `json:"group"`
+	GroupId                int64    `json:"groupId"`
+	Host                   string   `json:"host"`
+	HostId                 string   `json:"hostId"`
+	DataSource             string   `json:"dataSource"`
+	DataSourceId           int64    `json:"dataSourceId"`
+	Hds                    int64    `json:"hds"`
+	DataPoints             []string `json:"dataPoints"`
+	InstanceSelectBy       string   `json:"instanceSelectBy"`
+	Instances              []string `json:"instances"`
+	InstanceRegex          string   `json:"instanceRegex"`
+	ValidInstanceRegex     bool     `json:"validInstanceRegex"`
+	InstanceIncludes       []string `json:"instanceIncludes"`
+	InstanceExcludes       []string `json:"instanceExcludes"`
+	InstancePatternType    string   `json:"instancePatternType"`
+	InstancePropertyFilter string   `json:"instancePropertyFilter"`
+	CollectInterval        int64    `json:"collectInterval"`
+}
+
+/*
+QueryId identifies cached data of a query. It is made of the cache scope of the client, so that datasources and credentials
+never share entries, the host datasource id and a hash of the query shape, so that queries differing in datapoints or instance
+selection do not collide
+*/
+func QueryId(queryModel *models.QueryModel, santabaClient httpclient.SantabaClient) string {
+	shape := queryShape{
+		Type:                   queryModel.TypeSelected,
+		Group:                  queryModel.GroupSelected.Label,
+		GroupId:                queryModel.GroupSelected.Value,
+		Host:                   queryModel.HostSelected.Label,
+		HostId:                 queryModel.HostSelected.Value,
+		DataSource:             queryModel.DataSourceSelected.Label,
+		DataSourceId:           queryModel.DataSourceSelected.Ds,
+		Hds:                    queryModel.HdsSelected,
+		InstanceSelectBy:       queryModel.InstanceSelectBy,
+		InstanceRegex:          queryModel.InstanceRegex,
+		ValidInstanceRegex:     queryModel.ValidInstanceRegex,
+		InstanceIncludes:       sortedCopy(queryModel.InstanceIncludes),
+		InstanceExcludes:       sortedCopy(queryModel.InstanceExcludes),
+		InstancePatternType:    queryModel.InstancePatternType,
+		InstancePropertyFilter: queryModel.InstancePropertyFilter,
+		CollectInterval:        queryModel.CollectInterval,
+	}
+	for _, dataPoint := range queryModel.DataPointSelected {
+		shape.DataPoints = append(shape.DataPoints, dataPoint.Label)
+	}
+	sort.Strings(shape.DataPoints)
+	for _, instance := range queryModel.InstanceSelected {
+		shape.Instances = append(shape.Instances, instance.Label)
+	}
+	sort.Strings(shape.Instances)
+	// marshalling a struct of strings, numbers and string slices can not fail
+	canonical, _ := json.Marshal(shape)
+	hash := sha256.Sum256(canonical)
+	return santabaClient.CacheScope() + "|" + strconv.FormatInt(queryModel.HdsSelected, 10) + "|" + hex.EncodeToString(hash[:])
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,94 @@
+package logicmonitor_test
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+func newClient(uid string, accessID string, accessKey string) httpclient.SantabaClient {
+	return httpclient.SantabaClient{
+		DataSourceUID:  uid,
+		PluginSettings: &models.PluginSettings{Path: "portal", AccessID: accessID, IsLMV1Enabled: true},
+		AuthSettings:   &models.AuthSettings{AccessKey: accessKey},
+	}
+}
+
+func newQueryModel() *models.QueryModel {
+	return &models.QueryModel{
+		TypeSelected:       "host",
+		GroupSelected:      models.LabelIntValue{Label: "Linux", Value: 1},
+		HostSelected:       models.LabelStringValue{Label: "server", Value: "10"},
+		HdsSelected:        100,
+		DataSourceSelected: models.DataSource{Ds: 5, Label: "CPU"},
+		DataPointSelected:  []models.LabelIntValue{{Label: "idle", Value: 1}, {Label: "busy", Value: 2}},
+		InstanceSelectBy:   "Select",
+		InstanceSelected:   []models.LabelStringValue{{Label: "CPU-0", Value: "1"}, {Label: "CPU-1", Value: "2"}},
+	}
+}
+
+func TestQueryIdIsolation(t *testing.T) {
+	client := newClient("uid", "id", "key")
+	want := logicmonitor.QueryId(newQueryModel(), client)
+
+	differing := map[string]func() string{
+		"datasource uid": func() string { return logicmonitor.QueryId(newQueryModel(), newClient("other", "id", "key")) },
+		"access id":      func() string { return logicmonitor.QueryId(newQueryModel(), newClient("uid", "other", "key")) },
+		"access key":     func() string { return logicmonitor.QueryId(newQueryModel(), newClient("uid", "id", "other")) },
+		"hds": func() string {
+			queryModel := newQueryModel()
+			queryModel.HdsSelected = 101
+			return logicmonitor.QueryId(queryModel, client)
+		},
+		"datapoints": func() string {
+			queryModel := newQueryModel()
+			queryModel.DataPointSelected = queryModel.DataPointSelected[:1]
+			return logicmonitor.QueryId(queryModel, client)
+		},
+		"instances": func() string {
+			queryModel := newQueryModel()
+			queryModel.InstanceSelected = queryModel.InstanceSelected[1:]
+			return logicmonitor.QueryId(queryModel, client)
+		},
+		"instance select by": func() string {
+			queryModel := newQueryModel()
+			queryModel.InstanceSelectBy = "Regex"
+			return logicmonitor.QueryId(queryModel, client)
+		},
+	}
+	for name, queryId := range differing {
+		if got := queryId(); got == want {
+			t.Errorf("query id does not change with %s: %s", name, got)
+		}
+	}
+
+	reordered := newQueryModel()
+	reordered.DataPointSelected[0], reordered.DataPointSelected[1] = reordered.DataPointSelected[1], reordered.DataPointSelected[0]
+	reordered.InstanceSelected[0], reordered.InstanceSelected[1] = reordered.InstanceSelected[1], reordered.InstanceSelected[0]
+	reordered.LastQueryEditedTimeStamp = 1
+	if got := logicmonitor.QueryId(reordered, client); got != want {
+		t.Errorf("query id changes with selection order: %s, want %s", got, want)
+	}
+}
+
+func TestRawDataCacheIsolation(t *testing.T) {
+	queryModel := newQueryModel()
+	allowed := newClient("allowed", "id", "key")
+	restricted := newClient("restricted", "scoped-id", "scoped-key")
+	metaData := func(client httpclient.SantabaClient) models.MetaData {
+		queryId := logicmonitor.QueryId(queryModel, client)
+		return models.MetaData{Id: queryId + "60120", QueryId: queryId, DataSourceUID: client.DataSourceUID, CacheTTLInSeconds: 60}
+	}
+
+	cache.StoreData(metaData(allowed), &models.MultiInstanceRawData{Error: "OK"})
+	t.Cleanup(func() { cache.Remove(metaData(allowed)) })
+	if _, ok := cache.GetData(metaData(restricted)); ok {
+		t.Fatal("raw data cached for one datasource is served to another datasource of the portal")
+	}
+	if _, ok := cache.GetData(metaData(allowed)); !ok {
+		t.Fatal("raw data is not served to datasource it was cached for")
+	}
+}
@@ -0,0 +1,135 @@
+package logicmonitor
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+type seriesScore struct {
+	frameIdx int
+	fieldIdx int
+	score    float64
+}
+
+/*
+Ranking works on frames built by the normal data path, so raw data cache and api call throttler apply to ranking queries as well.
+Each value field is a series, it is reduced to a score over the query time range and only top/bottom N series are kept.
+Frames are ordered by the rank of their best series
+*/
+func rankSeries(response backend.DataResponse, queryModel models.QueryModel) backend.DataResponse {
+	scores := getRankedScores(response.Frames, queryModel)
+	keep := make(map[int]map[int]bool)
+	var frameOrder []int
+	for _, s := range scores {
+		if _, ok := keep[s.frameIdx]; !ok {
+			keep[s.frameIdx] = make(map[int]bool)
+			frameOrder = append(frameOrder, s.frameIdx)
+		}
+		keep[s.frameIdx][s.fieldIdx] = true
+	}
+	ranked := make(data.Frames, 0, len(frameOrder))
+	for _, frameIdx := range frameOrder {
+		frame := response.Frames[frameIdx]
+		fields := []*data.Field{frame.Fields[0]}
+		for fieldIdx := 1; fieldIdx < len(frame.Fields); fieldIdx++ {
+			if keep[frameIdx][fieldIdx] {
+				fields = append(fields, frame.Fields[fieldIdx])
+			}
+		}
+		frame.Fields = fields
+		ranked = append(ranked, frame)
+	}
+	response.Frames = ranked
+	return response
+}
+
+// Returns single table frame with device, instance, datapoint and score of top/bottom N series
+func rankAsTable(response backend.DataResponse, queryModel models.QueryModel) backend.DataResponse {
+	scores := getRankedScores(response.Frames, queryModel)
+	table := data.NewFrame(constants.RankingStr,
+		data.NewField(constants.DeviceLabel, nil, []string{}),
+		data.NewField("instance", nil, []string{}),
+		data.NewField("datapoint", nil, []string{}),
+		data.NewField("score", nil, []float64{}),
+	)
+	if len(response.Frames) > 0 {
+		table.RefID = response.Frames[0].RefID
+	}
+	for _, s := range scores {
+		field := response.Frames[s.frameIdx].Fields[s.fieldIdx]
+		name := field.Name
+		device := queryModel.HostSelected.Label
+		if labelled, ok := field.Labels[constants.DeviceLabel]; ok {
+			device = labelled
+		}
+		instance, dataPoint := name, ""
+		if idx := strings.LastIndex(name, constants.InstantAndDpDelim); idx >= 0 {
+			instance, dataPoint = name[:idx], name[idx+len(constants.InstantAndDpDelim):]
+		}
+		table.AppendRow(device, instance, dataPoint, s.score)
+	}
+	response.Frames = data.Frames{table}
+	return response
+}
+
+func getRankedScores(frames data.Frames, queryModel models.QueryModel) []seriesScore {
+	var scores []seriesScore
+	for frameIdx, frame := range frames {
+		for fieldIdx := 1; fieldIdx < len(frame.Fields); fieldIdx++ {
+			score := reduceField(frame.Fields[fieldIdx], queryModel.RankReducer)
+			if !math.IsNaN(score) {
+				scores = append(scores, seriesScore{frameIdx: frameIdx, fieldIdx: fieldIdx, score: score})
+			}
+		}
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if queryModel.RankOrder == constants.RankBottom {
+			return scores[i].score < scores[j].score
+		}
+		return scores[i].score > scores[j].score
+	})
+	if queryModel.RankLimit > 0 && len(scores) > queryModel.RankLimit {
+		scores = scores[:queryModel.RankLimit]
+	}
+	return scores
+}
+
+// Reduces values of field to a single score, missing values are ignored. NaN is returned when field has no value
+func reduceField(field *data.Field, reducer string) float64 {
+	values := make([]float64, 0, field.Len())
+	for row := 0; row < field.Len(); row++ {
+		if v, ok := floatAt(field, row); ok {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	switch reducer {
+	case constants.ReducerMax:
+		sort.Float64s(values)
+		return values[len(values)-1]
+	case constants.ReducerMin:
+		sort.Float64s(values)
+		return values[0]
+	case constants.ReducerLast:
+		// frames are sorted by time when built from raw data
+		return values[len(values)-1]
+	case constants.ReducerP95:
+		sort.Float64s(values)
+		// nearest rank
+		return values[int(math.Ceil(0.95*float64(len(values))))-1]
+	default:
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	}
+}
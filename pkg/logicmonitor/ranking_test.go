@@ -0,0 +1,207 @@
+package logicmonitor_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func valuesField(values ...float64) *data.Field {
+	field := data.NewField("CPU-0 ~ idle", nil, []*float64{})
+	for i := range values {
+		field.Append(&values[i])
+	}
+	field.Append(nil)
+	return field
+}
+
+func sequence(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		// reversed, so that reducers have to sort
+		values[i] = float64(n - i)
+	}
+	return values
+}
+
+func TestReducers(t *testing.T) {
+	tests := []struct {
+		reducer string
+		values  []float64
+		want    float64
+	}{
+		{reducer: constants.ReducerMax, values: []float64{3, 9, 1}, want: 9},
+		{reducer: constants.ReducerMin, values: []float64{3, 9, 1}, want: 1},
+		{reducer: constants.ReducerLast, values: []float64{3, 9, 1}, want: 1},
+		{reducer: "avg", values: []float64{3, 9, 0}, want: 4},
+		// nearest rank is ceil(0.95 * n)
+		{reducer: constants.ReducerP95, values: sequence(1), want: 1},
+		{reducer: constants.ReducerP95, values: sequence(10), want: 10},
+		{reducer: constants.ReducerP95, values: sequence(20), want: 19},
+		{reducer: constants.ReducerP95, values: sequence(21), want: 20},
+		{reducer: constants.ReducerP95, values: sequence(40), want: 38},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s of %d", test.reducer, len(test.values)), func(t *testing.T) {
+			if got := logicmonitor.ReduceField(valuesField(test.values...), test.reducer); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+	if got := logicmonitor.ReduceField(valuesField(), constants.ReducerMax); !math.IsNaN(got) {
+		t.Errorf("field without values reduces to %v, want NaN", got)
+	}
+}
+
+func rankingFrames() data.Frames {
+	frame := func(instance string, values ...float64) *data.Frame {
+		field := valuesField(values...)
+		field.Name = instance + " ~ idle"
+		times := make([]time.Time, field.Len())
+		return data.NewFrame(constants.ResponseStr, data.NewField(constants.TimeStr, nil, times), field)
+	}
+	return data.Frames{frame("a", 5), frame("b", 7), frame("c", 5), frame("d", 1)}
+}
+
+func rankedInstances(table *data.Frame) []string {
+	var instances []string
+	for row := 0; row < table.Rows(); row++ {
+		instances = append(instances, table.Fields[1].At(row).(string))
+	}
+	return instances
+}
+
+func TestRankingWithTies(t *testing.T) {
+	tests := []struct {
+		order string
+		limit int
+		want  string
+	}{
+		{order: constants.RankTop, limit: 2, want: "b,a"},
+		{order: constants.RankTop, limit: 3, want: "b,a,c"},
+		{order: constants.RankBottom, limit: 2, want: "d,a"},
+		{order: constants.RankBottom, limit: 0, want: "d,a,c,b"},
+	}
+	for _, test := range tests {
+		queryModel := models.QueryModel{RankOrder: test.order, RankLimit: test.limit, RankReducer: constants.ReducerMax}
+		response := logicmonitor.RankAsTable(backend.DataResponse{Frames: rankingFrames()}, queryModel)
+		if got := strings.Join(rankedInstances(response.Frames[0]), ","); got != test.want {
+			t.Errorf("%s %d = %s, want %s, ties kept in order of series", test.order, test.limit, got, test.want)
+		}
+	}
+
+	queryModel := models.QueryModel{RankOrder: constants.RankTop, RankLimit: 2, RankReducer: constants.ReducerMax}
+	response := logicmonitor.RankSeries(backend.DataResponse{Frames: rankingFrames()}, queryModel)
+	if len(response.Frames) != 2 || response.Frames[0].Fields[1].Name != "b ~ idle" || response.Frames[1].Fields[1].Name != "a ~ idle" {
+		t.Errorf("ranked series are not top 2 in rank order: %v", response.Frames)
+	}
+}
+
+func rawData(now time.Time, value float64) string {
+	return fmt.Sprintf(`{"errmsg":"OK","status":200,"data":{"dataSourceName":"CPU","dataPoints":["idle"],
+		"instances":{"CPU-0":{"time":[%d,%d],"values":[[%v],[%v]]}}}}`,
+		now.Add(-5*time.Minute).UnixMilli(), now.Add(-10*time.Minute).UnixMilli(), value, value)
+}
+
+func TestRankingAcrossGroup(t *testing.T) {
+	now := time.Now()
+	device := func(id int, name string, groups string) string {
+		return fmt.Sprintf(`{"id":%d,"displayName":%q,"systemProperties":[{"name":"system.displayname","value":%q},
+			{"name":"system.groups","value":%q}]}`, id, name, name, groups)
+	}
+	stub := (&testutil.SantabaStub{}).
+		Route("fields=id,displayName,systemProperties", http.StatusOK, testutil.Envelope(`{"total":3,"items":[`+
+			device(1, "web-1", "Prod/Web")+","+device(2, "web-2", "Prod/Web/EU,Linux")+","+device(3, "db-1", "Staging")+`]}`)).
+		Route("devices/1/devicedatasources?", http.StatusOK, `{"total":1,"items":[{"id":11}]}`).
+		Route("devices/2/devicedatasources?", http.StatusOK, `{"total":1,"items":[{"id":12}]}`).
+		Route("devices/3/devicedatasources?", http.StatusOK, `{"total":1,"items":[{"id":13}]}`).
+		Route("devices/1/devicedatasources/11/data", http.StatusOK, rawData(now, 40)).
+		Route("devices/2/devicedatasources/12/data", http.StatusOK, rawData(now, 90)).
+		Route("devices/3/devicedatasources/13/data", http.StatusOK, rawData(now, 99))
+	queryModel := map[string]interface{}{
+		"schemaVersion": 1, "queryMode": constants.RankingQueryMode, "rankFormat": constants.RankTableFormat,
+		"rankOrder": constants.RankTop, "rankLimit": 5, "rankReducer": constants.ReducerMax,
+		"groupSelected": map[string]interface{}{"label": "Prod", "value": 7}, "hostSelected": map[string]interface{}{"label": "*"},
+		"dataSourceSelected": map[string]interface{}{"ds": 5, "label": "CPU"},
+		"dataPointSelected":  []interface{}{map[string]interface{}{"label": "idle"}},
+		"instanceSelectBy":   constants.Regex, "instanceRegex": ".*", "validInstanceRegex": true, "collectInterval": 60,
+	}
+	queryJSON, _ := json.Marshal(queryModel)
+	query := backend.DataQuery{RefID: "A", JSON: queryJSON, TimeRange: backend.TimeRange{From: now.Add(-30 * time.Minute), To: now}}
+	pluginContext := backend.PluginContext{DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{UID: t.Name()}}
+
+	response := logicmonitor.Query(stub.Client(t), pluginContext, query)
+	if response.Error != nil {
+		t.Fatal(response.Error)
+	}
+	table := response.Frames[0]
+	var ranked []string
+	for row := 0; row < table.Rows(); row++ {
+		ranked = append(ranked, fmt.Sprintf("%s=%v", table.Fields[0].At(row), table.Fields[3].At(row)))
+	}
+	if got := strings.Join(ranked, ","); got != "web-2=90,web-1=40" {
+		t.Errorf("ranked %s, want devices of group and its subgroups", got)
+	}
+	if urls := stub.Requests("devices/3/"); len(urls) > 0 {
+		t.Errorf("device outside of group is queried: %v", urls)
+	}
+}
+
+func TestRankingScoresRawValues(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	var times []string
+	for i := 1; i <= 4; i++ {
+		times = append(times, fmt.Sprint(now.Add(-time.Duration(i)*time.Minute).UnixMilli()))
+	}
+	// a has a single sample of 10 at the oldest minute, b has 5 at every minute
+	stub := (&testutil.SantabaStub{}).Route("devices/1/devicedatasources/11/data", http.StatusOK, fmt.Sprintf(`{"errmsg":"OK","status":200,
+		"data":{"dataSourceName":"CPU","dataPoints":["idle"],"instances":{
+		"CPU-a":{"time":[%[1]s],"values":[["No Data"],["No Data"],["No Data"],[10]]},
+		"CPU-b":{"time":[%[1]s],"values":[[5],[5],[5],[5]]}}}}`, strings.Join(times, ",")))
+	query := func(rankFormat string, nullPolicy string) backend.DataResponse {
+		queryJSON, _ := json.Marshal(map[string]interface{}{
+			"schemaVersion": 1, "queryMode": constants.RankingQueryMode, "rankFormat": rankFormat,
+			"rankOrder": constants.RankTop, "rankLimit": 1, "rankReducer": "avg", "nullPolicy": nullPolicy,
+			"groupSelected": map[string]interface{}{"label": "Prod", "value": 7},
+			"hostSelected":  map[string]interface{}{"label": "web-1", "value": "1"}, "hdsSelected": 11,
+			"dataSourceSelected": map[string]interface{}{"ds": 5, "label": "CPU"},
+			"dataPointSelected":  []interface{}{map[string]interface{}{"label": "idle"}},
+			"instanceSelectBy":   constants.Regex, "instanceRegex": ".*", "validInstanceRegex": true, "collectInterval": 60,
+		})
+		pluginContext := backend.PluginContext{DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{UID: t.Name()}}
+		response := logicmonitor.Query(stub.Client(t), pluginContext, backend.DataQuery{RefID: "A", JSON: queryJSON,
+			TimeRange: backend.TimeRange{From: now.Add(-10 * time.Minute), To: now}})
+		if response.Error != nil {
+			t.Fatal(response.Error)
+		}
+		return response
+	}
+
+	for _, nullPolicy := range []string{constants.NullAsZero, constants.NullAsPrevious, constants.NullAsDrop} {
+		table := query(constants.RankTableFormat, nullPolicy).Frames[0]
+		if got := rankedInstances(table); len(got) != 1 || got[0] != "a" || table.Fields[3].At(0).(float64) != 10 {
+			t.Errorf("%s policy ranks %v, want a scored 10 on values sent by LM", nullPolicy, got)
+		}
+	}
+
+	series := query(constants.RankSeriesFormat, constants.NullAsPrevious).Frames
+	if len(series) != 1 || series[0].Fields[1].Name != "a"+constants.InstantAndDpDelim+"idle" {
+		t.Fatalf("ranked series = %v, want a", series)
+	}
+	for row := 0; row < series[0].Rows(); row++ {
+		if v, _ := series[0].Fields[1].NullableFloatAt(row); v == nil || *v != 10 {
+			t.Errorf("row %d of ranked series = %v, want missing values replaced by previous value", row, deref(v))
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package logicmonitor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var timeShiftRegex = regexp.MustCompile(`^(\d+)([smhdw])$`) //nolint:gochecknoglobals
+
+var timeShiftUnits = map[string]time.Duration{ //nolint:gochecknoglobals
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+/*
+Gets frames of the query and, when time shift is set, frames of the same query shifted back in time. Shifted range goes through
+the normal cache and api call path with its own cache id, so current and shifted ranges are cached independently. Timestamps of
+shifted frames are moved forward onto the current window and delta or percent change series are added when asked for.
+When shifted query fails, frames of current range are returned with the error
+*/
+func getFramesWithTimeShift(query backend.DataQuery, queryModel models.QueryModel, metaData models.MetaData,
+	santabaClient httpclient.SantabaClient, pluginContext backend.PluginContext) backend.DataResponse {
+	if queryModel.TimeShift == "" {
+		return getFrames(query, queryModel, metaData, santabaClient, pluginContext)
+	}
+	shift, err := parseTimeShift(queryModel.TimeShift)
+	if err == nil && queryModel.CompareMode != "" && queryModel.CompareMode != constants.CompareDelta &&
+		queryModel.CompareMode != constants.ComparePercent {
+		err = fmt.Errorf(constants.InvalidCompareMode, queryModel.CompareMode)
+	}
+	if err != nil {
+		return backend.DataResponse{Error: err}
+	}
+	response := getFrames(query, queryModel, metaData, santabaClient, pluginContext)
+	if response.Error != nil {
+		return response
+	}
+	shiftedQuery := query
+	shiftedQuery.TimeRange.From = query.TimeRange.From.Add(-shift)
+	shiftedQuery.TimeRange.To = query.TimeRange.To.Add(-shift)
+	shiftedMetaData := buildMetaData(&queryModel, &shiftedQuery, santabaClient)
+	shiftedMetaData.Id += "shift" + queryModel.TimeShift
+	shiftedMetaData.QueryId += "shift" + queryModel.TimeShift
+	shiftedMetaData.Diagnostics = metaData.Diagnostics
+	shiftedResponse := getFrames(shiftedQuery, queryModel, shiftedMetaData, santabaClient, pluginContext)
+	if shiftedResponse.Error != nil {
+		santabaClient.Logger.Warn("Time shifted query failed", shiftedResponse.Error)
+		response.Error = fmt.Errorf(constants.TimeShiftFailed, queryModel.TimeShift, shiftedResponse.Error)
+		return response
+	}
+	shiftedFrames := make(map[string]*data.Frame)
+	for _, frame := range shiftedResponse.Frames {
+		instance := frame.RefID
+		shiftFrame(frame, shift, fmt.Sprintf(constants.ShiftedSuffix, queryModel.TimeShift))
+		shiftedFrames[instance] = frame
+	}
+	var comparisons data.Frames
+	if queryModel.CompareMode != "" {
+		for _, frame := range response.Frames {
+			if shifted, ok := shiftedFrames[frame.RefID]; ok {
+				comparisons = append(comparisons, compareFrames(frame, shifted, queryModel))
+			}
+		}
+	}
+	response.Frames = append(response.Frames, shiftedResponse.Frames...)
+	response.Frames = append(response.Frames, comparisons...)
+	return response
+}
+
+// Go durations have no day or week unit, which are the most common shifts on dashboards
+func parseTimeShift(timeShift string) (time.Duration, error) {
+	match := timeShiftRegex.FindStringSubmatch(timeShift)
+	if match == nil {
+		return 0, fmt.Errorf(constants.InvalidTimeShift, timeShift)
+	}
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf(constants.InvalidTimeShift, timeShift)
+	}
+	return time.Duration(n) * timeShiftUnits[match[2]], nil
+}
+
+// Moves timestamps of frame forward by shift and adds suffix to instance part of frame and field names
+func shiftFrame(frame *data.Frame, shift time.Duration, suffix string) {
+	timeField := frame.Fields[0]
+	for row := 0; row < timeField.Len(); row++ {
+		if t, ok := timeField.At(row).(time.Time); ok {
+			timeField.Set(row, t.Add(shift))
+		}
+	}
+	for _, field := range frame.Fields[1:] {
+		field.Name = renameInstance(field.Name, frame.RefID, suffix)
+	}
+	frame.RefID += suffix
+}
+
+// Field names are instance ~ datapoint, suffix goes after instance so datapoint can still be found at the end of the name
+func renameInstance(name string, instance string, suffix string) string {
+	if len(name) >= len(instance) && name[:len(instance)] == instance {
+		return instance + suffix + name[len(instance):]
+	}
+	return name + suffix
+}
+
+/*
+Builds delta or percent change series of current frame against shifted frame. Shifted value taken for a row is the last one
+at or before row time, as shifted samples are not collected at exactly the same seconds. Percent change against zero is missing
+*/
+func compareFrames(current *data.Frame, shifted *data.Frame, queryModel models.QueryModel) *data.Frame {
+	suffix := fmt.Sprintf(constants.DeltaSuffix, queryModel.TimeShift)
+	if queryModel.CompareMode == constants.ComparePercent {
+		suffix = fmt.Sprintf(constants.PercentSuffix, queryModel.TimeShift)
+	}
+	shiftedTimes := getTimes(shifted.Fields[0])
+	order := make([]int, len(shiftedTimes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return shiftedTimes[order[i]].Before(shiftedTimes[order[j]]) })
+
+	comparison := data.NewFrame(current.Name)
+	comparison.RefID = current.RefID + suffix
+	timeField := data.NewField(constants.TimeStr, nil, []time.Time{})
+	comparison.Fields = append(comparison.Fields, timeField)
+	for fieldIdx := 1; fieldIdx < len(current.Fields) && fieldIdx < len(shifted.Fields); fieldIdx++ {
+		field := data.NewFieldFromFieldType(current.Fields[fieldIdx].Type(), 0)
+		field.Name = renameInstance(current.Fields[fieldIdx].Name, current.RefID, suffix)
+		comparison.Fields = append(comparison.Fields, field)
+	}
+	pos := -1
+	for row, t := range getTimes(current.Fields[0]) {
+		for pos+1 < len(order) && !shiftedTimes[order[pos+1]].After(t) {
+			pos++
+		}
+		timeField.Append(t)
+		for fieldIdx := 1; fieldIdx < len(comparison.Fields); fieldIdx++ {
+			value, ok := floatAt(current.Fields[fieldIdx], row)
+			previous, previousOk := 0.0, false
+			if pos >= 0 {
+				previous, previousOk = floatAt(shifted.Fields[fieldIdx], order[pos])
+			}
+			ok = ok && previousOk
+			if queryModel.CompareMode == constants.ComparePercent {
+				ok = ok && previous != 0
+				if ok {
+					value = (value - previous) / previous * 100
+				}
+			} else {
+				value -= previous
+			}
+			appendFloat(comparison.Fields[fieldIdx], value, ok)
+		}
+	}
+	return comparison
+}
+
+func getTimes(field *data.Field) []time.Time {
+	times := make([]time.Time, field.Len())
+	for row := range times {
+		times[row], _ = field.At(row).(time.Time)
+	}
+	return times
+}
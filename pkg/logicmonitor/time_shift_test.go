@@ -0,0 +1,148 @@
+package logicmonitor_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/logicmonitor"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/testutil"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestParseTimeShift(t *testing.T) {
+	valid := map[string]time.Duration{
+		"30s": 30 * time.Second,
+		"90m": 90 * time.Minute,
+		"1h":  time.Hour,
+		"7d":  7 * 24 * time.Hour,
+		"1w":  7 * 24 * time.Hour,
+	}
+	for timeShift, want := range valid {
+		if got, err := logicmonitor.ParseTimeShift(timeShift); err != nil || got != want {
+			t.Errorf("%s = %v, %v, want %v", timeShift, got, err, want)
+		}
+	}
+	for _, timeShift := range []string{"0d", "0s", "-1d", "1y", "1.5h", "d", "1", "1 d", "1dd", "", "99999999999999999999d"} {
+		if _, err := logicmonitor.ParseTimeShift(timeShift); err == nil {
+			t.Errorf("%q is accepted", timeShift)
+		}
+	}
+}
+
+var shiftStart = time.Unix(1_700_000_000, 0).UTC() //nolint:gochecknoglobals
+
+// seriesFrame builds frame of instance with idle datapoint, sample i being at offsets[i] seconds from shiftStart
+func seriesFrame(instance string, offsets []int, values []*float64) *data.Frame {
+	times := make([]time.Time, len(offsets))
+	for i, offset := range offsets {
+		times[i] = shiftStart.Add(time.Duration(offset) * time.Second)
+	}
+	frame := data.NewFrame(constants.ResponseStr, data.NewField(constants.TimeStr, nil, times),
+		data.NewField(instance+constants.InstantAndDpDelim+"idle", nil, values))
+	frame.RefID = instance
+	return frame
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestShiftFrame(t *testing.T) {
+	frame := seriesFrame("CPU-0", []int{0, 60}, []*float64{float(1), float(2)})
+	logicmonitor.ShiftFrame(frame, 24*time.Hour, " (1d ago)")
+	if got := frame.Fields[0].At(1).(time.Time); !got.Equal(shiftStart.Add(24*time.Hour + time.Minute)) {
+		t.Errorf("shifted time is %v, want moved forward onto current window", got)
+	}
+	if frame.RefID != "CPU-0 (1d ago)" || frame.Fields[1].Name != "CPU-0 (1d ago) ~ idle" {
+		t.Errorf("shifted frame %s has field %s", frame.RefID, frame.Fields[1].Name)
+	}
+}
+
+func comparedValues(comparison *data.Frame) []*float64 {
+	values := make([]*float64, comparison.Fields[1].Len())
+	for row := range values {
+		values[row] = comparison.Fields[1].At(row).(*float64)
+	}
+	return values
+}
+
+func TestCompareFrames(t *testing.T) {
+	current := seriesFrame("CPU-0", []int{0, 60, 120, 180, 240}, []*float64{float(10), float(30), float(30), nil, float(5)})
+	// samples of shifted range are collected at other seconds and may come in any order
+	shifted := seriesFrame("CPU-0", []int{230, 50, -10, 130}, []*float64{float(2), float(20), float(0), float(15)})
+	tests := []struct {
+		compareMode string
+		name        string
+		want        []*float64
+	}{
+		// rows take last shifted sample at or before their time: -10, 50, 50, 130, 230
+		{compareMode: constants.CompareDelta, name: "CPU-0 (delta 1d) ~ idle",
+			want: []*float64{float(10), float(10), float(10), nil, float(3)}},
+		// change against zero is missing
+		{compareMode: constants.ComparePercent, name: "CPU-0 (% change 1d) ~ idle",
+			want: []*float64{nil, float(50), float(50), nil, float(150)}},
+	}
+	for _, test := range tests {
+		comparison := logicmonitor.CompareFrames(current, shifted, models.QueryModel{TimeShift: "1d", CompareMode: test.compareMode})
+		if comparison.Fields[1].Name != test.name {
+			t.Errorf("%s field is %s, want %s", test.compareMode, comparison.Fields[1].Name, test.name)
+		}
+		got := comparedValues(comparison)
+		for row := range test.want {
+			if (got[row] == nil) != (test.want[row] == nil) || (got[row] != nil && *got[row] != *test.want[row]) {
+				t.Errorf("%s row %d = %v, want %v", test.compareMode, row, deref(got[row]), deref(test.want[row]))
+			}
+		}
+	}
+}
+
+func TestCompareFramesBeforeFirstShiftedSample(t *testing.T) {
+	current := seriesFrame("CPU-0", []int{0, 60}, []*float64{float(10), float(30)})
+	shifted := seriesFrame("CPU-0", []int{30}, []*float64{float(20)})
+	queryModel := models.QueryModel{TimeShift: "1h", CompareMode: constants.CompareDelta}
+	got := comparedValues(logicmonitor.CompareFrames(current, shifted, queryModel))
+	if got[0] != nil || got[1] == nil || *got[1] != 10 {
+		t.Errorf("delta = %v, %v, want missing before first shifted sample", deref(got[0]), deref(got[1]))
+	}
+}
+
+func deref(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func TestFailedTimeShiftIsReported(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	from := now.Add(-30 * time.Minute)
+	// only current range has data, shifted range gets not found
+	stub := (&testutil.SantabaStub{}).
+		Route(fmt.Sprintf("devices/1/devicedatasources/11/data?start=%d&", from.Unix()), http.StatusOK, rawData(now, 40))
+	queryJSON, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 1, "timeShift": "1d", "compareMode": constants.CompareDelta,
+		"groupSelected":      map[string]interface{}{"label": "Prod", "value": 7},
+		"hostSelected":       map[string]interface{}{"label": "web-1", "value": "1"},
+		"hdsSelected":        11,
+		"dataSourceSelected": map[string]interface{}{"ds": 5, "label": "CPU"},
+		"dataPointSelected":  []interface{}{map[string]interface{}{"label": "idle"}},
+		"instanceSelectBy":   constants.Regex, "instanceRegex": ".*", "validInstanceRegex": true, "collectInterval": 60,
+	})
+	pluginContext := backend.PluginContext{DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{UID: t.Name()}}
+
+	response := logicmonitor.Query(stub.Client(t), pluginContext,
+		backend.DataQuery{RefID: "A", JSON: queryJSON, TimeRange: backend.TimeRange{From: from, To: now}})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "shifted by 1d") {
+		t.Errorf("error = %v, want failure of shifted query", response.Error)
+	}
+	if len(response.Frames) != 1 || response.Frames[0].Rows() == 0 {
+		t.Errorf("frames = %v, want frames of current range", response.Frames)
+	}
+}
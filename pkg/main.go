@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"os"
+	"strconv"
 
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/cache"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/tracing"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 
 	plugin "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/datasource"
@@ -25,7 +30,21 @@ func main() {
 	backend.SetupPluginEnvironment(LOGICMONITOR_PLUGIN_ID)
 	pluginLogger := log.New()
 	pluginLogger.Debug("Starting logicmonitor datasource..")
-	if err := datasource.Manage(LOGICMONITOR_PLUGIN_ID, plugin.LogicmonitorBackendDataSource, datasource.ManageOpts{}); err != nil {
+	// Grafana replicas share raw data, time ranges and api call budget through redis when it is configured
+	if address := os.Getenv(constants.RedisAddressEnv); address != "" {
+		db, _ := strconv.Atoi(os.Getenv(constants.RedisDBEnv))
+		cache.SetBackend(cache.NewRedisBackend(address, os.Getenv(constants.RedisPasswordEnv), db))
+		pluginLogger.Info("Using shared cache", "address", address, "db", db)
+	}
+	shutdownTracing, err := tracing.SetupProvider()
+	if err != nil {
+		// plugin works without traces, they are not worth failing to start for
+		pluginLogger.Error("Could not set up tracing", "error", err.Error())
+		shutdownTracing = func(context.Context) error { return nil }
+	}
+	err = datasource.Manage(LOGICMONITOR_PLUGIN_ID, plugin.LogicmonitorBackendDataSource, datasource.ManageOpts{})
+	_ = shutdownTracing(context.Background())
+	if err != nil {
 		log.DefaultLogger.Error(err.Error())
 		pluginLogger.Error("Error starting Logicmonitor datasource", "error", err.Error())
 		os.Exit(1)
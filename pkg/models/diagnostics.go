@@ -0,0 +1,69 @@
+package models
+
+import "sync"
+
+// QueryDiagnostics is collected while executing a query and set on frame meta so that it shows in grafana query inspector
+type QueryDiagnostics struct {
+	mutex             sync.Mutex
+	CalledURLs        []string              `json:"calledUrls"`
+	TimeRanges        []DiagnosticTimeRange `json:"timeRanges"`
+	ApiCallsConsumed  int                   `json:"apiCallsConsumed"`
+	ApiCallsRemaining int                   `json:"apiCallsRemaining"`
+	MatchedInstances  int                   `json:"matchedInstances"`
+	TotalInstances    int                   `json:"totalInstances"`
+}
+
+type DiagnosticTimeRange struct {
+	From     int64  `json:"from"`
+	To       int64  `json:"to"`
+	Source   string `json:"source"`
+	CacheHit bool   `json:"cacheHit"`
+}
+
+// AddCalledURL is called from concurrent API calls of a query. nil diagnostics is ignored
+func (d *QueryDiagnostics) AddCalledURL(url string) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.CalledURLs = append(d.CalledURLs, url)
+	d.ApiCallsConsumed++
+}
+
+func (d *QueryDiagnostics) AddTimeRange(from int64, to int64, source string, cacheHit bool) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.TimeRanges = append(d.TimeRanges, DiagnosticTimeRange{From: from, To: to, Source: source, CacheHit: cacheHit})
+}
+
+// Snapshot copies diagnostics collected so far. nil diagnostics gives nil
+func (d *QueryDiagnostics) Snapshot() *QueryDiagnostics {
+	if d == nil {
+		return nil
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return &QueryDiagnostics{
+		CalledURLs:        append([]string(nil), d.CalledURLs...),
+		TimeRanges:        append([]DiagnosticTimeRange(nil), d.TimeRanges...),
+		ApiCallsConsumed:  d.ApiCallsConsumed,
+		ApiCallsRemaining: d.ApiCallsRemaining,
+		MatchedInstances:  d.MatchedInstances,
+		TotalInstances:    d.TotalInstances,
+	}
+}
+
+// AddInstanceCounts adds instance counts of a device, counts are summed up when query has multiple devices
+func (d *QueryDiagnostics) AddInstanceCounts(matched int, total int) {
+	if d == nil {
+		return
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.MatchedInstances += matched
+	d.TotalInstances += total
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+)
+
+// CurrentQueryModelVersion is schema version of queries written by this version of the plugin
+const CurrentQueryModelVersion = 1
+
+/*
+migrations upgrade query model from the version of their index to the next one. Queries saved before versioning are version 0.
+A migration must keep behaviour of the query identical, so that dashboards saved years apart give the same result
+*/
+var migrations = []func(queryModel *QueryModel){ //nolint:gochecknoglobals
+	migrateFeatureFlags,
+}
+
+// UnmarshalQueryModel reads query JSON and migrates it to the current schema version
+func UnmarshalQueryModel(data []byte) (QueryModel, error) {
+	var queryModel QueryModel
+	if err := json.Unmarshal(data, &queryModel); err != nil {
+		return queryModel, err //nolint:wrapcheck
+	}
+	return queryModel, MigrateQueryModel(&queryModel)
+}
+
+// MigrateQueryModel upgrades query model in place, queries of newer schema versions are rejected
+func MigrateQueryModel(queryModel *QueryModel) error {
+	if queryModel.SchemaVersion > CurrentQueryModelVersion {
+		return fmt.Errorf(constants.UnsupportedSchemaVersion, queryModel.SchemaVersion, CurrentQueryModelVersion)
+	}
+	for version := queryModel.SchemaVersion; version < CurrentQueryModelVersion; version++ {
+		migrations[version](queryModel)
+	}
+	queryModel.SchemaVersion = CurrentQueryModelVersion
+	return nil
+}
+
+/*
+Version 0 to 1: feature flags are folded into the fields they guarded, so the flags can be retired.
+Regex selection without regex feature, or with invalid regex, matched no instance. Interpolated host without host variable
+feature was not resolved. Queries without strategic API call feature cached per time range window. Empty options get the
+defaults they were treated as
+*/
+func migrateFeatureFlags(queryModel *QueryModel) {
+	if queryModel.InstanceSelectBy == constants.Regex && !(queryModel.EnableRegexFeature && queryModel.ValidInstanceRegex) {
+		queryModel.ValidInstanceRegex = false
+	}
+	queryModel.EnableRegexFeature = true
+	queryModel.IsQueryInterpolated = queryModel.IsQueryInterpolated && queryModel.EnableHostVariableFeature
+	queryModel.EnableHostVariableFeature = true
+	if !queryModel.EnableStrategicApiCallFeature {
+		setDefault(&queryModel.CacheStrategy, constants.WindowCacheStrategy)
+	}
+	queryModel.EnableStrategicApiCallFeature = true
+	setDefault(&queryModel.CacheStrategy, constants.IncrementalCacheStrategy)
+	setDefault(&queryModel.QueryMode, constants.TimeSeriesQueryMode)
+	setDefault(&queryModel.DownsampleFunction, constants.DownsampleAvg)
+	setDefault(&queryModel.NullPolicy, constants.NullAsNaN)
+	setDefault(&queryModel.RankReducer, constants.ReducerAvg)
+	setDefault(&queryModel.RankOrder, constants.RankTop)
+	setDefault(&queryModel.RankFormat, constants.RankSeriesFormat)
+	setDefault(&queryModel.InstancePatternType, constants.Regex)
+}
+
+func setDefault(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
@@ -0,0 +1,143 @@
+package models_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+func TestMigrateQueryModel(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		check func(queryModel models.QueryModel) error
+	}{
+		{
+			name:  "regex feature off matches no instance",
+			query: `{"instanceSelectBy":"Regex","instanceRegex":".*","validInstanceRegex":true}`,
+			check: func(queryModel models.QueryModel) error {
+				if queryModel.ValidInstanceRegex {
+					return fmt.Errorf("regex is valid, want it invalid as regex feature was off")
+				}
+				return nil
+			},
+		},
+		{
+			name:  "invalid regex stays invalid",
+			query: `{"instanceSelectBy":"Regex","instanceRegex":"(","validInstanceRegex":false,"enabledRegexFeature":true}`,
+			check: func(queryModel models.QueryModel) error {
+				if queryModel.ValidInstanceRegex {
+					return fmt.Errorf("invalid regex is valid after migration")
+				}
+				return nil
+			},
+		},
+		{
+			name:  "valid regex with regex feature is kept",
+			query: `{"instanceSelectBy":"Regex","instanceRegex":".*","validInstanceRegex":true,"enabledRegexFeature":true}`,
+			check: func(queryModel models.QueryModel) error {
+				if !queryModel.ValidInstanceRegex {
+					return fmt.Errorf("valid regex is invalid after migration")
+				}
+				return nil
+			},
+		},
+		{
+			name:  "host variable feature off is not interpolated",
+			query: `{"isQueryInterpolated":true}`,
+			check: func(queryModel models.QueryModel) error {
+				if queryModel.IsQueryInterpolated {
+					return fmt.Errorf("query is interpolated, want host variable feature off to be kept")
+				}
+				return nil
+			},
+		},
+		{
+			name:  "host variable feature on is interpolated",
+			query: `{"isQueryInterpolated":true,"enabledHostVariableFeature":true}`,
+			check: func(queryModel models.QueryModel) error {
+				if !queryModel.IsQueryInterpolated {
+					return fmt.Errorf("query is not interpolated after migration")
+				}
+				return nil
+			},
+		},
+		{
+			name:  "strategic API call feature off caches per window",
+			query: `{}`,
+			check: func(queryModel models.QueryModel) error {
+				if queryModel.IncrementalCache() {
+					return fmt.Errorf("cache strategy %q is incremental, want window", queryModel.CacheStrategy)
+				}
+				return nil
+			},
+		},
+		{
+			name:  "strategic API call feature on caches incrementally",
+			query: `{"enableStrategicApiCallFeature":true}`,
+			check: func(queryModel models.QueryModel) error {
+				if !queryModel.IncrementalCache() {
+					return fmt.Errorf("cache strategy %q is window, want incremental", queryModel.CacheStrategy)
+				}
+				return nil
+			},
+		},
+		{
+			name:  "empty options get defaults",
+			query: `{}`,
+			check: func(queryModel models.QueryModel) error {
+				got := []string{queryModel.QueryMode, queryModel.DownsampleFunction, queryModel.NullPolicy, queryModel.RankReducer,
+					queryModel.RankOrder, queryModel.RankFormat, queryModel.InstancePatternType}
+				want := []string{constants.TimeSeriesQueryMode, constants.DownsampleAvg, constants.NullAsNaN, constants.ReducerAvg,
+					constants.RankTop, constants.RankSeriesFormat, constants.Regex}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					return fmt.Errorf("defaults are %v, want %v", got, want)
+				}
+				return nil
+			},
+		},
+		{
+			name:  "set options are kept",
+			query: `{"queryMode":"Ranking","nullPolicy":"zero","cacheStrategy":"incremental"}`,
+			check: func(queryModel models.QueryModel) error {
+				if queryModel.QueryMode != constants.RankingQueryMode || queryModel.NullPolicy != constants.NullAsZero ||
+					!queryModel.IncrementalCache() {
+					return fmt.Errorf("options changed by migration: %+v", queryModel)
+				}
+				return nil
+			},
+		},
+		{
+			name:  "current version is not migrated",
+			query: `{"schemaVersion":1,"instanceSelectBy":"Regex","validInstanceRegex":true}`,
+			check: func(queryModel models.QueryModel) error {
+				if !queryModel.ValidInstanceRegex || queryModel.QueryMode != "" || !queryModel.IncrementalCache() {
+					return fmt.Errorf("query of current version changed: %+v", queryModel)
+				}
+				return nil
+			},
+		},
+	}
+	for _, c := range cases {
+		queryModel, err := models.UnmarshalQueryModel([]byte(c.query))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if queryModel.SchemaVersion != models.CurrentQueryModelVersion {
+			t.Errorf("%s: schema version %d, want %d", c.name, queryModel.SchemaVersion, models.CurrentQueryModelVersion)
+		}
+		if err := c.check(queryModel); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+	}
+}
+
+func TestMigrateQueryModelRejectsFutureVersion(t *testing.T) {
+	future := fmt.Sprintf(`{"schemaVersion":%d}`, models.CurrentQueryModelVersion+1)
+	if _, err := models.UnmarshalQueryModel([]byte(future)); err == nil {
+		t.Error("query of newer schema version is accepted")
+	}
+}
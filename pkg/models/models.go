@@ -1,5 +1,12 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+)
+
 type LabelStringValue struct {
 	Label string `json:"label"`
 	Value string `json:"value"`
@@ -59,28 +66,170 @@ type HostDataSource struct {
 type AutoCompleteHosts struct {
 	Items []string `json:"items,omitempty"`
 }
+
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type Device struct {
+	Id                  int64      `json:"id"`
+	DisplayName         string     `json:"displayName"`
+	SystemProperties    []Property `json:"systemProperties"`
+	CustomProperties    []Property `json:"customProperties"`
+	InheritedProperties []Property `json:"inheritedProperties"`
+	AutoProperties      []Property `json:"autoProperties"`
+}
+
+type Devices struct {
+	Total int      `json:"total,omitempty"`
+	Items []Device `json:"items,omitempty"`
+}
+
+type Instance struct {
+	Id               int64      `json:"id"`
+	Name             string     `json:"name"`
+	DisplayName      string     `json:"displayName"`
+	Description      string     `json:"description"`
+	SystemProperties []Property `json:"systemProperties"`
+	AutoProperties   []Property `json:"autoProperties"`
+	CustomProperties []Property `json:"customProperties"`
+}
+
+type Instances struct {
+	Total int        `json:"total,omitempty"`
+	Items []Instance `json:"items,omitempty"`
+}
+
+// ResolvedDevice is a device matching property filter of a query with all its properties
+type ResolvedDevice struct {
+	Id          string
+	DisplayName string
+	Properties  map[string]string
+}
+
+type DataPointDefinition struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        int    `json:"type"`
+	AlertExpr   string `json:"alertExpr"`
+}
+
+type DataSourceDefinition struct {
+	CollectInterval int64                 `json:"collectInterval"`
+	DataPoints      []DataPointDefinition `json:"dataPoints"`
+}
+
+type Alert struct {
+	Id                string `json:"id"`
+	Type              string `json:"type"`
+	StartEpoch        int64  `json:"startEpoch"`
+	EndEpoch          int64  `json:"endEpoch"`
+	Cleared           bool   `json:"cleared"`
+	Severity          int    `json:"severity"`
+	MonitorObjectName string `json:"monitorObjectName"`
+	InstanceName      string `json:"instanceName"`
+	DataPointName     string `json:"dataPointName"`
+	AlertValue        string `json:"alertValue"`
+	Threshold         string `json:"threshold"`
+}
+
+type Alerts struct {
+	Total int     `json:"total,omitempty"`
+	Items []Alert `json:"items,omitempty"`
+}
+
+type OpsNoteTag struct {
+	Name string `json:"name"`
+}
+
+type OpsNote struct {
+	Id            string       `json:"id"`
+	HappenOnInSec int64        `json:"happenOnInSec"`
+	Note          string       `json:"note"`
+	CreatedBy     string       `json:"createdBy"`
+	Tags          []OpsNoteTag `json:"tags"`
+}
+
+type OpsNotes struct {
+	Total int       `json:"total,omitempty"`
+	Items []OpsNote `json:"items,omitempty"`
+}
+
+type Sdt struct {
+	Id            string `json:"id"`
+	Type          string `json:"type"`
+	StartDateTime int64  `json:"startDateTime"`
+	EndDateTime   int64  `json:"endDateTime"`
+	Comment       string `json:"comment"`
+	Admin         string `json:"admin"`
+	IsEffective   bool   `json:"isEffective"`
+}
+
+type Sdts struct {
+	Total int   `json:"total,omitempty"`
+	Items []Sdt `json:"items,omitempty"`
+}
+
+// Annotation is the common shape alerts, opsnotes and sdts are converted to before building frames
+type Annotation struct {
+	Time    int64
+	TimeEnd int64
+	Title   string
+	Text    string
+	Tags    []string
+}
+
+// QueryModel is the query saved in dashboards. Use UnmarshalQueryModel to read it, it upgrades queries of older schema versions
 type QueryModel struct {
-	TypeSelected                  string             `json:"typeSelected"`
-	GroupSelected                 LabelIntValue      `json:"groupSelected"`
-	HostSelected                  LabelStringValue   `json:"hostSelected"`
-	HdsSelected                   int64              `json:"hdsSelected"`
-	DataSourceSelected            DataSource         `json:"dataSourceSelected"`
-	InstanceSelected              []LabelStringValue `json:"instanceSelected"`
-	InstanceSearch                string             `json:"instanceSearch"`
-	DataPointSelected             []LabelIntValue    `json:"dataPointSelected"`
-	WithStreaming                 bool               `json:"withStreaming"`
-	CollectInterval               int64              `json:"collectInterval"`
-	LastQueryEditedTimeStamp      int64              `json:"lastQueryEditedTimeStamp"`
-	InstanceSelectBy              string             `json:"instanceSelectBy"`
-	InstanceRegex                 string             `json:"instanceRegex"`
-	ValidInstanceRegex            bool               `json:"validInstanceRegex"`
-	IsQueryInterpolated           bool               `json:"isQueryInterpolated"`
-	EnableRegexFeature            bool               `json:"enabledRegexFeature"`
-	EnableStrategicApiCallFeature bool               `json:"enableStrategicApiCallFeature"`
-	EnableHostVariableFeature     bool               `json:"enabledHostVariableFeature"`
-	EnableApiCallThrottler        bool               `json:"enableApiCallThrottler"`
-	MaxNumberOfApiCallPerQuery    int64              `json:"maxNumberOfApiCallPerQuery"`
-	ConcurrentApiCallsPerQuery    int64              `json:"concurrentApiCallsPerQuery"`
+	SchemaVersion            int                `json:"schemaVersion"`
+	TypeSelected             string             `json:"typeSelected"`
+	GroupSelected            LabelIntValue      `json:"groupSelected"`
+	HostSelected             LabelStringValue   `json:"hostSelected"`
+	HdsSelected              int64              `json:"hdsSelected"`
+	DataSourceSelected       DataSource         `json:"dataSourceSelected"`
+	InstanceSelected         []LabelStringValue `json:"instanceSelected"`
+	InstanceSearch           string             `json:"instanceSearch"`
+	DataPointSelected        []LabelIntValue    `json:"dataPointSelected"`
+	WithStreaming            bool               `json:"withStreaming"`
+	CollectInterval          int64              `json:"collectInterval"`
+	LastQueryEditedTimeStamp int64              `json:"lastQueryEditedTimeStamp"`
+	InstanceSelectBy         string             `json:"instanceSelectBy"`
+	InstanceRegex            string             `json:"instanceRegex"`
+	ValidInstanceRegex       bool               `json:"validInstanceRegex"`
+	IsQueryInterpolated      bool               `json:"isQueryInterpolated"`
+	// Deprecated: regex selection is always enabled since schema version 1, flag is only read by migration
+	EnableRegexFeature bool `json:"enabledRegexFeature"`
+	// Deprecated: replaced by CacheStrategy in schema version 1, flag is only read by migration
+	EnableStrategicApiCallFeature bool `json:"enableStrategicApiCallFeature"`
+	// Deprecated: host variable is always enabled since schema version 1, flag is only read by migration
+	EnableHostVariableFeature  bool     `json:"enabledHostVariableFeature"`
+	CacheStrategy              string   `json:"cacheStrategy"`
+	EnableApiCallThrottler     bool     `json:"enableApiCallThrottler"`
+	MaxNumberOfApiCallPerQuery int64    `json:"maxNumberOfApiCallPerQuery"`
+	ConcurrentApiCallsPerQuery int64    `json:"concurrentApiCallsPerQuery"`
+	QueryMode                  string   `json:"queryMode"`
+	AnnotationTypes            []string `json:"annotationTypes"`
+	DownsampleFunction         string   `json:"downsampleFunction"`
+	NullPolicy                 string   `json:"nullPolicy"`
+	GapIntervals               int64    `json:"gapIntervals"`
+	RankReducer                string   `json:"rankReducer"`
+	RankOrder                  string   `json:"rankOrder"`
+	RankLimit                  int      `json:"rankLimit"`
+	RankFormat                 string   `json:"rankFormat"`
+	PropertyFilter             string   `json:"propertyFilter"`
+	InstanceIncludes           []string `json:"instanceIncludes"`
+	InstanceExcludes           []string `json:"instanceExcludes"`
+	InstancePatternType        string   `json:"instancePatternType"`
+	InstancePropertyFilter     string   `json:"instancePropertyFilter"`
+	TimeShift                  string   `json:"timeShift"`
+	CompareMode                string   `json:"compareMode"`
+}
+
+// IncrementalCache tells if raw data of the query is cached incrementally, the default, rather than per time range window
+func (queryModel QueryModel) IncrementalCache() bool {
+	return queryModel.CacheStrategy != constants.WindowCacheStrategy
 }
 
 type Error struct {
@@ -104,6 +253,42 @@ type PluginSettings struct {
 	IsLMV1Enabled   bool   `json:"isLMV1Enabled"` //nolint:tagliatelle
 	Version         string `json:"version"`
 	SkipTLSVarify   bool   `json:"skipTLSVarify"`
+	EnableActions   bool   `json:"enableActions"`
+	ActionsMinRole  string `json:"actionsMinRole"`
+	// Request logging of this datasource, see httpclient.RequestLogger
+	RequestLogLevel      string `json:"requestLogLevel"`
+	RequestLogBodyBytes  int    `json:"requestLogBodyBytes"`
+	RequestLogSampleRate int64  `json:"requestLogSampleRate"`
+	// Cache warming of configured queries and, when learning, of recent dashboard queries
+	EnableCacheWarming        bool        `json:"enableCacheWarming"`
+	CacheWarmingLearn         bool        `json:"cacheWarmingLearn"`
+	CacheWarmingBudgetPercent int         `json:"cacheWarmingBudgetPercent"`
+	CacheWarmingQueries       []WarmQuery `json:"cacheWarmingQueries"`
+}
+
+// WarmQuery is a query kept warm in cache, for the last RangeSeconds
+type WarmQuery struct {
+	Query        json.RawMessage `json:"query"`
+	RangeSeconds int64           `json:"rangeSeconds"`
+}
+
+// HealthCheckItem is one check of the health report, status being pass, warn or fail
+type HealthCheckItem struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// HealthReport is sent as JSONDetails of the health check
+type HealthReport struct {
+	Checks             []HealthCheckItem `json:"checks"`
+	AuthMode           string            `json:"authMode"`
+	LatencyMs          int64             `json:"latencyMs"`
+	RateLimitLimit     int64             `json:"rateLimitLimit,omitempty"`
+	RateLimitRemaining int64             `json:"rateLimitRemaining,omitempty"`
+	ClockSkewMs        int64             `json:"clockSkewMs"`
+	TLSVersion         string            `json:"tlsVersion,omitempty"`
+	Proxy              string            `json:"proxy"`
 }
 
 type AuthSettings struct {
@@ -126,9 +311,201 @@ type MetaData struct {
 	MatchedInstances    bool
 	InstanceSelectedMap map[string]int
 	PendingApiCalls     int
+	Diagnostics         *QueryDiagnostics
+	InstanceFilter      InstanceMatcher
+	// DataSourceUID and Host tag cache entries of the query, see CacheTags
+	DataSourceUID string
+	Host          string
+}
+
+// InstanceMatcher applies include/exclude patterns and instance property filter on top of instance selection
+type InstanceMatcher interface {
+	Match(instanceName string, fullName string) bool
+}
+
+// CacheTags tell which datasource, host and query a cache entry belongs to
+type CacheTags struct {
+	DataSourceUID string
+	Host          string
+	QueryId       string
+}
+
+// CacheFilter selects cache entries of datasource, optionally of host or query. All selects every entry
+type CacheFilter struct {
+	DataSourceUID string
+	Host          string
+	QueryId       string
+}
+
+// CacheEntryInfo describes cache entry, From and To are time range of data in epoch milliseconds when entry holds time series.
+// TTLRemainingSeconds is -1 for entries not expiring
+type CacheEntryInfo struct {
+	Cache               string `json:"cache"`
+	Key                 string `json:"key"`
+	DataSourceUID       string `json:"dataSourceUid,omitempty"`
+	Host                string `json:"host,omitempty"`
+	QueryId             string `json:"queryId,omitempty"`
+	SizeBytes           int    `json:"sizeBytes"`
+	From                int64  `json:"from,omitempty"`
+	To                  int64  `json:"to,omitempty"`
+	TTLRemainingSeconds int64  `json:"ttlRemainingSeconds"`
 }
 
 type ApiCallsTracker struct {
 	TimeStamp int64
 	NrOfCalls int
 }
+
+// ExportRequest asks for raw samples of query in time range, times in epoch milliseconds
+type ExportRequest struct {
+	Query  json.RawMessage `json:"query"`
+	From   int64           `json:"from"`
+	To     int64           `json:"to"`
+	Format string          `json:"format"`
+}
+
+// ExportRow is single sample of exported data, Value is nil when LM has no data for the sample
+type ExportRow struct {
+	Time      time.Time
+	Host      string
+	Instance  string
+	DataPoint string
+	Value     *float64
+}
+
+type AckAlertRequest struct {
+	AlertId string `json:"alertId"`
+	Comment string `json:"comment"`
+}
+
+// CreateSdtRequest schedules one time downtime, times in epoch milliseconds
+type CreateSdtRequest struct {
+	Scope                string `json:"scope"`
+	DeviceId             int64  `json:"deviceId"`
+	DeviceGroupId        int64  `json:"deviceGroupId"`
+	DataSourceInstanceId int64  `json:"dataSourceInstanceId"`
+	StartDateTime        int64  `json:"startDateTime"`
+	EndDateTime          int64  `json:"endDateTime"`
+	Comment              string `json:"comment"`
+}
+
+type DeleteSdtRequest struct {
+	SdtId string `json:"sdtId"`
+}
+
+type AckPayload struct {
+	AckComment string `json:"ackComment"`
+}
+
+type SdtPayload struct {
+	Type                 string `json:"type"`
+	SdtType              string `json:"sdtType"`
+	DeviceId             int64  `json:"deviceId,omitempty"`
+	DeviceGroupId        int64  `json:"deviceGroupId,omitempty"`
+	DataSourceInstanceId int64  `json:"dataSourceInstanceId,omitempty"`
+	StartDateTime        int64  `json:"startDateTime"`
+	EndDateTime          int64  `json:"endDateTime"`
+	Comment              string `json:"comment"`
+}
+
+type ImportDashboardRequest struct {
+	DashboardId int64 `json:"dashboardId"`
+}
+
+type WidgetConfig struct {
+	Col   int `json:"col"`
+	Row   int `json:"row"`
+	SizeX int `json:"sizex"`
+	SizeY int `json:"sizey"`
+}
+
+type LMDashboard struct {
+	Id            int64                   `json:"id"`
+	Name          string                  `json:"name"`
+	Description   string                  `json:"description"`
+	WidgetsConfig map[string]WidgetConfig `json:"widgetsConfig"`
+}
+
+type GlobMatchToggle struct {
+	Value  string `json:"value"`
+	IsGlob bool   `json:"isGlob"`
+}
+
+type WidgetDataPoint struct {
+	Name                string          `json:"name"`
+	DataPointId         int64           `json:"dataPointId"`
+	DataPointName       string          `json:"dataPointName"`
+	DataSourceId        int64           `json:"dataSourceId"`
+	DataSourceFullName  string          `json:"dataSourceFullName"`
+	DeviceGroupFullPath GlobMatchToggle `json:"deviceGroupFullPath"`
+	DeviceDisplayName   GlobMatchToggle `json:"deviceDisplayName"`
+	InstanceName        GlobMatchToggle `json:"instanceName"`
+}
+
+type VirtualDataPoint struct {
+	Name string `json:"name"`
+	Rpn  string `json:"rpn"`
+}
+
+type GraphInfo struct {
+	DataPoints        []WidgetDataPoint  `json:"dataPoints"`
+	VirtualDataPoints []VirtualDataPoint `json:"virtualDataPoints"`
+}
+
+type Widget struct {
+	Id          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Content     string     `json:"content"`
+	GraphInfo   *GraphInfo `json:"graphInfo"`
+}
+
+type Widgets struct {
+	Total int      `json:"total,omitempty"`
+	Items []Widget `json:"items,omitempty"`
+}
+
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type PanelDataSource struct {
+	Type string `json:"type"`
+	Uid  string `json:"uid"`
+}
+
+type GrafanaPanel struct {
+	Id          int                      `json:"id"`
+	Type        string                   `json:"type"`
+	Title       string                   `json:"title"`
+	Description string                   `json:"description,omitempty"`
+	GridPos     GridPos                  `json:"gridPos"`
+	DataSource  *PanelDataSource         `json:"datasource,omitempty"`
+	Targets     []map[string]interface{} `json:"targets,omitempty"`
+	Options     map[string]interface{}   `json:"options,omitempty"`
+}
+
+type GrafanaDashboard struct {
+	Title         string            `json:"title"`
+	Description   string            `json:"description,omitempty"`
+	Tags          []string          `json:"tags"`
+	Panels        []GrafanaPanel    `json:"panels"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Time          map[string]string `json:"time"`
+}
+
+type UntranslatedWidget struct {
+	WidgetId int64  `json:"widgetId"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Reason   string `json:"reason"`
+}
+
+type ImportDashboardResponse struct {
+	Dashboard    GrafanaDashboard     `json:"dashboard"`
+	Untranslated []UntranslatedWidget `json:"untranslated"`
+}
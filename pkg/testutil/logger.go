@@ -0,0 +1,55 @@
+package testutil
+
+import (
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// Logger records what is logged, for tests of audit and request logs
+type Logger struct {
+	mutex   sync.Mutex
+	entries []LogEntry
+}
+
+// LogEntry is one message logged with its key value pairs
+type LogEntry struct {
+	Level   log.Level
+	Message string
+	Args    []interface{}
+}
+
+// Arg returns value logged for key
+func (e LogEntry) Arg(key string) (interface{}, bool) {
+	for i := 0; i+1 < len(e.Args); i += 2 {
+		if e.Args[i] == key {
+			return e.Args[i+1], true
+		}
+	}
+	return nil, false
+}
+
+func (l *Logger) Debug(msg string, args ...interface{}) { l.log(log.Debug, msg, args) }
+func (l *Logger) Info(msg string, args ...interface{})  { l.log(log.Info, msg, args) }
+func (l *Logger) Warn(msg string, args ...interface{})  { l.log(log.Warn, msg, args) }
+func (l *Logger) Error(msg string, args ...interface{}) { l.log(log.Error, msg, args) }
+func (l *Logger) Level() log.Level                      { return log.Debug }
+
+func (l *Logger) log(level log.Level, msg string, args []interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, LogEntry{Level: level, Message: msg, Args: args})
+}
+
+// Logged returns entries having message, in the order they were logged
+func (l *Logger) Logged(message string) []LogEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	var entries []LogEntry
+	for _, entry := range l.entries {
+		if entry.Message == message {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
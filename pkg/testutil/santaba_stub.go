@@ -0,0 +1,108 @@
+// Package testutil holds fixtures shared by tests of several packages
+package testutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/httpclient"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// RoundTripFunc answers requests with a function, for tests needing more than fixed routes
+type RoundTripFunc func(request *http.Request) (*http.Response, error)
+
+func (f RoundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+// Response of status and body to request
+func Response(request *http.Request, status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewBufferString(body)),
+		Request: request}
+}
+
+// Envelope wraps data like santaba v1 responses
+func Envelope(data string) string {
+	return `{"status":200,"errmsg":"OK","data":` + data + `}`
+}
+
+// SantabaStub answers santaba requests with the first route contained in the request URL and records the requests
+type SantabaStub struct {
+	mutex    sync.Mutex
+	routes   []stubRoute
+	requests []Request
+}
+
+type stubRoute struct {
+	contains string
+	status   int
+	body     string
+}
+
+// Request recorded by SantabaStub
+type Request struct {
+	Method string
+	URL    string
+	Body   string
+}
+
+func (r Request) String() string {
+	return r.Method + " " + r.URL
+}
+
+func (s *SantabaStub) Route(contains string, status int, body string) *SantabaStub {
+	s.routes = append(s.routes, stubRoute{contains: contains, status: status, body: body})
+	return s
+}
+
+func (s *SantabaStub) RoundTrip(request *http.Request) (*http.Response, error) {
+	recorded := Request{Method: request.Method, URL: request.URL.String()}
+	if request.Body != nil {
+		body, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			return nil, err //nolint:wrapcheck
+		}
+		recorded.Body = string(body)
+	}
+	s.mutex.Lock()
+	s.requests = append(s.requests, recorded)
+	routes := s.routes
+	s.mutex.Unlock()
+	for _, route := range routes {
+		if strings.Contains(recorded.URL, route.contains) {
+			return Response(request, route.status, route.body), nil
+		}
+	}
+	return Response(request, http.StatusNotFound, `{"errorMessage":"not found"}`), nil
+}
+
+// Requests having URL containing contains, in the order they were made
+func (s *SantabaStub) Requests(contains string) []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var requests []Request
+	for _, request := range s.requests {
+		if strings.Contains(request.URL, contains) {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// Client of a datasource with uid of the test, sending requests to the stub
+func (s *SantabaStub) Client(t testing.TB) httpclient.SantabaClient {
+	t.Helper()
+	return httpclient.SantabaClient{
+		DataSourceUID:  t.Name(),
+		PluginSettings: &models.PluginSettings{Path: "portal"},
+		AuthSettings:   &models.AuthSettings{},
+		Client:         &http.Client{Transport: s},
+		Logger:         log.DefaultLogger,
+	}
+}
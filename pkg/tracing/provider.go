@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+/*
+SetupProvider registers a tracer provider exporting spans to the OTLP collector grafana passes to the plugin in its environment,
+which grafana does when tracing is configured for the plugin. Spans stay with the no-op provider when no collector is passed.
+Returned shutdown exports spans still buffered, it is to be called when the plugin exits
+*/
+func SetupProvider() (func(context.Context) error, error) {
+	address := os.Getenv(constants.OTLPAddressEnv)
+	if address == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if propagation := os.Getenv(constants.OTLPPropagationEnv); propagation != "" && propagation != constants.OTLPPropagationW3C {
+		return nil, fmt.Errorf("unsupported trace propagation %s, only %s is supported", propagation, constants.OTLPPropagationW3C)
+	}
+	// exporter connects in background, plugin starts also when collector is down
+	exporter, err := otlptracegrpc.New(context.Background(), otlptracegrpc.WithEndpoint(address), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter for %s: %w", address, err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(constants.PluginId))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+/*
+Spans go to the global tracer provider, which is a no-op until one is registered. The plugin SDK in use does not register one,
+SetupProvider registers an OTLP exporting provider when grafana has tracing configured for the plugin
+*/
+
+// Start starts span as child of the span in ctx, nil ctx is taken as background
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(constants.PluginId).Start(ctx, name, trace.WithAttributes(attrs...)) //nolint:spancheck
+}
+
+/*
+StartDetached starts span of work outliving the request in ctx, like a background refresh. The span is root of a new trace
+linked to the span in ctx, its context is not cancelled when the request ends
+*/
+func StartDetached(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	var links []trace.Link
+	if ctx != nil {
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			links = append(links, trace.Link{SpanContext: spanContext})
+		}
+	}
+	return otel.Tracer(constants.PluginId).Start(context.Background(), name, //nolint:spancheck
+		trace.WithAttributes(attrs...), trace.WithLinks(links...))
+}
+
+// End records error, if any, on span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ContextWithGrafanaTrace continues the trace of grafana, sent as W3C trace context in grpc metadata of the plugin request
+func ContextWithGrafanaTrace(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, metadataCarrier(md))
+}
+
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
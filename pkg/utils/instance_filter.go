@@ -0,0 +1,88 @@
+package logicmonitor
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+)
+
+type instanceFilter struct {
+	includes           []*regexp.Regexp
+	excludes           []*regexp.Regexp
+	properties         PropertyFilter
+	instanceProperties map[string]map[string]string
+}
+
+/*
+NewInstanceFilter compiles include/exclude patterns and instance property filter of the query. Patterns are glob or regex as per
+InstancePatternType and are matched against instance name without datasource prefix. Includes apply only when instances are
+selected by pattern, excludes and property filter apply to every instance selection. Nil is returned when there is nothing to filter
+*/
+func NewInstanceFilter(queryModel *models.QueryModel, instanceProperties map[string]map[string]string) (models.InstanceMatcher, error) {
+	filter := &instanceFilter{instanceProperties: instanceProperties}
+	var err error
+	if queryModel.InstanceSelectBy == constants.Pattern {
+		if filter.includes, err = compileInstancePatterns(queryModel.InstanceIncludes, queryModel.InstancePatternType); err != nil {
+			return nil, err
+		}
+	}
+	if filter.excludes, err = compileInstancePatterns(queryModel.InstanceExcludes, queryModel.InstancePatternType); err != nil {
+		return nil, err
+	}
+	if queryModel.InstancePropertyFilter != "" {
+		if filter.properties, _, err = ParsePropertyFilter(queryModel.InstancePropertyFilter); err != nil {
+			return nil, err
+		}
+	}
+	if len(filter.includes) == 0 && len(filter.excludes) == 0 && filter.properties == nil {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+func compileInstancePatterns(patterns []string, patternType string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		expr := pattern
+		if patternType == constants.Glob {
+			expr = GlobToRegex(pattern)
+		}
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf(constants.InvalidInstancePattern, pattern, err)
+		}
+		compiled = append(compiled, regex)
+	}
+	return compiled, nil
+}
+
+func (f *instanceFilter) Match(instanceName string, fullName string) bool {
+	if len(f.includes) > 0 && !matchAny(f.includes, instanceName) {
+		return false
+	}
+	if matchAny(f.excludes, instanceName) {
+		return false
+	}
+	if f.properties != nil {
+		properties, ok := f.instanceProperties[fullName]
+		if !ok {
+			properties = f.instanceProperties[instanceName]
+		}
+		return f.properties.Match(properties)
+	}
+	return true
+}
+
+func matchAny(regexes []*regexp.Regexp, name string) bool {
+	for _, regex := range regexes {
+		if regex.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package logicmonitor_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/models"
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+)
+
+var interfaces = []string{"eth0", "eth1", "eth10", "lo"} //nolint:gochecknoglobals
+
+// matchedInstances returns instances of Interfaces datasource matched by query, names are as in raw data
+func matchedInstances(t *testing.T, queryModel models.QueryModel, instanceProperties map[string]map[string]string) string {
+	t.Helper()
+	filter, err := utils.NewInstanceFilter(&queryModel, instanceProperties)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var matched []string
+	for _, instance := range interfaces {
+		if shortName, ok := utils.IsInstanceMatched(models.MetaData{InstanceFilter: filter}, &queryModel, "Interfaces",
+			"Interfaces-"+instance); ok {
+			matched = append(matched, shortName)
+		}
+	}
+	return strings.Join(matched, ",")
+}
+
+func TestInstancePatterns(t *testing.T) {
+	tests := []struct {
+		name        string
+		patternType string
+		includes    []string
+		excludes    []string
+		want        string
+	}{
+		{name: "glob include", patternType: constants.Glob, includes: []string{"eth*"}, want: "eth0,eth1,eth10"},
+		{name: "glob is anchored", patternType: constants.Glob, includes: []string{"eth1"}, want: "eth1"},
+		{name: "glob exclude", patternType: constants.Glob, includes: []string{"eth*"}, excludes: []string{"eth1?"},
+			want: "eth0,eth1"},
+		{name: "regex is not anchored", patternType: constants.Regex, includes: []string{"th1"}, want: "eth1,eth10"},
+		{name: "regex exclude", patternType: constants.Regex, excludes: []string{`^eth\d$`}, want: "eth10,lo"},
+		{name: "exclude wins over include", patternType: constants.Glob, includes: []string{"eth0", "lo"},
+			excludes: []string{"eth0"}, want: "lo"},
+		{name: "no pattern selects all", patternType: constants.Glob, want: "eth0,eth1,eth10,lo"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queryModel := models.QueryModel{InstanceSelectBy: constants.Pattern, InstancePatternType: test.patternType,
+				InstanceIncludes: test.includes, InstanceExcludes: test.excludes}
+			if got := matchedInstances(t, queryModel, nil); got != test.want {
+				t.Errorf("matched %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInstanceExcludesApplyToSelection(t *testing.T) {
+	queryModel := models.QueryModel{InstanceSelectBy: constants.Select, InstancePatternType: constants.Glob,
+		InstanceSelected: []models.LabelStringValue{{Label: "eth0"}, {Label: "eth1"}},
+		InstanceIncludes: []string{"lo"}, InstanceExcludes: []string{"eth1"}}
+	if got := matchedInstances(t, queryModel, nil); got != "eth0" {
+		t.Errorf("matched %s, want selected instances less excluded ones, includes being only for pattern selection", got)
+	}
+}
+
+func TestInstancePropertyFilter(t *testing.T) {
+	instanceProperties := map[string]map[string]string{
+		"Interfaces-eth0": {"auto.speed": "1000"},
+		"eth1":            {"auto.speed": "1000"},
+		"eth10":           {"auto.speed": "100"},
+	}
+	queryModel := models.QueryModel{InstanceSelectBy: constants.Pattern, InstancePatternType: constants.Glob,
+		InstanceIncludes: []string{"*"}, InstanceExcludes: []string{"eth0"}, InstancePropertyFilter: "auto.speed = 1000"}
+	if got := matchedInstances(t, queryModel, instanceProperties); got != "eth1" {
+		t.Errorf("matched %s, want instances by full or short name having property, less excluded ones", got)
+	}
+}
+
+func TestInvalidInstancePattern(t *testing.T) {
+	for _, queryModel := range []models.QueryModel{
+		{InstanceSelectBy: constants.Pattern, InstancePatternType: constants.Regex, InstanceIncludes: []string{"eth("}},
+		{InstanceSelectBy: constants.Select, InstancePatternType: constants.Regex, InstanceExcludes: []string{"[lo"}},
+	} {
+		_, err := utils.NewInstanceFilter(&queryModel, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), strings.SplitN(constants.InvalidInstancePattern, "%", 2)[0]) {
+			t.Errorf("error = %v, want invalid instance pattern", err)
+		}
+	}
+}
+
+func TestNoInstanceFilter(t *testing.T) {
+	queryModel := models.QueryModel{InstanceSelectBy: constants.Select, InstanceIncludes: []string{"eth*"}, InstanceExcludes: []string{""}}
+	if filter, err := utils.NewInstanceFilter(&queryModel, nil); filter != nil || err != nil {
+		t.Errorf("filter = %v, %v, want nil when there is nothing to filter", filter, err)
+	}
+}
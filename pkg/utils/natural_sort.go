@@ -0,0 +1,51 @@
+package logicmonitor
+
+import "unicode"
+
+/*
+NaturalLess compares strings treating runs of digits as numbers, so "eth2" sorts before "eth10". Strings equal in natural order,
+like "a01" and "a1", are compared as plain strings to keep the order total
+*/
+func NaturalLess(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			ei, ej := digitsEnd(ra, i), digitsEnd(rb, j)
+			na, nb := trimZeros(ra[i:ei]), trimZeros(rb[j:ej])
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			for k := range na {
+				if na[k] != nb[k] {
+					return na[k] < nb[k]
+				}
+			}
+			i, j = ei, ej
+			continue
+		}
+		if ra[i] != rb[j] {
+			return ra[i] < rb[j]
+		}
+		i++
+		j++
+	}
+	if len(ra)-i != len(rb)-j {
+		return len(ra)-i < len(rb)-j
+	}
+	return a < b
+}
+
+func digitsEnd(r []rune, start int) int {
+	for start < len(r) && unicode.IsDigit(r[start]) {
+		start++
+	}
+	return start
+}
+
+func trimZeros(digits []rune) []rune {
+	for len(digits) > 1 && digits[0] == '0' {
+		digits = digits[1:]
+	}
+	return digits
+}
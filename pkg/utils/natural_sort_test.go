@@ -0,0 +1,44 @@
+package logicmonitor_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+)
+
+func TestNaturalLess(t *testing.T) {
+	cases := []struct {
+		a    string
+		b    string
+		less bool
+	}{
+		{"eth2", "eth10", true},
+		{"eth10", "eth2", false},
+		{"eth2", "eth2", false},
+		{"disk9-part2", "disk10-part1", true},
+		{"disk1-part10", "disk1-part9", false},
+		{"eth", "eth0", true},
+		{"a", "b", true},
+		{"10", "9", false},
+		// same number written with leading zeros, zero padded name sorts first so that order is total
+		{"a01", "a1", true},
+		{"a1", "a01", false},
+		{"a01b", "a1c", true},
+	}
+	for _, c := range cases {
+		if got := utils.NaturalLess(c.a, c.b); got != c.less {
+			t.Errorf("NaturalLess(%q, %q) = %v, want %v", c.a, c.b, got, c.less)
+		}
+	}
+}
+
+func TestNaturalSort(t *testing.T) {
+	names := []string{"eth10", "a1", "eth2", "eth1", "a01", "lo", "eth"}
+	sort.Slice(names, func(i, j int) bool { return utils.NaturalLess(names[i], names[j]) })
+	want := "a01 a1 eth eth1 eth2 eth10 lo"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("sorted %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,287 @@
+package logicmonitor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/constants"
+)
+
+/*
+PropertyFilter is parsed from a filter expression on device properties like
+
+	env = prod AND (site ~ "lon*" OR customer =~ "^acme-.*")
+
+Supported operators are = (equals), != (not equals), ~ (glob) and =~ (regex). Conditions are combined with AND/OR, AND binds
+stronger than OR and parentheses can be used for grouping. Values having spaces or special characters are quoted
+*/
+type PropertyFilter interface {
+	Match(properties map[string]string) bool
+}
+
+type propertyCondition struct {
+	name  string
+	op    string
+	value string
+	regex *regexp.Regexp
+}
+
+type propertyAnd []PropertyFilter
+
+type propertyOr []PropertyFilter
+
+func (c propertyCondition) Match(properties map[string]string) bool {
+	value, ok := properties[c.name]
+	switch c.op {
+	case "!=":
+		return !ok || value != c.value
+	case "=":
+		return ok && value == c.value
+	default:
+		return ok && c.regex.MatchString(value)
+	}
+}
+
+func (a propertyAnd) Match(properties map[string]string) bool {
+	for _, filter := range a {
+		if !filter.Match(properties) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o propertyOr) Match(properties map[string]string) bool {
+	for _, filter := range o {
+		if filter.Match(properties) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupMembership matches devices of groups matching a glob on group full path, subgroups included, by display name glob
+type groupMembership struct {
+	group *regexp.Regexp
+	host  *regexp.Regexp
+}
+
+func (g groupMembership) Match(properties map[string]string) bool {
+	if !g.host.MatchString(properties[constants.SystemDisplayNameProperty]) {
+		return false
+	}
+	for _, group := range strings.Split(properties[constants.SystemGroupsProperty], ",") {
+		if group = strings.TrimSpace(group); group != "" && g.group.MatchString(group) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+GroupFilter matches devices in groups matching group glob or in their subgroups, having display name matching host glob.
+Empty globs match everything, like * selected in query editor
+*/
+func GroupFilter(groupGlob string, hostGlob string) (PropertyFilter, error) {
+	if groupGlob == "" {
+		groupGlob = "*"
+	}
+	if hostGlob == "" {
+		hostGlob = "*"
+	}
+	group, err := regexp.Compile(strings.TrimSuffix(GlobToRegex(groupGlob), "$") + "(/.*)?$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid group pattern %s: %w", groupGlob, err)
+	}
+	host, err := regexp.Compile(GlobToRegex(hostGlob))
+	if err != nil {
+		return nil, fmt.Errorf("invalid host pattern %s: %w", hostGlob, err)
+	}
+	return groupMembership{group: group, host: host}, nil
+}
+
+// IsGlob tells if host or group selected in query stands for more than one, empty selection is all
+func IsGlob(selected string) bool {
+	return selected == "" || strings.ContainsAny(selected, "*?")
+}
+
+type propertyFilterParser struct {
+	tokens []string
+	pos    int
+	names  []string
+}
+
+// ParsePropertyFilter parses filter expression and returns names of the properties used in it, in order of appearance
+func ParsePropertyFilter(expression string) (PropertyFilter, []string, error) {
+	tokens, err := tokenizePropertyFilter(expression)
+	if err != nil {
+		return nil, nil, err
+	}
+	parser := &propertyFilterParser{tokens: tokens}
+	filter, err := parser.parseOr()
+	if err != nil {
+		return nil, nil, err
+	}
+	if parser.pos < len(tokens) {
+		return nil, nil, fmt.Errorf("unexpected %s in property filter", tokens[parser.pos])
+	}
+	return filter, parser.names, nil
+}
+
+func (p *propertyFilterParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+func (p *propertyFilterParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *propertyFilterParser) parseOr() (PropertyFilter, error) {
+	filters := propertyOr{}
+	for {
+		filter, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+		if !strings.EqualFold(p.peek(), "OR") {
+			break
+		}
+		p.next()
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return filters, nil
+}
+
+func (p *propertyFilterParser) parseAnd() (PropertyFilter, error) {
+	filters := propertyAnd{}
+	for {
+		filter, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+		if !strings.EqualFold(p.peek(), "AND") {
+			break
+		}
+		p.next()
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return filters, nil
+}
+
+func (p *propertyFilterParser) parseFactor() (PropertyFilter, error) {
+	if p.peek() == "(" {
+		p.next()
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ) in property filter")
+		}
+		return filter, nil
+	}
+	name, op, value := p.next(), p.next(), p.next()
+	if name == "" || op == "" || value == "" {
+		return nil, fmt.Errorf("incomplete condition in property filter")
+	}
+	value = unquote(value)
+	condition := propertyCondition{name: name, op: op, value: value}
+	var err error
+	switch op {
+	case "=", "!=":
+	case "~":
+		condition.regex, err = regexp.Compile(GlobToRegex(value))
+	case "=~":
+		condition.regex, err = regexp.Compile(value)
+	default:
+		return nil, fmt.Errorf("invalid operator %s in property filter", op)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %s in property filter: %w", value, err)
+	}
+	p.names = appendIfMissing(p.names, name)
+	return condition, nil
+}
+
+func tokenizePropertyFilter(expression string) ([]string, error) {
+	var tokens []string
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '(' || runes[i] == ')':
+			tokens = append(tokens, string(runes[i]))
+			i++
+		case strings.HasPrefix(string(runes[i:]), "=~") || strings.HasPrefix(string(runes[i:]), "!="):
+			tokens = append(tokens, string(runes[i:i+2]))
+			i += 2
+		case runes[i] == '=' || runes[i] == '~':
+			tokens = append(tokens, string(runes[i]))
+			i++
+		case runes[i] == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("missing closing quote in property filter")
+			}
+			tokens = append(tokens, string(runes[i:end+1]))
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()=~!"`, runes[end]) {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("unexpected %c in property filter", runes[i])
+			}
+			tokens = append(tokens, string(runes[i:end]))
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return strings.ReplaceAll(value[1:len(value)-1], `\"`, `"`)
+	}
+	return value
+}
+
+func appendIfMissing(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// GlobToRegex converts glob with * and ? wildcards to anchored regex
+func GlobToRegex(glob string) string {
+	regex := regexp.QuoteMeta(glob)
+	regex = strings.ReplaceAll(regex, `\*`, ".*")
+	regex = strings.ReplaceAll(regex, `\?`, ".")
+	return "^" + regex + "$"
+}
@@ -0,0 +1,130 @@
+package logicmonitor_test
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	utils "github.com/grafana/grafana-logicmonitor-datasource-backend/pkg/utils"
+)
+
+func TestPropertyFilter(t *testing.T) {
+	prod := map[string]string{"env": "prod", "site": "london-1", "customer": "acme-eu"}
+	staging := map[string]string{"env": "staging", "site": "paris", "customer": "acme-us", "owner": "Jane Doe"}
+	tests := []struct {
+		expression string
+		want       []bool // matches of prod and staging
+		names      []string
+	}{
+		{expression: "env = prod", want: []bool{true, false}, names: []string{"env"}},
+		{expression: "env != prod", want: []bool{false, true}, names: []string{"env"}},
+		{expression: "owner != x", want: []bool{true, true}, names: []string{"owner"}},
+		{expression: `site ~ "lon*"`, want: []bool{true, false}, names: []string{"site"}},
+		{expression: `site ~ "lon"`, want: []bool{false, false}, names: []string{"site"}},
+		{expression: `customer =~ "^acme-(eu|us)$"`, want: []bool{true, true}, names: []string{"customer"}},
+		{expression: `owner = "Jane Doe"`, want: []bool{false, true}, names: []string{"owner"}},
+		// AND binds stronger than OR
+		{expression: "env = staging OR env = prod AND site = paris", want: []bool{false, true}, names: []string{"env", "site"}},
+		{expression: "(env = staging OR env = prod) AND site = paris", want: []bool{false, true}, names: []string{"env", "site"}},
+		{expression: "env = prod AND site = paris OR customer = acme-eu", want: []bool{true, false},
+			names: []string{"env", "site", "customer"}},
+		{expression: "env = prod and (site = paris or customer = acme-eu)", want: []bool{true, false},
+			names: []string{"env", "site", "customer"}},
+	}
+	for _, test := range tests {
+		filter, names, err := utils.ParsePropertyFilter(test.expression)
+		if err != nil {
+			t.Errorf("%s: %v", test.expression, err)
+			continue
+		}
+		if got := []bool{filter.Match(prod), filter.Match(staging)}; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s matches %v, want %v", test.expression, got, test.want)
+		}
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s uses properties %v, want %v", test.expression, names, test.names)
+		}
+	}
+}
+
+func TestPropertyFilterQuoting(t *testing.T) {
+	filter, _, err := utils.ParsePropertyFilter(`note = "say \"hi\" (now) OR = ~"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filter.Match(map[string]string{"note": `say "hi" (now) OR = ~`}) {
+		t.Error("quoted value with escaped quotes, parentheses and operators is not matched as is")
+	}
+}
+
+func TestPropertyFilterParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"env":                        "incomplete condition",
+		"env =":                      "incomplete condition",
+		`env = "prod`:                "missing closing quote",
+		"(env = prod":                "missing )",
+		"env = prod)":                "unexpected )",
+		"env = prod site = x":        "unexpected site",
+		"env > prod":                 "invalid operator >",
+		"env prod x":                 "invalid operator prod",
+		`env =~ "(unclosed"`:         "invalid pattern",
+		"env = prod AND":             "incomplete condition",
+		"env = prod OR OR env = dev": "invalid operator env",
+	}
+	for expression, want := range tests {
+		if _, _, err := utils.ParsePropertyFilter(expression); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error %v, want %s", expression, err, want)
+		}
+	}
+}
+
+func TestGlobToRegex(t *testing.T) {
+	tests := []struct {
+		glob    string
+		matches []string
+		misses  []string
+	}{
+		{glob: "web-*", matches: []string{"web-", "web-01"}, misses: []string{"xweb-01", "web"}},
+		{glob: "db?", matches: []string{"db1"}, misses: []string{"db", "db12"}},
+		// regex characters are literal
+		{glob: "a.b+(c)", matches: []string{"a.b+(c)"}, misses: []string{"axb+(c)", "a.bb(c)"}},
+		{glob: "[x]|^$", matches: []string{"[x]|^$"}, misses: []string{"x", ""}},
+	}
+	for _, test := range tests {
+		regex := regexp.MustCompile(utils.GlobToRegex(test.glob))
+		for _, value := range test.matches {
+			if !regex.MatchString(value) {
+				t.Errorf("%s does not match %q", test.glob, value)
+			}
+		}
+		for _, value := range test.misses {
+			if regex.MatchString(value) {
+				t.Errorf("%s matches %q", test.glob, value)
+			}
+		}
+	}
+}
+
+func TestGroupFilter(t *testing.T) {
+	filter, err := utils.GroupFilter("Prod", "web-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		host   string
+		groups string
+		want   bool
+	}{
+		{host: "web-1", groups: "Prod", want: true},
+		{host: "web-1", groups: "Linux, Prod/Web/EU", want: true},
+		{host: "web-1", groups: "Production", want: false},
+		{host: "db-1", groups: "Prod", want: false},
+		{host: "web-1", groups: "", want: false},
+	}
+	for _, test := range tests {
+		properties := map[string]string{"system.displayname": test.host, "system.groups": test.groups}
+		if got := filter.Match(properties); got != test.want {
+			t.Errorf("%s in %s matched = %v, want %v", test.host, test.groups, got, test.want)
+		}
+	}
+}
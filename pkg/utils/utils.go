@@ -13,7 +13,18 @@ import (
 )
 
 func IsInstanceMatched(metadata models.MetaData, queryModel *models.QueryModel, dataSourceName string, instanceName string) (string, bool) {
-	if queryModel.EnableRegexFeature && queryModel.ValidInstanceRegex && queryModel.InstanceSelectBy == constants.Regex {
+	shortenInstance, matched := isInstanceSelected(queryModel, dataSourceName, instanceName)
+	if matched && metadata.InstanceFilter != nil {
+		matched = metadata.InstanceFilter.Match(shortenInstance, instanceName)
+	}
+	return shortenInstance, matched
+}
+
+func isInstanceSelected(queryModel *models.QueryModel, dataSourceName string, instanceName string) (string, bool) {
+	if queryModel.InstanceSelectBy == constants.Pattern {
+		// includes are matched by instance filter
+		return instanceName[strings.IndexByte(instanceName, '-')+1:], true
+	} else if queryModel.ValidInstanceRegex && queryModel.InstanceSelectBy == constants.Regex {
 		instace := instanceName[strings.IndexByte(instanceName, '-')+1:]
 		match, err := regexp.MatchString(queryModel.InstanceRegex, instace)
 		return instace, err == nil && match
@@ -50,17 +61,18 @@ Get frame from cache if present. this is the case when data will be just appende
 If not present in the cache then its the first call.
 Get existing frame if its for the same instance. This is in case of multiple rawdata api calls
 */
-func GetFrame(tempMap map[string]*data.Frame, instanceName string, dataPointSelected []models.LabelIntValue) *data.Frame {
+func GetFrame(tempMap map[string]*data.Frame, instanceName string, dataPointSelected []models.LabelIntValue, nullable bool) *data.Frame {
 
 	val, ok := tempMap[instanceName]
 	if ok {
 		return val
 	} else {
-		return initiateNewDataFrame(instanceName, dataPointSelected)
+		return initiateNewDataFrame(instanceName, dataPointSelected, nullable)
 	}
 }
 
-func initiateNewDataFrame(instanceName string, dataPointSelected []models.LabelIntValue) *data.Frame {
+// nullable fields are used when missing values are to be sent as null instead of NaN
+func initiateNewDataFrame(instanceName string, dataPointSelected []models.LabelIntValue, nullable bool) *data.Frame {
 	frame := data.NewFrame(constants.ResponseStr)
 	// add fields
 	frame.Fields = append(frame.Fields,
@@ -68,13 +80,31 @@ func initiateNewDataFrame(instanceName string, dataPointSelected []models.LabelI
 	)
 	frame.RefID = instanceName
 	for _, datapoint := range dataPointSelected {
-		frame.Fields = append(frame.Fields,
-			data.NewField(instanceName+constants.InstantAndDpDelim+datapoint.Label, nil, []float64{}),
-		)
+		if nullable {
+			frame.Fields = append(frame.Fields,
+				data.NewField(instanceName+constants.InstantAndDpDelim+datapoint.Label, nil, []*float64{}),
+			)
+		} else {
+			frame.Fields = append(frame.Fields,
+				data.NewField(instanceName+constants.InstantAndDpDelim+datapoint.Label, nil, []float64{}),
+			)
+		}
 	}
 	return frame
 }
 
+// IsListRequest tells if request is for a list endpoint, these are read page by page
+func IsListRequest(request string) bool {
+	switch request {
+	case constants.AllHostReq, constants.AllInstanceReq, constants.DataSourceReq, constants.HostDataSourceReq,
+		constants.PropertyDevicesReq, constants.InstancePropertiesReq, constants.DeviceAlertReq, constants.GroupAlertReq,
+		constants.DeviceActiveAlertReq, constants.GroupActiveAlertReq, constants.DashboardWidgetsReq,
+		constants.DeviceOpsNoteReq, constants.GroupOpsNoteReq, constants.DeviceSdtReq, constants.GroupSdtReq:
+		return true
+	}
+	return false
+}
+
 //nolint:cyclop
 func BuildURLReplacingQueryParams(request string, qm *models.QueryModel, from int64, to int64, metaData models.MetaData) string {
 	switch request {
@@ -113,6 +143,26 @@ func BuildURLReplacingQueryParams(request string, qm *models.QueryModel, from in
 		return constants.AllHostURL
 	case constants.AllInstanceReq:
 		return fmt.Sprintf(constants.AllInstanceURL, qm.HostSelected.Value, qm.HdsSelected)
+	case constants.PropertyDevicesReq:
+		return constants.PropertyDevicesURL
+	case constants.InstancePropertiesReq:
+		return fmt.Sprintf(constants.InstancePropertiesURL, qm.HostSelected.Value, qm.HdsSelected)
+	case constants.DeviceAlertReq:
+		return fmt.Sprintf(constants.DeviceAlertURL, qm.HostSelected.Value, to, from)
+	case constants.GroupAlertReq:
+		return fmt.Sprintf(constants.GroupAlertURL, qm.GroupSelected.Value, to, from)
+	case constants.DeviceActiveAlertReq:
+		return fmt.Sprintf(constants.DeviceActiveAlertURL, qm.HostSelected.Value, to)
+	case constants.GroupActiveAlertReq:
+		return fmt.Sprintf(constants.GroupActiveAlertURL, qm.GroupSelected.Value, to)
+	case constants.DeviceOpsNoteReq:
+		return fmt.Sprintf(constants.DeviceOpsNoteURL, from, to, url.QueryEscape(`"`+qm.HostSelected.Label+`"`))
+	case constants.GroupOpsNoteReq:
+		return fmt.Sprintf(constants.GroupOpsNoteURL, from, to, url.QueryEscape(`"`+qm.GroupSelected.Label+`"`))
+	case constants.DeviceSdtReq:
+		return fmt.Sprintf(constants.DeviceSdtURL, qm.HostSelected.Value)
+	case constants.GroupSdtReq:
+		return fmt.Sprintf(constants.GroupSdtURL, qm.GroupSelected.Value)
 	default:
 		return constants.RequestNotValidStr
 	}
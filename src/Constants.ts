@@ -21,8 +21,7 @@ export class Constants {
     static readonly EnableRegexFeature = true // Alows user to make instance selections on regex
     static readonly EnableHostVariableFeature = true // Allow host variable. need to configure manually on variable section (Ex- Device1 : 123, Device2 : 321)
 
-    // New Algorithm. if EnableStrategicApiCallFeature is true, Call API for only data that is not available in cache and allow query on cache for specific duration
-    static readonly EnableStrategicApiCallFeature = true
+    static readonly QuerySchemaVersion = 1 // Schema version of queries, backend migrates queries of older versions
     static readonly EnableApiCallThrottler = true // Handle API calls on throttler strategy in santaba
     static readonly MaxNumberOfApiCallPerQuery = -1 // Allowed API calls  per query for historical data. -1 for unlimited
     static readonly ConcurrentApiCallsPerQuery = 1 // Concurrent API calls per query if historical data is enabled. -1 for unlimited
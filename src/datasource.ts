@@ -24,13 +24,13 @@ export class DataSource extends DataSourceWithBackend<MyQuery, MyDataSourceOptio
     this.url = instanceSettings.url;
     this.id = instanceSettings.id;
     this.storedJsonData = instanceSettings.jsonData;
+    // alerts, opsnotes and sdts are returned as annotation frames by the backend
+    this.annotations = {};
   }
 
   applyTemplateVariables(query: MyQuery, scopedVars: ScopedVars): Record<string, any> {
-    // load configurations
-    query.enabledHostVariableFeature = Constants.EnableHostVariableFeature
-    query.enableStrategicApiCallFeature = Constants.EnableStrategicApiCallFeature
-    query.enabledRegexFeature = Constants.EnableRegexFeature
+    // load configurations, schema version tells backend the query needs no migration
+    query.schemaVersion = Constants.QuerySchemaVersion
     query.enableApiCallThrottler = Constants.EnableApiCallThrottler
     query.maxNumberOfApiCallPerQuery = Constants.MaxNumberOfApiCallPerQuery
     query.concurrentApiCallsPerQuery = Constants.ConcurrentApiCallsPerQuery
@@ -16,17 +16,33 @@ export interface MyQuery extends DataQuery {
   dataPointSelected: any[];
   collectInterval: number;
   lastQueryEditedTimeStamp: any;
+  schemaVersion?: number
   isQueryInterpolated: boolean
   withStreaming: boolean;
   enableHostVariable: boolean
-  enabledRegexFeature: boolean
-  enabledHostVariableFeature: boolean
-  enableStrategicApiCallFeature: boolean
   enableApiCallThrottler: boolean
   maxNumberOfApiCallPerQuery: any
   concurrentApiCallsPerQuery: any
+  cacheStrategy?: string
+  queryMode?: string
+  annotationTypes?: string[]
+  downsampleFunction?: string
+  nullPolicy?: string
+  gapIntervals?: number
+  rankReducer?: string
+  rankOrder?: string
+  rankLimit?: number
+  rankFormat?: string
+  propertyFilter?: string
+  instanceIncludes?: string[]
+  instanceExcludes?: string[]
+  instancePatternType?: string
+  instancePropertyFilter?: string
+  timeShift?: string
+  compareMode?: string
 }
 export const defaultQuery: Partial<MyQuery> = {
+  schemaVersion: 1,
   withStreaming: false,
 };
 /**
@@ -38,6 +54,15 @@ export interface MyDataSourceOptions extends DataSourceJsonData {
   isLMV1Enabled?: boolean;
   isBearerEnabled?: boolean;
   skipTLSVarify?: boolean;
+  enableActions?: boolean;
+  actionsMinRole?: string;
+  requestLogLevel?: string;
+  requestLogBodyBytes?: number;
+  requestLogSampleRate?: number;
+  enableCacheWarming?: boolean;
+  cacheWarmingLearn?: boolean;
+  cacheWarmingBudgetPercent?: number;
+  cacheWarmingQueries?: Array<{ query: MyQuery; rangeSeconds?: number }>;
 }
 /**
  * Value that is used in the backend, but never sent over HTTP to the frontend